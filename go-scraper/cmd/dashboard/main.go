@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
@@ -15,8 +18,10 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
 	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
 	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/textdiff"
 	"github.com/gorilla/sessions"
 )
 
@@ -26,11 +31,18 @@ type Job struct {
 	ID        int
 	Title     string
 	Company   string
+	CompanyID int
 	Location  string
 	Type      string
 	URL       string
 	DateAdded time.Time
 	Status    string
+	Next      []string // statuses the pipeline allows moving to
+	Starred   bool
+	Tags      []string
+	OpenDays  int
+	Closed    bool
+	Snippet   template.HTML // search match with highlighted terms
 }
 
 // PageData includes 'User' field required for authenticated templates
@@ -42,6 +54,13 @@ type PageData struct {
 	User       string
 }
 
+// server holds what the handlers share: one database handle opened at
+// startup, used by every request and the background importer.
+type server struct {
+	db       *db.DB
+	pipeline db.Pipeline // application stages, from CONFIG_PATH or the default
+}
+
 // importRunning is an atomic flag to prevent overlapping imports
 var importRunning int32
 
@@ -70,6 +89,15 @@ func AuthRequired(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// userID returns the ID of the logged-in user, whose statuses and flags
+// the job pages show.
+func (s *server) userID(r *http.Request) (int, error) {
+	session, _ := store.Get(r, "session")
+	name, _ := session.Values["user"].(string)
+	id, _, _, err := s.db.GetUserByUsername(name)
+	return id, err
+}
+
 // -------------------- AUTHENTICATION TEMPLATES --------------------
 
 var loginHTML = `<!doctype html>
@@ -301,7 +329,7 @@ const landingHTML = `
 		<div class="section">
 		  <div class="section-title">Additional Filters</div>
 		  <div class="actions">
-			 <input type="text" name="q" placeholder="Search titles..." />
+			 <input type="text" name="q" placeholder='Search jobs: golang "new grad" eng*' />
 			 <select name="company">
 				<option value="">All Companies</option>
 				{{range .Companies}}
@@ -315,10 +343,60 @@ const landingHTML = `
 				{{end}}
 			 </select>
 			 <select name="status">
-				<option value="Not Applied" selected>Not Applied</option>
-				<option value="Applied">Applied</option>
+				{{range .Statuses}}
+				<option value="{{.}}"{{if eq . "Not Applied"}} selected{{end}}>{{.}}</option>
+				{{end}}
 				<option value="">All Statuses</option>
 			 </select>
+			 {{if .Tags}}
+			 <select name="tag">
+				<option value="">Any Tag</option>
+				{{range .Tags}}
+				<option value="{{.}}">#{{.}}</option>
+				{{end}}
+			 </select>
+			 {{end}}
+			 {{if .Profiles}}
+			 <select name="profile">
+				<option value="">All Profiles</option>
+				{{range .Profiles}}
+				<option value="{{.}}">{{.}}</option>
+				{{end}}
+			 </select>
+			 {{end}}
+			 {{if .Batches}}
+			 <select name="batch">
+				<option value="">Any YC Batch</option>
+				{{range .Batches}}
+				<option value="{{.}}+">{{.}} or later</option>
+				{{end}}
+			 </select>
+			 {{end}}
+			 {{if .Industries}}
+			 <select name="industry">
+				<option value="">All Industries</option>
+				{{range .Industries}}
+				<option value="{{.}}">{{.}}</option>
+				{{end}}
+			 </select>
+			 <select name="team_lt">
+				<option value="">Any Team Size</option>
+				<option value="25">Fewer than 25</option>
+				<option value="100">Fewer than 100</option>
+				<option value="500">Fewer than 500</option>
+			 </select>
+			 {{end}}
+			 <label>Added since <input type="date" name="added_after" /></label>
+			 <select name="sort">
+				<option value="">Best match when searching, else newest</option>
+				<option value="newest">Newest first</option>
+				<option value="oldest">Oldest first</option>
+				<option value="title">Title</option>
+				<option value="company">Company</option>
+			 </select>
+			 <label><input type="checkbox" name="closed" value="1"> Include closed postings</label>
+			 <label><input type="checkbox" name="starred" value="1"> Starred only</label>
+			 <label><input type="checkbox" name="hidden" value="1"> Include hidden jobs</label>
 			 <button type="submit">Show Jobs</button>
 		  </div>
 		</div>
@@ -341,17 +419,21 @@ const resultsHTML = `
 		/* Dashboard Stats Styling */
 		.stats {
 			display: flex;
+			flex-wrap: wrap;
+			gap: 10px;
 			justify-content: space-around;
 			margin: 20px 0 30px 0;
 		}
 		.stat-card {
 			background: white;
-			padding: 20px;
+			padding: 14px;
 			border-radius: 8px;
 			box-shadow: 0 2px 4px rgba(0,0,0,0.1);
 			text-align: center;
-			min-width: 150px;
+			min-width: 90px;
+			text-decoration: none;
 		}
+		.stat-card.active { outline: 2px solid #007bff; }
 		.stat-number {
 			font-size: 2em;
 			font-weight: bold;
@@ -363,6 +445,12 @@ const resultsHTML = `
 		}
 		/* Filter Pills and Job List Styling */
 		.pill { display:inline-block; background:#e9ecef; color:#495057; padding:6px 10px; border-radius:999px; margin: 0 6px 6px 0; font-size: 0.9em; }
+		.profiles { margin-bottom:10px; }
+		.profiles .pill { text-decoration:none; }
+		.profiles .pill.active { background:#007bff; color:#fff; }
+		.pager { text-align:center; margin-top:16px; }
+		.pager .pill { text-decoration:none; }
+		.pager .pill.active { background:#007bff; color:#fff; }
 		ul { list-style: none; padding: 0; }
 		li { background:#fff; padding:14px 16px; border-radius:8px; margin-bottom:10px; box-shadow: 0 2px 6px rgba(0,0,0,.06); display:flex; justify-content:space-between; align-items:center; gap: 10px; }
 		.meta { color:#6c757d; font-size: 0.95em; }
@@ -394,38 +482,51 @@ const resultsHTML = `
 		.download { background:#6c757d; color:#fff; padding:8px 14px; border-radius:6px; text-decoration:none; font-size:0.95em; }
 		.download:hover { background:#5a6268; }
 		.status-applied { opacity: 0.6; }
-		/* Style the mark button when a job is already applied */
+		/* Style the status picker once a job is in the pipeline */
 		li.status-applied .btn-mark { background: #28a745; color: #fff; }
+		li.status-closed { opacity: 0.5; }
+		.closed-tag { background:#f8d7da; color:#721c24; padding:2px 6px; border-radius:4px; font-size:0.85em; }
+		.snippet { color:#555; font-size:0.9em; margin-top:4px; }
+		.snippet mark { background:#fff3cd; padding:0 1px; }
+		a.pill.tag { text-decoration:none; margin-top:6px; }
 	</style>
 	<script>
-		function markApplied(jobId, btn) {
-			// Send a toggle request to mark/unmark as applied. Server responds with { success: bool, status: "Applied"|"Not Applied" }
-			fetch('/mark-applied', {
+		function setStatus(jobId, sel) {
+			// The server answers { success: bool, status, error } and refuses moves the pipeline does not allow
+			fetch('/set-status', {
 				method: 'POST',
 				headers: {'Content-Type': 'application/json'},
-				body: JSON.stringify({id: jobId})
+				body: JSON.stringify({id: jobId, status: sel.value})
 			})
 			.then(r => r.json())
 			.then(data => {
-				if(data.success) {
-					if (data.status === 'Applied') {
-						btn.textContent = '✅ Applied';
-						btn.style.background = '#28a745';
-						btn.style.color = '#fff';
-						btn.parentElement.parentElement.classList.add('status-applied');
-					} else {
-						// Unapplied state
-						btn.textContent = 'Mark as Applied';
-						btn.style.background = '';
-						btn.style.color = '';
-						btn.parentElement.parentElement.classList.remove('status-applied');
-					}
+				if (data.success) {
+					location.reload();
 				} else {
-					alert('Failed to update status');
+					alert(data.error || 'Failed to update status');
+					sel.selectedIndex = 0;
 				}
 			})
 			.catch(() => alert('Error updating status'));
 		}
+		function setFlag(jobId, flag, value, btn) {
+			fetch('/job-flag', {
+				method: 'POST',
+				headers: {'Content-Type': 'application/json'},
+				body: JSON.stringify({id: jobId, flag: flag, value: value})
+			})
+			.then(r => r.json())
+			.then(data => {
+				if (!data.success) {
+					alert('Failed to update job');
+				} else if (flag === 'hidden') {
+					btn.closest('li').remove();
+				} else {
+					location.reload();
+				}
+			})
+			.catch(() => alert('Error updating job'));
+		}
 	</script>
  </head>
  <body>
@@ -434,6 +535,7 @@ const resultsHTML = `
 	   <h1>Job Dashboard ({{.Total}} jobs found)</h1>
 	   <div class="actions">
 		 <a class="download" href="/download-csv?{{.QueryString}}">⬇ Download CSV</a>
+		 <a class="back" href="/companies">Companies</a>
 		 <a class="back" href="/filters">◀ Back to Filters</a>
 	   </div>
 	 </div>
@@ -444,44 +546,334 @@ const resultsHTML = `
 			<div class="stat-number">{{.TotalJobs}}</div>
 			<div class="stat-label">Total Jobs</div>
 		</div>
-		<div class="stat-card">
-			<div class="stat-number">{{.NotApplied}}</div>
-			<div class="stat-label">Not Applied</div>
-		</div>
-		<div class="stat-card">
-			<div class="stat-number">{{.Applied}}</div>
-			<div class="stat-label">Applied</div>
-		</div>
+		{{range .Stages}}
+		<a class="stat-card{{if .Active}} active{{end}}" href="/results?{{.Query}}">
+			<div class="stat-number">{{.Count}}</div>
+			<div class="stat-label">{{.Name}}</div>
+		</a>
+		{{end}}
+	 </div>
+
+	 {{if .Profiles}}
+	 <div class="profiles">
+	   {{range .Profiles}}<a class="pill{{if .Active}} active{{end}}" href="/results?{{.Query}}">{{if .Name}}{{.Name}}{{else}}All profiles{{end}}</a>{{end}}
 	 </div>
+	 {{end}}
 
 	 <div style="margin-bottom:10px;">
 	   {{if .Query}}<span class="pill">Search: {{.Query}}</span>{{end}}
 	   {{if .Company}}<span class="pill">Company: {{.Company}}</span>{{end}}
 	   {{if .Location}}<span class="pill">Location: {{.Location}}</span>{{end}}
 	   {{if .Status}}<span class="pill">Status: {{.Status}}</span>{{end}}
+	   {{if .Starred}}<span class="pill">Starred</span>{{end}}
+	   {{range .Tags}}<span class="pill">#{{.}}</span>{{end}}
+	   {{with .CompanyFilter}}
+	   {{if .Batch}}<span class="pill">Batch: {{.Batch}}</span>{{end}}
+	   {{if .Industry}}<span class="pill">Industry: {{.Industry}}</span>{{end}}
+	   {{if .MaxTeamSize}}<span class="pill">Team size &lt; {{.MaxTeamSize}}</span>{{end}}
+	   {{end}}
 	   {{range .Levels}}<span class="pill">{{.}}</span>{{end}}
+	   {{if not .AddedAfter.IsZero}}<span class="pill">Added since {{.AddedAfter.Format "2006-01-02"}}</span>{{end}}
+	   {{if .Sort}}<span class="pill">Sorted by {{.Sort}}</span>{{end}}
 	 </div>
+	 {{if .Query}}
+	 <div class="profiles">
+	   Sort: {{range .SortLinks}}<a class="pill{{if .Active}} active{{end}}" href="/results?{{.Query}}">{{.Name}}</a>{{end}}
+	 </div>
+	 {{end}}
 	 <ul>
 		{{range .Jobs}}
-		<li {{if eq .Status "Applied"}}class="status-applied"{{end}}>
+		<li class="{{if ne .Status "Not Applied"}}status-applied{{end}}{{if .Closed}} status-closed{{end}}">
 		   <div>
-			  <div><strong>{{.Title}}</strong> — {{.Company}}</div>
-			  <div class="meta">{{.Location}} • {{.Type}} • {{.DateAdded.Format "2006-01-02"}} • {{.Status}}</div>
+			  <div><strong><a href="/job?id={{.ID}}" style="color:inherit">{{.Title}}</a></strong> — {{if .CompanyID}}<a href="/company?id={{.CompanyID}}" style="color:inherit">{{.Company}}</a>{{else}}{{.Company}}{{end}} {{if .Closed}}<span class="closed-tag">Closed</span>{{end}}</div>
+			  <div class="meta">{{.Location}} • {{.Type}} • {{.DateAdded.Format "2006-01-02"}} • {{if .Closed}}was open{{else}}open{{end}} {{.OpenDays}}d • {{.Status}}</div>
+			  {{if .Snippet}}<div class="snippet">{{.Snippet}}</div>{{end}}
+			  {{if .Tags}}<div>{{range .Tags}}<a class="pill tag" href="/results?status=&tag={{.}}">#{{.}}</a>{{end}}</div>{{end}}
 		   </div>
 		   <div>
 			  <a class="btn" href="{{.URL}}" target="_blank">Open</a>
-			  <button class="btn btn-mark" onclick="markApplied({{.ID}}, this)">{{if eq .Status "Applied"}}✅ Applied{{else}}Mark as Applied{{end}}</button>
+			  <select class="btn btn-mark" title="Status" onchange="setStatus({{.ID}}, this)">
+				<option selected>{{.Status}}</option>
+				{{range .Next}}<option>{{.}}</option>{{end}}
+			  </select>
+			  <button class="btn btn-mark" title="Star" onclick="setFlag({{.ID}}, 'starred', {{not .Starred}}, this)">{{if .Starred}}★{{else}}☆{{end}}</button>
+			  <button class="btn btn-mark" title="Hide" onclick="setFlag({{.ID}}, 'hidden', true, this)">Hide</button>
 		   </div>
 		</li>
 		{{else}}
 		<li>No jobs match your filters.</li>
 		{{end}}
 	 </ul>
+	 {{if or .PrevPage .NextPage .PageLinks}}
+	 <div class="pager">
+	   {{if .PrevPage}}<a class="pill" href="/results?{{.PrevPage}}">← Previous</a>{{end}}
+	   {{range .PageLinks}}<a class="pill{{if .Active}} active{{end}}" href="/results?{{.Query}}">{{.Name}}</a>{{end}}
+	   {{if .NextPage}}<a class="pill" href="/results?{{.NextPage}}">Next →</a>{{end}}
+	 </div>
+	 {{end}}
    </div>
  </body>
  </html>
 `
 
+const jobHTML = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Job.Title}} — {{.Job.Company}}</title>
+	<style>
+		body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 40px; background: #f8f9fa; }
+		.container { max-width: 900px; margin: 0 auto; }
+		.card { background:#fff; padding:20px 24px; border-radius:8px; box-shadow: 0 2px 6px rgba(0,0,0,.06); margin-bottom:16px; }
+		.meta { color:#6c757d; font-size: 0.95em; }
+		.back { text-decoration:none; color:#007bff; font-weight: 500; }
+		.desc { white-space: pre-wrap; font-size: 0.95em; color:#343a40; }
+		.timeline { list-style:none; padding:0; }
+		.timeline li { border-left:3px solid #007bff; padding:6px 12px; margin-bottom:12px; }
+		.when { font-weight:600; color:#495057; }
+		.diff { font-family: ui-monospace, Menlo, Consolas, monospace; font-size:0.85em; white-space: pre-wrap; margin:6px 0 0; }
+		.diff .add { background:#e6ffed; color:#22863a; }
+		.diff .del { background:#ffeef0; color:#b31d28; }
+		.closed-tag { background:#f8d7da; color:#721c24; padding:2px 6px; border-radius:4px; font-size:0.85em; }
+		.snippet { color:#555; font-size:0.9em; margin-top:4px; }
+		.snippet mark { background:#fff3cd; padding:0 1px; }
+		.note { border-left:3px solid #ffc107; padding:6px 12px; margin-bottom:12px; }
+		.note p, .note ul, .note ol, .note pre, .note blockquote { margin:6px 0; }
+		.note pre { background:#f6f8fa; padding:8px; overflow-x:auto; }
+		.note blockquote { border-left:3px solid #dee2e6; padding-left:8px; color:#6c757d; }
+		.tag { background:#e9ecef; padding:2px 8px; border-radius:999px; }
+		textarea { width:100%; box-sizing:border-box; }
+	</style>
+	<script>
+		function unmerge(jobId) {
+			fetch('/unmerge', {
+				method: 'POST',
+				headers: {'Content-Type': 'application/json'},
+				body: JSON.stringify({id: jobId})
+			})
+			.then(r => r.json())
+			.then(data => data.success ? location.reload() : alert('Failed to unmerge'))
+			.catch(() => alert('Error unmerging job'));
+		}
+		function setStatus(jobId, sel) {
+			fetch('/set-status', {
+				method: 'POST',
+				headers: {'Content-Type': 'application/json'},
+				body: JSON.stringify({id: jobId, status: sel.value})
+			})
+			.then(r => r.json())
+			.then(data => {
+				if (data.success) {
+					location.reload();
+				} else {
+					alert(data.error || 'Failed to update status');
+					sel.selectedIndex = 0;
+				}
+			})
+			.catch(() => alert('Error updating status'));
+		}
+		// send posts or deletes a note, tag or attachment and reloads the page
+		function send(method, url, body) {
+			const opts = {method: method};
+			if (body instanceof FormData) {
+				opts.body = body;
+			} else if (body) {
+				opts.headers = {'Content-Type': 'application/json'};
+				opts.body = JSON.stringify(body);
+			}
+			fetch(url, opts)
+			.then(r => r.json())
+			.then(data => data.success ? location.reload() : alert(data.error || 'Request failed'))
+			.catch(() => alert('Request failed'));
+			return false;
+		}
+	</script>
+ </head>
+ <body>
+   <div class="container">
+	 <p><a class="back" href="javascript:history.back()">◀ Back to results</a></p>
+	 <div class="card">
+		<h1>{{.Job.Title}} {{if .Job.IsClosed}}<span class="closed-tag">Closed</span>{{end}}</h1>
+		<div class="meta">{{if .Job.CompanyID}}<a href="/company?id={{.Job.CompanyID}}">{{.Job.Company}}</a>{{else}}{{.Job.Company}}{{end}} • {{.Job.Location}} • {{.Job.Type}} • {{.Job.Status}}</div>
+		<div class="meta">First seen {{.Job.FirstSeenAt.Format "2006-01-02"}} • Last seen {{.Job.LastSeenAt.Format "2006-01-02"}}{{if .Job.ClosedAt}} • Closed {{.Job.ClosedAt.Format "2006-01-02"}}{{end}} • {{.Job.OpenDays}}d open</div>
+		{{if .Job.Profiles}}<div class="meta">Profiles: {{range $i, $p := .Job.Profiles}}{{if $i}}, {{end}}<a href="/results?status=&profile={{$p}}">{{$p}}</a>{{end}}</div>{{end}}
+		<p><a href="{{.Job.URL}}" target="_blank">Open posting ↗</a>{{if .Job.Source}} <span class="meta">via {{.Job.Source}}</span>{{end}}</p>
+		{{if .Job.MergedInto}}<p class="meta">This listing was merged into <a href="/job?id={{.Job.MergedInto}}">another job</a>. <button onclick="unmerge({{.Job.ID}})">Unmerge</button></p>{{end}}
+	 </div>
+	 <div class="card">
+		<h2>Application</h2>
+		<p>Status:
+			<select onchange="setStatus({{.Job.ID}}, this)">
+				<option selected>{{.Job.Status}}</option>
+				{{range .Next}}<option>{{.}}</option>{{end}}
+			</select>
+		</p>
+		<ul class="timeline">
+		{{range .History}}
+			<li><div class="when">{{.ChangedAt.Format "2006-01-02 15:04"}}</div>{{.From}} → {{.To}}</li>
+		{{else}}
+			<li>No status changes yet.</li>
+		{{end}}
+		</ul>
+	 </div>
+	 <div class="card">
+		<h2>Notes</h2>
+		{{range .Notes}}
+			<div class="note"><div class="when">{{.CreatedAt.Format "2006-01-02 15:04"}} <button onclick="send('DELETE', '/notes?id={{.ID}}')">Delete</button></div>{{markdown .Body}}</div>
+		{{end}}
+		<form onsubmit="return send('POST', '/notes', {job: {{.Job.ID}}, body: this.body.value})">
+			<textarea name="body" rows="3" placeholder="Recruiter, referral, prep notes (markdown)" required></textarea>
+			<button type="submit">Add note</button>
+		</form>
+	 </div>
+	 <div class="card">
+		<h2>Tags</h2>
+		<p>{{range .Job.Tags}}<span class="tag"><a href="/results?status=&tag={{.}}">#{{.}}</a> <button onclick="send('DELETE', '/tags?job={{$.Job.ID}}&tag=' + encodeURIComponent({{.}}))">×</button></span> {{else}}<span class="meta">No tags yet.</span>{{end}}</p>
+		<form onsubmit="return send('POST', '/tags', {job: {{.Job.ID}}, tag: this.tag.value})">
+			<input name="tag" placeholder="e.g. referral" required>
+			<button type="submit">Add tag</button>
+		</form>
+	 </div>
+	 <div class="card">
+		<h2>Attachments</h2>
+		<ul class="timeline">
+		{{range .Attachments}}
+			<li><a href="/attachments?id={{.ID}}">{{.Name}}</a> <span class="meta">{{.ContentType}} • {{.Size}} bytes • {{.CreatedAt.Format "2006-01-02"}}</span>
+				<button onclick="send('DELETE', '/attachments?id={{.ID}}')">Delete</button></li>
+		{{end}}
+		</ul>
+		<form onsubmit="return send('POST', '/attachments', new FormData(this))">
+			<input type="hidden" name="job" value="{{.Job.ID}}">
+			<input type="file" name="file" required>
+			<button type="submit">Upload</button> <span class="meta">up to 10 MB, e.g. the resume you sent or an offer letter</span>
+		</form>
+	 </div>
+	 {{if .Sources}}
+	 <div class="card">
+		<h2>Also listed at</h2>
+		<ul class="timeline">
+		{{range .Sources}}
+			<li>
+				<a href="{{.URL}}" target="_blank">{{.Title}}</a> <span class="meta">{{.Company}} • {{.Location}}{{if .Source}} • {{.Source}}{{end}}{{if .ClosedAt}} • closed{{end}}</span>
+				<button onclick="unmerge({{.ID}})">Not the same job</button>
+			</li>
+		{{end}}
+		</ul>
+	 </div>
+	 {{end}}
+	 <div class="card">
+		<h2>Change history</h2>
+		<ul class="timeline">
+		{{range .Versions}}
+			<li>
+				<div class="when">{{.ChangedAt.Format "2006-01-02 15:04"}}</div>
+				{{range .Changes}}
+				<div><strong>{{.Field}}</strong>{{if .Lines}}:
+					<div class="diff">{{range .Lines}}<div class="{{if eq .Op '+'}}add{{else}}del{{end}}">{{printf "%c" .Op}} {{.Text}}</div>{{end}}</div>
+				{{else}}: <del>{{.Old}}</del> → {{.New}}{{end}}</div>
+				{{end}}
+			</li>
+		{{else}}
+			<li>No changes recorded since this posting was first seen.</li>
+		{{end}}
+		</ul>
+	 </div>
+	 {{if .Job.Description}}
+	 <div class="card">
+		<h2>Description</h2>
+		<div class="desc">{{.Job.Description}}</div>
+	 </div>
+	 {{end}}
+   </div>
+ </body>
+ </html>
+`
+
+const companiesHTML = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Companies</title>
+	<style>
+		body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 40px; background: #f8f9fa; }
+		.container { max-width: 1100px; margin: 0 auto; }
+		.header { display:flex; justify-content:space-between; align-items:center; margin-bottom: 12px; }
+		.back { text-decoration:none; color:#007bff; font-weight: 500; }
+		table { width:100%; border-collapse: collapse; background:#fff; border-radius:8px; box-shadow: 0 2px 6px rgba(0,0,0,.06); }
+		th, td { text-align:left; padding:10px 12px; border-bottom:1px solid #e9ecef; vertical-align: top; }
+		th { color:#495057; font-size:0.9em; }
+		.meta { color:#6c757d; font-size: 0.9em; }
+		.pill { display:inline-block; background:#e9ecef; color:#495057; padding:2px 8px; border-radius:999px; margin: 0 4px 4px 0; font-size: 0.85em; }
+		.logo { width:20px; height:20px; vertical-align:middle; margin-right:6px; }
+	</style>
+</head>
+<body>
+  <div class="container">
+	<div class="header">
+	  <h1>Companies ({{len .Companies}})</h1>
+	  <a class="back" href="/results?status=">◀ Back to results</a>
+	</div>
+	<table>
+	  <tr><th>Company</th><th>Board</th><th>Batch</th><th>Industry</th><th>Team</th><th>Tags</th><th>Open jobs</th><th>Notes</th></tr>
+	  {{range .Companies}}
+	  <tr>
+		<td>{{if .LogoURL}}<img class="logo" src="{{.LogoURL}}" alt="">{{end}}<a href="/company?id={{.ID}}">{{.Name}}</a>{{if .Website}}<div class="meta"><a href="{{.Website}}" target="_blank">{{.Website}}</a></div>{{end}}</td>
+		<td class="meta">{{.Platform}}/{{.Slug}}</td>
+		<td>{{.YCBatch}}{{if .YCStatus}}<div class="meta">{{.YCStatus}}</div>{{end}}</td>
+		<td>{{.Industry}}</td>
+		<td>{{if .TeamSize}}{{.TeamSize}}{{end}}</td>
+		<td>{{range .Tags}}<span class="pill">{{.}}</span>{{end}}</td>
+		<td><a href="/results?status=&company_id={{.ID}}">{{.OpenJobs}}</a></td>
+		<td class="meta">{{.Notes}}</td>
+	  </tr>
+	  {{else}}
+	  <tr><td colspan="8">No companies yet. Run the scraper to populate the catalog from config.</td></tr>
+	  {{end}}
+	</table>
+  </div>
+</body>
+</html>
+`
+
+const companyHTML = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Company.Name}}</title>
+	<style>
+		body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 40px; background: #f8f9fa; }
+		.container { max-width: 900px; margin: 0 auto; }
+		.card { background:#fff; padding:20px 24px; border-radius:8px; box-shadow: 0 2px 6px rgba(0,0,0,.06); margin-bottom:16px; }
+		.meta { color:#6c757d; font-size: 0.95em; }
+		.back { text-decoration:none; color:#007bff; font-weight: 500; }
+		.pill { display:inline-block; background:#e9ecef; color:#495057; padding:2px 8px; border-radius:999px; margin: 0 4px 4px 0; font-size: 0.85em; }
+		.logo { width:40px; height:40px; vertical-align:middle; margin-right:10px; }
+		textarea { width:100%; min-height:120px; font: inherit; padding:8px; box-sizing: border-box; }
+		button { background:#007bff; color:#fff; padding:8px 14px; border:none; border-radius:6px; cursor:pointer; margin-top:8px; }
+	</style>
+</head>
+<body>
+  <div class="container">
+	<p><a class="back" href="/companies">◀ All companies</a></p>
+	<div class="card">
+	  <h1>{{if .Company.LogoURL}}<img class="logo" src="{{.Company.LogoURL}}" alt="">{{end}}{{.Company.Name}}</h1>
+	  <div class="meta">{{.Company.Platform}}/{{.Company.Slug}}{{if .Company.YCBatch}} • YC {{.Company.YCBatch}}{{end}}{{if .Company.YCStatus}} ({{.Company.YCStatus}}){{end}}{{if .Company.Industry}} • {{.Company.Industry}}{{end}}{{if .Company.TeamSize}} • {{.Company.TeamSize}} people{{end}}</div>
+	  {{if .Company.Website}}<p><a href="{{.Company.Website}}" target="_blank">{{.Company.Website}} ↗</a></p>{{end}}
+	  {{if .Company.Tags}}<div>{{range .Company.Tags}}<span class="pill">{{.}}</span>{{end}}</div>{{end}}
+	  <p><a href="/results?status=&company_id={{.Company.ID}}">View {{.Company.OpenJobs}} open jobs</a></p>
+	</div>
+	<div class="card">
+	  <h2>Notes</h2>
+	  <form method="POST" action="/company?id={{.Company.ID}}">
+		<textarea name="notes">{{.Company.Notes}}</textarea>
+		<button type="submit">Save notes</button>
+	  </form>
+	</div>
+  </div>
+</body>
+</html>
+`
+
 // -------------------- HELPERS --------------------
 
 var levelRegex = regexp.MustCompile("(?i)(intern|new grad|new graduate|entry level|entry-level|junior|associate|apprentice|co-op|co op|coop|fellow)")
@@ -542,7 +934,7 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // loginHandler handles GET (show form) and POST (process login)
-func loginHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) loginHandler(w http.ResponseWriter, r *http.Request) {
 	// Use a small template to render the login page with an optional error message
 	tmpl := template.Must(template.New("login").Parse(loginHTML))
 	switch r.Method {
@@ -553,14 +945,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		username := strings.TrimSpace(r.FormValue("username"))
 		password := strings.TrimSpace(r.FormValue("password"))
-		d, err := db.Connect()
-		if err != nil {
-			logger.Error("db connect: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = tmpl.Execute(w, map[string]string{"Error": "Internal server error"})
-			return
-		}
-		defer d.Close()
+		d := s.db
 		valid, err := d.AuthenticateUser(username, password)
 		if err != nil {
 			logger.Error("auth check: %v", err)
@@ -586,7 +971,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // registerHandler handles GET (show form) and POST (process registration)
-func registerHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) registerHandler(w http.ResponseWriter, r *http.Request) {
 	tmpl := template.Must(template.New("register").Parse(registerHTML))
 	switch r.Method {
 	case http.MethodGet:
@@ -607,16 +992,9 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		d, err := db.Connect()
-		if err != nil {
-			logger.Error("db connect: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = tmpl.Execute(w, map[string]string{"Error": "Internal server error"})
-			return
-		}
-		defer d.Close()
+		d := s.db
 
-		err = d.CreateUser(username, password)
+		err := d.CreateUser(username, password)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			_ = tmpl.Execute(w, map[string]string{"Error": "Username already exists or invalid password"})
@@ -638,17 +1016,11 @@ func logoutHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // filtersHandler shows the job filter page (authenticated)
-func filtersHandler(w http.ResponseWriter, r *http.Request) {
-	d, err := db.Connect()
-	if err != nil {
-		logger.Error("db connect: %v", err)
-		http.Error(w, "Database connection error", http.StatusInternalServerError)
-		return
-	}
-	defer d.Close()
+func (s *server) filtersHandler(w http.ResponseWriter, r *http.Request) {
+	d := s.db
 
 	// Collect distinct titles and derive available levels
-	rows, err := d.Conn.Query(`SELECT DISTINCT title FROM job_applications`)
+	rows, err := d.Read.Query(`SELECT DISTINCT title FROM job_applications`)
 	if err != nil {
 		logger.Error("distinct titles: %v", err)
 		http.Error(w, "Query error", http.StatusInternalServerError)
@@ -673,7 +1045,7 @@ func filtersHandler(w http.ResponseWriter, r *http.Request) {
 	sort.Strings(levels)
 
 	// Collect distinct companies
-	rows, err = d.Conn.Query(`SELECT DISTINCT company FROM job_applications ORDER BY company`)
+	rows, err = d.Read.Query(`SELECT DISTINCT company FROM job_applications ORDER BY company`)
 	if err != nil {
 		logger.Error("distinct companies: %v", err)
 		http.Error(w, "Query error", http.StatusInternalServerError)
@@ -689,7 +1061,7 @@ func filtersHandler(w http.ResponseWriter, r *http.Request) {
 	rows.Close()
 
 	// Collect distinct locations
-	rows, err = d.Conn.Query(`SELECT DISTINCT location FROM job_applications ORDER BY location`)
+	rows, err = d.Read.Query(`SELECT DISTINCT location FROM job_applications ORDER BY location`)
 	if err != nil {
 		logger.Error("distinct locations: %v", err)
 		http.Error(w, "Query error", http.StatusInternalServerError)
@@ -704,18 +1076,45 @@ func filtersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	rows.Close()
 
+	// Search profiles defined in the scraper config
+	profiles, err := d.ListProfiles()
+	if err != nil {
+		logger.Error("list profiles: %v", err)
+	}
+
+	// YC directory facets, once a directory has been imported
+	batches, industries, err := d.CompanyFacets()
+	if err != nil {
+		logger.Error("company facets: %v", err)
+	}
+
 	// Get username for the template
 	session, _ := store.Get(r, "session")
 	user := fmt.Sprintf("%v", session.Values["user"])
 
+	// The user's own tags
+	var tags []string
+	if uid, err := s.userID(r); err != nil {
+		logger.Error("look up user: %v", err)
+	} else if tags, err = d.ListUserTags(uid); err != nil {
+		logger.Error("list tags: %v", err)
+	}
+
 	// Render the template
 	lt := template.Must(template.New("landing").Parse(landingHTML))
 	data := struct {
-		Levels    []string
-		Companies []string
-		Locations []string
-		User      string
-	}{Levels: levels, Companies: companies, Locations: locations, User: user}
+		Levels     []string
+		Companies  []string
+		Locations  []string
+		Profiles   []string
+		Batches    []string
+		Industries []string
+		Statuses   []string
+		Tags       []string
+		User       string
+	}{Levels: levels, Companies: companies, Locations: locations, Profiles: profiles,
+		Batches: batches, Industries: industries, User: user,
+		Statuses: append([]string{db.StatusNotApplied}, s.pipeline.Stages...), Tags: tags}
 	if err := lt.Execute(w, data); err != nil {
 		logger.Error("landing template: %v", err)
 	}
@@ -728,104 +1127,58 @@ func dashboardHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // resultsHandler shows filtered job results (authenticated, includes pagination)
-func resultsHandler(w http.ResponseWriter, r *http.Request) {
-	// Query parameters
-	selLevels := r.URL.Query()["level"]
-	q := strings.TrimSpace(r.URL.Query().Get("q"))
-	status := r.URL.Query().Get("status")
-	company := r.URL.Query().Get("company")
-	location := r.URL.Query().Get("location")
-
-	d, err := db.Connect()
+func (s *server) resultsHandler(w http.ResponseWriter, r *http.Request) {
+	filter := jobFilterFromQuery(r)
+	profile := filter.Profile
+	company := filter.Company
+
+	d := s.db
+	uid, err := s.userID(r)
 	if err != nil {
-		logger.Error("db connect: %v", err)
-		http.Error(w, "Database connection error", http.StatusInternalServerError)
+		logger.Error("look up user: %v", err)
+		http.Redirect(w, r, "/login", http.StatusFound)
 		return
 	}
-	defer d.Close()
+	filter.UserID = uid
 
-	// First, get overall job statistics for the dashboard counters
-	var totalCount, notAppliedCount, appliedCount int
-	err = d.Conn.QueryRow(`
-		SELECT COUNT(*), 
-			COALESCE(SUM(CASE WHEN status = 'Not Applied' THEN 1 ELSE 0 END), 0), 
-			COALESCE(SUM(CASE WHEN status = 'Applied' THEN 1 ELSE 0 END), 0) 
-		FROM job_applications`).Scan(&totalCount, &notAppliedCount, &appliedCount)
+	// First, get the user's job statistics for the dashboard counters:
+	// the jobs the list would show under each status
+	countFilter := filter
+	countFilter.Status = ""
+	counts, err := d.CountJobsByStatus(countFilter)
 	if err != nil {
 		logger.Error("query job stats: %v", err)
 		http.Error(w, "Query error for stats", http.StatusInternalServerError)
 		return
 	}
-
-	// Build WHERE clause
-	var clauses []string
-	var args []interface{}
-
-	// Status filter - default to "Not Applied" unless explicitly set
-	if status != "" {
-		clauses = append(clauses, fmt.Sprintf("status = $%d", len(args)+1))
-		args = append(args, status)
-	} else {
-		// By default, only show "Not Applied" jobs
-		clauses = append(clauses, fmt.Sprintf("status = $%d", len(args)+1))
-		args = append(args, "Not Applied")
-	}
-	// Company filter
-	if company != "" {
-		clauses = append(clauses, fmt.Sprintf("company = $%d", len(args)+1))
-		args = append(args, company)
-	}
-	// Location filter
-	if location != "" {
-		clauses = append(clauses, fmt.Sprintf("location = $%d", len(args)+1))
-		args = append(args, location)
-	}
-	// Query string over title
-	if q != "" {
-		// Use LIKE on SQLite, with COLLATE NOCASE for case-insensitivity
-		clauses = append(clauses, fmt.Sprintf("title LIKE $%d COLLATE NOCASE", len(args)+1))
-		args = append(args, "%"+q+"%")
-	}
-	// Levels mapped to title keywords
-	var levelPatterns []string
-	for _, lv := range selLevels {
-		switch lv {
-		case "Intern":
-			levelPatterns = append(levelPatterns, "intern")
-		case "New Grad":
-			levelPatterns = append(levelPatterns, "new grad", "new graduate")
-		case "Entry Level":
-			levelPatterns = append(levelPatterns, "entry level", "entry-level")
-		case "Junior":
-			levelPatterns = append(levelPatterns, "junior")
-		case "Associate":
-			levelPatterns = append(levelPatterns, "associate")
-		case "Apprentice":
-			levelPatterns = append(levelPatterns, "apprentice")
-		case "Fellow":
-			levelPatterns = append(levelPatterns, "fellow")
-		case "Co-op":
-			levelPatterns = append(levelPatterns, "co-op", "co op", "coop")
-		}
-	}
-
-	if len(levelPatterns) > 0 {
-		// Build (title LIKE $x OR title LIKE $y ...) group
-		var parts []string
-		for _, pat := range levelPatterns {
-			parts = append(parts, fmt.Sprintf("title LIKE $%d COLLATE NOCASE", len(args)+1))
-			args = append(args, "%"+pat+"%")
+	totalCount := 0
+	for _, n := range counts {
+		totalCount += n
+	}
+	// One counter per stage, then any status no longer in the pipeline
+	type stageCount struct {
+		Name   string
+		Count  int
+		Query  template.URL
+		Active bool
+	}
+	var stages []stageCount
+	names := append([]string{db.StatusNotApplied}, s.pipeline.Stages...)
+	var other []string
+	for name := range counts {
+		if !s.pipeline.Has(name) {
+			other = append(other, name)
 		}
-		clauses = append(clauses, "("+strings.Join(parts, " OR ")+")")
 	}
-
-	where := ""
-	if len(clauses) > 0 {
-		where = " WHERE " + strings.Join(clauses, " AND ")
+	sort.Strings(other)
+	for _, name := range append(names, other...) {
+		v := r.URL.Query()
+		v.Del("page")
+		v.Del("cursor")
+		v.Set("status", name)
+		stages = append(stages, stageCount{Name: name, Count: counts[name], Query: template.URL(v.Encode()), Active: name == filter.Status})
 	}
 
-	var jobs []Job
-
 	// Pagination: page & page_size
 	page := 1
 	pageSize := 20
@@ -847,154 +1200,237 @@ func resultsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total count for this query
-	var total int
-	countQ := "SELECT COUNT(*) FROM job_applications" + where
-	if err := d.Conn.QueryRow(countQ, args...).Scan(&total); err != nil {
+	total, err := d.CountJobs(filter)
+	if err != nil {
 		logger.Error("count query: %v", err)
 	}
-
-	// Apply limit/offset
-	offset := (page - 1) * pageSize
-	limitIdx := len(args) + 1
-	offsetIdx := len(args) + 2
-
-	dataQ := fmt.Sprintf(
-		"SELECT id, title, company, location, type, url, date_added, status FROM job_applications%s ORDER BY date_added DESC LIMIT $%d OFFSET $%d",
-		where, limitIdx, offsetIdx,
-	)
-	argsData := append([]interface{}{}, args...)
-	argsData = append(argsData, pageSize, offset)
-
-	rows, err := d.Conn.Query(dataQ, argsData...)
+	// Prev and next links carry a cursor; page numbers jump by offset
+	req := db.PageRequest{Cursor: r.URL.Query().Get("cursor"), Page: page, Size: pageSize}
+	list, err := d.ListJobsPage(filter, req)
+	if errors.Is(err, db.ErrBadCursor) {
+		// e.g. a link kept across a sort change: start from the top
+		req.Cursor, req.Page = "", 1
+		list, err = d.ListJobsPage(filter, req)
+	}
 	if err != nil {
 		logger.Error("list query: %v", err)
 		http.Error(w, `{"error":"list query"}`, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
+	// The last status change of each job can always be undone
+	ids := make([]int, len(list.Jobs))
+	for i, lc := range list.Jobs {
+		ids[i] = lc.ID
+	}
+	last, err := d.LastStatusChanges(uid, ids)
+	if err != nil {
+		logger.Error("last status changes: %v", err)
+	}
+	var jobs []Job
+	for _, lc := range list.Jobs {
+		jobs = append(jobs, Job{ID: lc.ID, Title: lc.Title, Company: lc.Company, CompanyID: lc.CompanyID, Location: lc.Location,
+			Type: lc.Type, URL: lc.URL, DateAdded: lc.DateAdded, Status: lc.Status, Next: s.pipeline.NextAfter(lc.Status, last[lc.ID]), Starred: lc.Starred, Tags: lc.Tags, OpenDays: lc.OpenDays(), Closed: lc.IsClosed(),
+			Snippet: highlightSnippet(lc.Snippet)})
+	}
+
+	// Links that switch profile while keeping the other filters
+	type profileLink struct {
+		Name   string
+		Query  template.URL // already encoded; html/template would escape a string
+		Active bool
+	}
+	var profileLinks []profileLink
+	if names, err := d.ListProfiles(); err != nil {
+		logger.Error("list profiles: %v", err)
+	} else if len(names) > 0 {
+		for _, name := range append([]string{""}, names...) {
+			v := r.URL.Query()
+			v.Del("page")
+			v.Del("cursor")
+			if name == "" {
+				v.Del("profile")
+			} else {
+				v.Set("profile", name)
+			}
+			profileLinks = append(profileLinks, profileLink{Name: name, Query: template.URL(v.Encode()), Active: name == profile})
+		}
+	}
 
-	for rows.Next() {
-		var job Job
-		var typ string
-		if err := rows.Scan(&job.ID, &job.Title, &job.Company, &job.Location, &typ, &job.URL, &job.DateAdded, &job.Status); err != nil {
-			logger.Error("scan row: %v", err)
-			continue
+	// With a search, links that switch between relevance and date order
+	var sortLinks []profileLink
+	if filter.Search != "" {
+		for _, o := range []struct {
+			name string
+			sort db.JobSort
+		}{{"Relevance", db.SortRelevance}, {"Newest", db.SortNewest}, {"Oldest", db.SortOldest}} {
+			v := r.URL.Query()
+			v.Del("page")
+			v.Del("cursor")
+			v.Set("sort", string(o.sort))
+			sortLinks = append(sortLinks, profileLink{Name: o.name, Query: template.URL(v.Encode()), Active: filter.Sort == o.sort})
+		}
+	}
+
+	pageQuery := func(cursor string, page int) template.URL {
+		v := r.URL.Query()
+		v.Del("cursor")
+		v.Del("page")
+		if cursor != "" {
+			v.Set("cursor", cursor)
+		}
+		if page > 1 {
+			v.Set("page", strconv.Itoa(page))
+		}
+		return template.URL(v.Encode())
+	}
+	var prevPage, nextPage template.URL
+	if list.Prev != "" {
+		prevPage = pageQuery(list.Prev, 0)
+	}
+	if list.Next != "" {
+		nextPage = pageQuery(list.Next, 0)
+	}
+	// Page numbers around the current one, plus the first and last; after
+	// following a cursor the current page number is unknown
+	var pageLinks []profileLink
+	current := 0
+	if req.Cursor == "" {
+		current = req.Page
+	}
+	if pages := (total + pageSize - 1) / pageSize; pages > 1 {
+		for n := 1; n <= pages; n++ {
+			if n == 1 || n == pages || (n >= current-3 && n <= current+3) || (current == 0 && n <= 5) {
+				pageLinks = append(pageLinks, profileLink{Name: strconv.Itoa(n), Query: pageQuery("", n), Active: n == current})
+			}
+		}
+	}
+
+	// A company_id filter shows the catalog name in the filter pills
+	if filter.CompanyID > 0 && company == "" {
+		if c, err := d.GetCompany(filter.CompanyID); err == nil {
+			company = c.Name
 		}
-		job.Type = typ
-		jobs = append(jobs, job)
 	}
 
 	// Struct used by the template (enhanced with job statistics)
 	rt := template.Must(template.New("results").Funcs(template.FuncMap{"eq": func(a, b interface{}) bool { return a == b }}).Parse(resultsHTML))
 	data := struct {
-		Jobs        []Job
-		Levels      []string
-		Query       string
-		Company     string
-		Location    string
-		Status      string
-		Total       int
-		QueryString string
-		TotalJobs   int
-		NotApplied  int
-		Applied     int
+		Jobs          []Job
+		Levels        []string
+		Query         string
+		Company       string
+		Location      string
+		Status        string
+		Starred       bool
+		Tags          []string
+		Total         int
+		QueryString   template.URL
+		IncludeClosed bool
+		Profiles      []profileLink
+		CompanyFilter db.CompanyFilter
+		AddedAfter    time.Time
+		Sort          db.JobSort
+		SortLinks     []profileLink
+		PrevPage      template.URL
+		NextPage      template.URL
+		PageLinks     []profileLink
+		TotalJobs     int
+		Stages        []stageCount
 	}{
-		Jobs: jobs, Levels: selLevels, Query: q, Company: company, Location: location,
-		Status: status, Total: total, QueryString: r.URL.RawQuery,
-		TotalJobs: totalCount, NotApplied: notAppliedCount, Applied: appliedCount,
+		Jobs: jobs, Levels: filter.Levels, Query: filter.Search, Company: company, Location: filter.Location,
+		Status: filter.Status, Starred: filter.Starred, Tags: filter.Tags, Total: total, QueryString: template.URL(r.URL.Query().Encode()), IncludeClosed: filter.IncludeClosed,
+		Profiles: profileLinks, CompanyFilter: filter.Companies, AddedAfter: filter.AddedAfter, Sort: filter.Sort, SortLinks: sortLinks,
+		PrevPage: prevPage, NextPage: nextPage, PageLinks: pageLinks,
+		TotalJobs: totalCount, Stages: stages,
 	}
 	if err := rt.Execute(w, data); err != nil {
 		logger.Error("results template: %v", err)
 	}
 }
 
-// downloadCSVHandler exports filtered job results as CSV (authenticated)
-func downloadCSVHandler(w http.ResponseWriter, r *http.Request) {
-	selLevels := r.URL.Query()["level"]
-	q := strings.TrimSpace(r.URL.Query().Get("q"))
-	status := r.URL.Query().Get("status")
-	company := r.URL.Query().Get("company")
-	location := r.URL.Query().Get("location")
-
-	d, err := db.Connect()
-	if err != nil {
-		logger.Error("db connect: %v", err)
-		http.Error(w, "Database connection error", http.StatusInternalServerError)
-		return
-	}
-	defer d.Close()
-
-	// Build WHERE clause (same logic as /results but without pagination/user)
-	var clauses []string
-	var args []interface{}
-
-	if status != "" {
-		clauses = append(clauses, fmt.Sprintf("status = $%d", len(args)+1))
-		args = append(args, status)
-	}
-	if company != "" {
-		clauses = append(clauses, fmt.Sprintf("company = $%d", len(args)+1))
-		args = append(args, company)
-	}
-	if location != "" {
-		clauses = append(clauses, fmt.Sprintf("location = $%d", len(args)+1))
-		args = append(args, location)
-	}
-	if q != "" {
-		clauses = append(clauses, fmt.Sprintf("title LIKE $%d COLLATE NOCASE", len(args)+1))
-		args = append(args, "%"+q+"%")
-	}
-	var levelPatterns []string
-	for _, lv := range selLevels {
-		switch lv {
-		case "Intern":
-			levelPatterns = append(levelPatterns, "intern")
-		case "New Grad":
-			levelPatterns = append(levelPatterns, "new grad", "new graduate")
-		case "Entry Level":
-			levelPatterns = append(levelPatterns, "entry level", "entry-level")
-		case "Junior":
-			levelPatterns = append(levelPatterns, "junior")
-		case "Associate":
-			levelPatterns = append(levelPatterns, "associate")
-		case "Apprentice":
-			levelPatterns = append(levelPatterns, "apprentice")
-		case "Fellow":
-			levelPatterns = append(levelPatterns, "fellow")
-		case "Co-op":
-			levelPatterns = append(levelPatterns, "co-op", "co op", "coop")
-		}
+// highlightSnippet escapes a search snippet and marks its matched terms.
+func highlightSnippet(s string) template.HTML {
+	s = template.HTMLEscapeString(s)
+	s = strings.ReplaceAll(s, db.SnippetOpen, "<mark>")
+	s = strings.ReplaceAll(s, db.SnippetClose, "</mark>")
+	return template.HTML(s)
+}
+
+// jobFilterFromQuery reads the job filters shared by /results and
+// /download-csv. A missing status means "Not Applied"; an empty one means
+// any status. Dates are YYYY-MM-DD, and added_before includes that day.
+// A search without a sort is ordered by relevance.
+func jobFilterFromQuery(r *http.Request) db.JobFilter {
+	q := r.URL.Query()
+	f := db.JobFilter{
+		Status:        db.StatusNotApplied,
+		Starred:       q.Get("starred") == "1",
+		IncludeHidden: q.Get("hidden") == "1",
+		Tags:          q["tag"],
+		Search:        strings.TrimSpace(q.Get("q")),
+		Company:       q.Get("company"),
+		Location:      q.Get("location"),
+		Levels:        q["level"],
+		Sort:          db.JobSort(q.Get("sort")),
+		IncludeClosed: q.Get("closed") == "1",
+		Profile:       q.Get("profile"),
+		Companies:     companyFilterFromQuery(r),
+	}
+	if _, ok := q["status"]; ok {
+		f.Status = q.Get("status")
+	}
+	// Searches rank by relevance unless another order is picked
+	if f.Search != "" && f.Sort == "" {
+		f.Sort = db.SortRelevance
+	}
+	f.CompanyID, _ = strconv.Atoi(q.Get("company_id"))
+	if t, err := time.Parse("2006-01-02", q.Get("added_after")); err == nil {
+		f.AddedAfter = t
+	}
+	if t, err := time.Parse("2006-01-02", q.Get("added_before")); err == nil {
+		f.AddedBefore = t.AddDate(0, 0, 1)
+	}
+	return f
+}
+
+// companyFilterFromQuery reads the YC directory filters: batch ("W24" or
+// "W24+" for that batch and later), industry, and team_lt (team size below).
+func companyFilterFromQuery(r *http.Request) db.CompanyFilter {
+	f := db.CompanyFilter{
+		Batch:    r.URL.Query().Get("batch"),
+		Industry: r.URL.Query().Get("industry"),
 	}
-	if len(levelPatterns) > 0 {
-		var parts []string
-		for _, pat := range levelPatterns {
-			parts = append(parts, fmt.Sprintf("title LIKE $%d COLLATE NOCASE", len(args)+1))
-			args = append(args, "%"+pat+"%")
-		}
-		clauses = append(clauses, "("+strings.Join(parts, " OR ")+")")
+	// An unescaped "W24+" typed into the address bar arrives as "W24 "
+	if strings.HasSuffix(f.Batch, " ") {
+		f.Batch = strings.TrimSpace(f.Batch) + "+"
 	}
+	f.MaxTeamSize, _ = strconv.Atoi(r.URL.Query().Get("team_lt"))
+	return f
+}
 
-	where := ""
-	if len(clauses) > 0 {
-		where = " WHERE " + strings.Join(clauses, " AND ")
+// downloadCSVHandler exports filtered job results as CSV (authenticated)
+func (s *server) downloadCSVHandler(w http.ResponseWriter, r *http.Request) {
+	filter := jobFilterFromQuery(r)
+
+	d := s.db
+	uid, err := s.userID(r)
+	if err != nil {
+		logger.Error("look up user: %v", err)
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
 	}
+	filter.UserID = uid
 
-	// Select all fields for CSV
-	dataQ := fmt.Sprintf(
-		"SELECT title, company, location, type, url, date_added, status FROM job_applications%s ORDER BY date_added DESC",
-		where,
-	)
-	rows, err := d.Conn.Query(dataQ, args...)
+	jobs, err := d.ListJobs(filter, 0, 0)
 	if err != nil {
 		logger.Error("list query: %v", err)
 		http.Error(w, `{"error":"list query"}`, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", "attachment; filename=jobs.csv")
-	w.Write([]byte("Title,Company,Location,Type,URL,Date Added,Status\n"))
+	w.Write([]byte("Title,Company,Location,Type,URL,Date Added,Status,Tags\n"))
 
 	// CSV escaping helper
 	escape := func(s string) string {
@@ -1004,18 +1440,179 @@ func downloadCSVHandler(w http.ResponseWriter, r *http.Request) {
 		return s
 	}
 
-	for rows.Next() {
-		var title, company, loc, typ, url, dateAdded, st string
-		if err := rows.Scan(&title, &company, &loc, &typ, &url, &dateAdded, &st); err != nil {
-			continue
-		}
-		line := fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s\n", escape(title), escape(company), escape(loc), escape(typ), escape(url), escape(dateAdded), escape(st))
+	for _, j := range jobs {
+		line := fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s,%s\n", escape(j.Title), escape(j.Company), escape(j.Location), escape(j.Type),
+			escape(j.URL), escape(j.DateAdded.Format(time.RFC3339)), escape(j.Status), escape(strings.Join(j.Tags, ", ")))
 		w.Write([]byte(line))
 	}
 }
 
-// markAppliedHandler updates job status via POST (authenticated)
-func markAppliedHandler(w http.ResponseWriter, r *http.Request) {
+// jobDetailHandler shows a single job with its change timeline (authenticated)
+func (s *server) jobDetailHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	d := s.db
+
+	job, err := d.GetJob(id)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logger.Error("get job: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	versions, err := d.ListJobVersions(id)
+	if err != nil {
+		logger.Error("list job versions: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	sources, err := d.ListJobSources(id)
+	if err != nil {
+		logger.Error("list job sources: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	if job.Profiles, err = d.JobProfiles(id); err != nil {
+		logger.Error("list job profiles: %v", err)
+	}
+	var (
+		history     []db.StatusChange
+		notes       []db.Note
+		attachments []db.Attachment
+	)
+	if uid, err := s.userID(r); err != nil {
+		logger.Error("look up user: %v", err)
+	} else if uj, err := d.GetUserJob(uid, id); err != nil {
+		logger.Error("get user job: %v", err)
+	} else {
+		job.Status, job.Starred, job.Hidden = uj.Status, uj.Starred, uj.Hidden
+		if history, err = d.ListStatusHistory(uid, id); err != nil {
+			logger.Error("list status history: %v", err)
+		}
+		if notes, err = d.ListJobNotes(uid, id); err != nil {
+			logger.Error("list notes: %v", err)
+		}
+		if job.Tags, err = d.ListJobTags(uid, id); err != nil {
+			logger.Error("list tags: %v", err)
+		}
+		if attachments, err = d.ListJobAttachments(uid, id); err != nil {
+			logger.Error("list attachments: %v", err)
+		}
+	}
+
+	// Long descriptions are shown as a line diff instead of old → new
+	type change struct {
+		db.FieldChange
+		Lines []textdiff.Line
+	}
+	type version struct {
+		ChangedAt time.Time
+		Changes   []change
+	}
+	var timeline []version
+	for _, v := range versions {
+		tv := version{ChangedAt: v.ChangedAt}
+		for _, c := range v.Changes {
+			tc := change{FieldChange: c}
+			if c.Field == "description" {
+				tc.Lines = textdiff.Changed(textdiff.Lines(c.Old, c.New))
+			}
+			tv.Changes = append(tv.Changes, tc)
+		}
+		timeline = append(timeline, tv)
+	}
+
+	// The last status change can always be undone
+	var lastChange *db.StatusChange
+	if len(history) > 0 {
+		lastChange = &history[len(history)-1]
+	}
+
+	jt := template.Must(template.New("job").Funcs(template.FuncMap{"markdown": renderNote}).Parse(jobHTML))
+	data := struct {
+		Job         db.Job
+		Next        []string
+		History     []db.StatusChange
+		Notes       []db.Note
+		Attachments []db.Attachment
+		Versions    []version
+		Sources     []db.Job
+	}{Job: job, Next: s.pipeline.NextAfter(job.Status, lastChange), History: history, Notes: notes, Attachments: attachments,
+		Versions: timeline, Sources: sources}
+	if err := jt.Execute(w, data); err != nil {
+		logger.Error("job template: %v", err)
+	}
+}
+
+// companiesHandler lists the companies catalog (authenticated)
+func (s *server) companiesHandler(w http.ResponseWriter, r *http.Request) {
+	d := s.db
+
+	companies, err := d.ListCompanies()
+	if err != nil {
+		logger.Error("list companies: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	ct := template.Must(template.New("companies").Parse(companiesHTML))
+	if err := ct.Execute(w, struct{ Companies []db.Company }{companies}); err != nil {
+		logger.Error("companies template: %v", err)
+	}
+}
+
+// companyHandler shows one company and saves its notes on POST (authenticated)
+func (s *server) companyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid company id", http.StatusBadRequest)
+		return
+	}
+
+	d := s.db
+
+	if r.Method == http.MethodPost {
+		err := d.SetCompanyNotes(id, strings.TrimSpace(r.FormValue("notes")))
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			logger.Error("set company notes: %v", err)
+			http.Error(w, "Update error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/company?id=%d", id), http.StatusSeeOther)
+		return
+	}
+
+	company, err := d.GetCompany(id)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logger.Error("get company: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	if company.OpenJobs, err = d.CountJobs(db.JobFilter{CompanyID: id}); err != nil {
+		logger.Error("count company jobs: %v", err)
+	}
+	ct := template.Must(template.New("company").Parse(companyHTML))
+	if err := ct.Execute(w, struct{ Company db.Company }{company}); err != nil {
+		logger.Error("company template: %v", err)
+	}
+}
+
+// unmergeHandler splits a duplicate listing back out into its own job (authenticated)
+func (s *server) unmergeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -1027,51 +1624,117 @@ func markAppliedHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"success":false}`, http.StatusBadRequest)
 		return
 	}
-	d, err := db.Connect()
+	d := s.db
+
+	err := d.UnmergeJob(req.ID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"success":false}`, http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		logger.Error("db connect: %v", err)
+		logger.Error("unmerge job: %v", err)
 		http.Error(w, `{"success":false}`, http.StatusInternalServerError)
 		return
 	}
-	defer d.Close()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"success":true}`))
+}
+
+// setStatusHandler moves a job to another pipeline stage for the
+// logged-in user via POST (authenticated). Moves the pipeline does not allow
+// are refused with 409 Conflict.
+func (s *server) setStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID     int    `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !s.pipeline.Has(req.Status) {
+		http.Error(w, `{"success":false,"error":"unknown status"}`, http.StatusBadRequest)
+		return
+	}
+	d := s.db
+	uid, err := s.userID(r)
+	if err != nil {
+		logger.Error("look up user: %v", err)
+		http.Error(w, `{"success":false}`, http.StatusUnauthorized)
+		return
+	}
 
-	// Read current status
-	var curStatus string
-	row := d.Conn.QueryRow(`SELECT status FROM job_applications WHERE id = $1`, req.ID)
-	if err := row.Scan(&curStatus); err != nil {
-		logger.Error("fetch current status: %v", err)
+	w.Header().Set("Content-Type", "application/json")
+	err = d.SetJobStatus(s.pipeline, uid, req.ID, req.Status)
+	var terr *db.TransitionError
+	switch {
+	case errors.As(err, &terr):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": terr.Error()})
+		return
+	case err == sql.ErrNoRows:
+		http.Error(w, `{"success":false}`, http.StatusNotFound)
+		return
+	case err != nil:
+		logger.Error("update status: %v", err)
 		http.Error(w, `{"success":false}`, http.StatusInternalServerError)
 		return
 	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "status": req.Status})
+}
 
-	// Toggle status
-	newStatus := "Applied"
-	if curStatus == "Applied" {
-		newStatus = "Not Applied"
+// jobFlagHandler stars or hides a job for the logged-in user via POST
+// (authenticated)
+func (s *server) jobFlagHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID    int    `json:"id"`
+		Flag  string `json:"flag"`
+		Value bool   `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"success":false}`, http.StatusBadRequest)
+		return
+	}
+	d := s.db
+	uid, err := s.userID(r)
+	if err != nil {
+		logger.Error("look up user: %v", err)
+		http.Error(w, `{"success":false}`, http.StatusUnauthorized)
+		return
 	}
 
-	_, err = d.Conn.Exec(`UPDATE job_applications SET status = $1 WHERE id = $2`, newStatus, req.ID)
+	switch req.Flag {
+	case "starred":
+		err = d.SetJobStarred(uid, req.ID, req.Value)
+	case "hidden":
+		err = d.SetJobHidden(uid, req.ID, req.Value)
+	default:
+		http.Error(w, `{"success":false}`, http.StatusBadRequest)
+		return
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"success":false}`, http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		logger.Error("update status: %v", err)
+		logger.Error("set job flag: %v", err)
 		http.Error(w, `{"success":false}`, http.StatusInternalServerError)
 		return
 	}
-
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(fmt.Sprintf(`{"success":true, "status":"%s"}`, newStatus)))
+	w.Write([]byte(`{"success":true}`))
 }
 
 // initAdminHandler creates admin user if it doesn't exist (for Render deployment)
-func initAdminHandler(w http.ResponseWriter, r *http.Request) {
-	d, err := db.Connect()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Database connection failed: %v", err), http.StatusInternalServerError)
-		return
-	}
-	defer d.Close()
+func (s *server) initAdminHandler(w http.ResponseWriter, r *http.Request) {
+	d := s.db
 
 	// Check if admin user already exists
-	_, _, _, err = d.GetUserByUsername("admin")
+	_, _, _, err := d.GetUserByUsername("admin")
 	if err == nil {
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(`
@@ -1120,7 +1783,7 @@ type JobImport struct {
 }
 
 // importJobsHandler imports jobs from a JSON URL (for Render deployment)
-func importJobsHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) importJobsHandler(w http.ResponseWriter, r *http.Request) {
 	jsonURL := r.URL.Query().Get("url")
 	if jsonURL == "" {
 		w.Header().Set("Content-Type", "text/html")
@@ -1175,20 +1838,13 @@ func importJobsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Connect to database
-	d, err := db.Connect()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Database connection failed: %v", err), http.StatusInternalServerError)
-		return
-	}
-	defer d.Close()
+	d := s.db
 
 	// Import jobs
-	imported := 0
-	for _, job := range jobs {
-		err = d.InsertJobTyped(job.Title, job.Company, job.Location, job.Type, job.URL)
-		if err == nil {
-			imported++
-		}
+	counts, err := importJobs(d, jobs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Import failed: %v", err), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "text/html")
@@ -1198,9 +1854,20 @@ func importJobsHandler(w http.ResponseWriter, r *http.Request) {
 	<body style="font-family: Arial; text-align: center; padding: 50px;">
 		<h2>✅ Jobs Imported Successfully!</h2>
 		<p><strong>%d out of %d jobs imported</strong></p>
-		<p>(Duplicates are automatically skipped)</p>
+		<p>(%d already known, %d failed)</p>
 		<a href="/login" style="background: #007bff; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px;">Go to Dashboard</a>
-	</body></html>`, imported, len(jobs))))
+	</body></html>`, counts.Inserted, len(jobs), counts.Updated+counts.Unchanged, counts.Failed)))
+}
+
+// importJobs stores imported jobs in one batch. Each job counts as seen
+// when it was added, so importing an older export neither reopens jobs
+// closed since nor reverts later edits.
+func importJobs(d *db.DB, jobs []JobImport) (db.UpsertCounts, error) {
+	batch := make([]db.Job, len(jobs))
+	for i, j := range jobs {
+		batch[i] = db.Job{Title: j.Title, Company: j.Company, Location: j.Location, Type: j.Type, URL: j.URL, SeenAt: j.DateAdded}
+	}
+	return d.UpsertJobs(context.Background(), batch)
 }
 
 // importStatusHandler returns JSON with the last import status.
@@ -1262,12 +1929,11 @@ func importJobsFromURL(jsonURL string, database *db.DB) (int, error) {
 		}
 	}
 
-	imported := 0
-	for _, job := range jobs {
-		if err := database.InsertJobTyped(job.Title, job.Company, job.Location, job.Type, job.URL); err == nil {
-			imported++
-		}
+	counts, err := importJobs(database, jobs)
+	if err != nil {
+		return 0, err
 	}
+	imported := counts.Inserted
 
 	// update last import status
 	status := importStatus{Count: int32(imported), Time: time.Now(), Error: ""}
@@ -1277,13 +1943,8 @@ func importJobsFromURL(jsonURL string, database *db.DB) (int, error) {
 }
 
 // quickSetupHandler creates sample jobs for testing (for Render deployment)
-func quickSetupHandler(w http.ResponseWriter, r *http.Request) {
-	d, err := db.Connect()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Database connection failed: %v", err), http.StatusInternalServerError)
-		return
-	}
-	defer d.Close()
+func (s *server) quickSetupHandler(w http.ResponseWriter, r *http.Request) {
+	d := s.db
 
 	// Sample jobs to insert
 	sampleJobs := []struct {
@@ -1304,7 +1965,7 @@ func quickSetupHandler(w http.ResponseWriter, r *http.Request) {
 	// Insert sample jobs
 	inserted := 0
 	for _, job := range sampleJobs {
-		err = d.InsertJobTyped(job.title, job.company, job.location, job.jobType, job.url)
+		err := d.InsertJobTyped(job.title, job.company, job.location, job.jobType, job.url)
 		if err == nil {
 			inserted++
 		}
@@ -1382,6 +2043,21 @@ func autoInitialize(database *db.DB) {
 	logger.Info("Database initialization complete")
 } // -------------------- MAIN --------------------
 
+// backupAndPrune takes a scheduled backup and removes the backups the
+// default retention no longer keeps.
+func backupAndPrune(d *db.DB, dir string) {
+	path, err := d.Backup(dir)
+	if err != nil {
+		logger.Error("Scheduled backup failed: %v", err)
+		return
+	}
+	removed, err := d.PruneBackups(dir, db.DefaultRetention)
+	if err != nil {
+		logger.Error("Pruning backups: %v", err)
+	}
+	logger.Info("Scheduled backup written to %s (%d old backups removed)", path, len(removed))
+}
+
 func main() {
 	// Check for PORT environment variable (required for Render)
 	port := os.Getenv("PORT")
@@ -1398,6 +2074,16 @@ func main() {
 		logger.Fatal("Failed to connect to DB: %v", err)
 	}
 
+	defer database.Close()
+	s := &server{db: database, pipeline: db.DefaultPipeline}
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		cfg, err := config.Load(path)
+		if err != nil {
+			logger.Fatal("Failed to load config: %v", err)
+		}
+		s.pipeline = cfg.Pipeline.Application()
+	}
+
 	// Auto-initialize for Render deployment
 	autoInitialize(database)
 
@@ -1445,29 +2131,58 @@ func main() {
 		}
 	}
 
+	// Optional scheduled backups: BACKUP_INTERVAL (minutes) into BACKUP_DIR
+	if backupInterval := os.Getenv("BACKUP_INTERVAL"); backupInterval != "" {
+		minInt, err := strconv.Atoi(backupInterval)
+		if err == nil && minInt > 0 {
+			dir := os.Getenv("BACKUP_DIR")
+			if dir == "" {
+				dir = "data/backups"
+			}
+			logger.Info("Scheduled backups enabled: every %d minutes to %s", minInt, dir)
+			go func(d *db.DB, t time.Duration) {
+				ticker := time.NewTicker(t)
+				defer ticker.Stop()
+				for range ticker.C {
+					backupAndPrune(d, dir)
+				}
+			}(database, time.Duration(minInt)*time.Minute)
+		} else if err != nil {
+			logger.Error("Invalid BACKUP_INTERVAL value: %v", err)
+		}
+	}
+
 	// All routes are now based on the authenticated logic
 	http.HandleFunc("/", rootHandler)
-	http.HandleFunc("/login", loginHandler)
-	http.HandleFunc("/register", registerHandler)
+	http.HandleFunc("/login", s.loginHandler)
+	http.HandleFunc("/register", s.registerHandler)
 	http.HandleFunc("/logout", logoutHandler)
 
 	// Admin initialization route (for Render deployment)
-	http.HandleFunc("/init-admin", initAdminHandler)
+	http.HandleFunc("/init-admin", s.initAdminHandler)
 
 	// Job import route (for Render deployment)
-	http.HandleFunc("/import-jobs", importJobsHandler)
+	http.HandleFunc("/import-jobs", s.importJobsHandler)
 	// Import status route
 	http.HandleFunc("/import-status", importStatusHandler)
 
 	// Quick setup route (for Render deployment)
-	http.HandleFunc("/quick-setup", quickSetupHandler)
+	http.HandleFunc("/quick-setup", s.quickSetupHandler)
 
 	// Protected routes (UI from main (1).go, logic from main.go)
-	http.HandleFunc("/filters", AuthRequired(filtersHandler))
+	http.HandleFunc("/filters", AuthRequired(s.filtersHandler))
 	http.HandleFunc("/dashboard", AuthRequired(dashboardHandler))
-	http.HandleFunc("/results", AuthRequired(resultsHandler))
-	http.HandleFunc("/download-csv", AuthRequired(downloadCSVHandler))
-	http.HandleFunc("/mark-applied", AuthRequired(markAppliedHandler))
+	http.HandleFunc("/results", AuthRequired(s.resultsHandler))
+	http.HandleFunc("/job", AuthRequired(s.jobDetailHandler))
+	http.HandleFunc("/companies", AuthRequired(s.companiesHandler))
+	http.HandleFunc("/company", AuthRequired(s.companyHandler))
+	http.HandleFunc("/unmerge", AuthRequired(s.unmergeHandler))
+	http.HandleFunc("/download-csv", AuthRequired(s.downloadCSVHandler))
+	http.HandleFunc("/set-status", AuthRequired(s.setStatusHandler))
+	http.HandleFunc("/job-flag", AuthRequired(s.jobFlagHandler))
+	http.HandleFunc("/notes", AuthRequired(s.notesHandler))
+	http.HandleFunc("/tags", AuthRequired(s.tagsHandler))
+	http.HandleFunc("/attachments", AuthRequired(s.attachmentsHandler))
 
 	logger.Info("Listening on http://localhost:%s", port)
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/markdown"
+)
+
+// maxAttachmentSize bounds one uploaded file.
+const maxAttachmentSize = 10 << 20
+
+// inlineTypes are the attachment types served as themselves; anything else
+// the uploader claimed is sent as application/octet-stream, so a stored
+// file can never run as a page on the dashboard's origin.
+var inlineTypes = map[string]string{
+	"application/pdf": "application/pdf",
+	"image/png":       "image/png",
+	"image/jpeg":      "image/jpeg",
+	"text/plain":      "text/plain; charset=utf-8",
+}
+
+// servedType returns the Content-Type to download an attachment with.
+func servedType(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		if t, ok := inlineTypes[mt]; ok {
+			return t
+		}
+	}
+	return "application/octet-stream"
+}
+
+// renderNote renders a note body's markdown for the job page. The renderer
+// escapes any HTML in the note first, so the result is safe to trust.
+func renderNote(body string) template.HTML {
+	return template.HTML(markdown.ToHTML(body))
+}
+
+// writeJSON sends v as a JSON response with the status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeDBError answers a failed notes, tags or attachments call: 404 for
+// a missing job or item, 500 otherwise.
+func writeDBError(w http.ResponseWriter, what string, err error) {
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not found"})
+		return
+	}
+	logger.Error("%s: %v", what, err)
+	writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false})
+}
+
+// notesHandler lists (GET ?job=ID), adds (POST {"job", "body"}) and
+// deletes (DELETE ?id=ID) the logged-in user's markdown notes on a job
+// (authenticated)
+func (s *server) notesHandler(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.userID(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false})
+		return
+	}
+	d := s.db
+
+	switch r.Method {
+	case http.MethodGet:
+		jobID, _ := strconv.Atoi(r.URL.Query().Get("job"))
+		notes, err := d.ListJobNotes(uid, jobID)
+		if err != nil {
+			writeDBError(w, "list notes", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, notes)
+	case http.MethodPost:
+		var req struct {
+			Job  int    `json:"job"`
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Body == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "job and body are required"})
+			return
+		}
+		id, err := d.AddJobNote(uid, req.Job, req.Body)
+		if err != nil {
+			writeDBError(w, "add note", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "id": id})
+	case http.MethodDelete:
+		id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+		if err := d.DeleteJobNote(uid, id); err != nil {
+			writeDBError(w, "delete note", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// tagsHandler lists (GET ?job=ID, or every tag without job), adds (POST
+// {"job", "tag"}) and removes (DELETE ?job=ID&tag=name) the logged-in
+// user's tags on a job (authenticated)
+func (s *server) tagsHandler(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.userID(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false})
+		return
+	}
+	d := s.db
+	jobID, _ := strconv.Atoi(r.URL.Query().Get("job"))
+
+	switch r.Method {
+	case http.MethodGet:
+		var tags []string
+		if jobID > 0 {
+			tags, err = d.ListJobTags(uid, jobID)
+		} else {
+			tags, err = d.ListUserTags(uid)
+		}
+		if err != nil {
+			writeDBError(w, "list tags", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, tags)
+	case http.MethodPost:
+		var req struct {
+			Job int    `json:"job"`
+			Tag string `json:"tag"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false})
+			return
+		}
+		err := d.AddJobTag(uid, req.Job, req.Tag)
+		if err == db.ErrBadTag {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeDBError(w, "add tag", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+	case http.MethodDelete:
+		if err := d.DeleteJobTag(uid, jobID, r.URL.Query().Get("tag")); err != nil && err != db.ErrBadTag {
+			writeDBError(w, "delete tag", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// attachmentsHandler lists (GET ?job=ID), downloads (GET ?id=ID), uploads
+// (POST multipart with job and file) and deletes (DELETE ?id=ID) the
+// logged-in user's files kept with a job (authenticated)
+func (s *server) attachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.userID(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false})
+		return
+	}
+	d := s.db
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+
+	switch {
+	case r.Method == http.MethodGet && id > 0:
+		a, err := d.GetJobAttachment(uid, id)
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			logger.Error("get attachment: %v", err)
+			http.Error(w, "Query error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", servedType(a.ContentType))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+		w.Write(a.Data)
+	case r.Method == http.MethodGet:
+		jobID, _ := strconv.Atoi(r.URL.Query().Get("job"))
+		list, err := d.ListJobAttachments(uid, jobID)
+		if err != nil {
+			writeDBError(w, "list attachments", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	case r.Method == http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "a file of at most 10 MB is required"})
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, maxAttachmentSize+1))
+		if err != nil || len(data) > maxAttachmentSize {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "a file of at most 10 MB is required"})
+			return
+		}
+		contentType := header.Header.Get("Content-Type")
+		if contentType == "" || contentType == "application/octet-stream" {
+			contentType = http.DetectContentType(data)
+		}
+		jobID, _ := strconv.Atoi(r.FormValue("job"))
+		id, err := d.AddJobAttachment(uid, jobID, header.Filename, contentType, data)
+		if err != nil {
+			writeDBError(w, "add attachment", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "id": id})
+	case r.Method == http.MethodDelete:
+		if err := d.DeleteJobAttachment(uid, id); err != nil {
+			writeDBError(w, "delete attachment", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
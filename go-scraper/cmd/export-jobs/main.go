@@ -2,11 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/exporter"
 )
 
 type JobExport struct {
@@ -17,9 +19,13 @@ type JobExport struct {
 	URL       string    `json:"url"`
 	DateAdded time.Time `json:"date_added"`
 	Status    string    `json:"status"`
+	Tags      []string  `json:"tags,omitempty"`
 }
 
 func main() {
+	user := flag.String("user", "", "include this dashboard user's application statuses and tags")
+	flag.Parse()
+
 	// Connect to local database
 	database, err := db.Connect()
 	if err != nil {
@@ -28,8 +34,19 @@ func main() {
 	}
 	defer database.Close()
 
+	// Statuses are those of the given dashboard user, if any; jobs the
+	// user hid are still exported
+	var uid int
+	if *user != "" {
+		if uid, _, _, err = database.GetUserByUsername(*user); err != nil {
+			fmt.Printf("Unknown user %q: %v\n", *user, err)
+			os.Exit(1)
+		}
+	}
+	filter := exporter.UserFilter(uid)
+
 	// Get all jobs
-	jobs, err := database.ListJobs(db.JobFilter{}, 1, 1000)
+	jobs, err := database.ListJobs(filter, 0, 0)
 	if err != nil {
 		fmt.Printf("Failed to fetch jobs: %v\n", err)
 		os.Exit(1)
@@ -46,6 +63,7 @@ func main() {
 			URL:       job.URL,
 			DateAdded: job.DateAdded,
 			Status:    job.Status,
+			Tags:      job.Tags,
 		})
 	}
 
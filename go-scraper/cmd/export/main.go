@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"html/template"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/exporter"
 )
 
 type Job struct {
@@ -19,6 +21,7 @@ type Job struct {
 	URL       string    `json:"url"`
 	DateAdded time.Time `json:"date_added"`
 	Status    string    `json:"status"`
+	Tags      []string  `json:"tags,omitempty"`
 }
 
 const htmlTemplate = `<!DOCTYPE html>
@@ -88,6 +91,7 @@ const htmlTemplate = `<!DOCTYPE html>
                 <div class="job-location">📍 {{.Location}}</div>
                 <div class="job-type">{{.Type}}</div>
                 <div class="job-date">📅 {{.DateAdded.Format "Jan 2, 2006"}}</div>
+                {{if .Tags}}<div class="job-type">{{range .Tags}}#{{.}} {{end}}</div>{{end}}
                 <div class="job-status {{if eq .Status "Applied"}}status-applied{{else}}status-not-applied{{end}}">
                     {{.Status}}
                 </div>
@@ -124,6 +128,9 @@ const htmlTemplate = `<!DOCTYPE html>
 </html>`
 
 func main() {
+	user := flag.String("user", "", "include this dashboard user's application statuses and tags")
+	flag.Parse()
+
 	// Connect to database
 	database, err := db.Connect()
 	if err != nil {
@@ -132,8 +139,19 @@ func main() {
 	}
 	defer database.Close()
 
+	// Statuses are those of the given dashboard user, if any; jobs the
+	// user hid are still exported
+	var uid int
+	if *user != "" {
+		if uid, _, _, err = database.GetUserByUsername(*user); err != nil {
+			fmt.Printf("Unknown user %q: %v\n", *user, err)
+			os.Exit(1)
+		}
+	}
+	filter := exporter.UserFilter(uid)
+
 	// Get all jobs
-	jobs, err := database.ListJobs(db.JobFilter{}, 1, 1000) // Get first 1000 jobs
+	jobs, err := database.ListJobs(filter, 0, 0)
 	if err != nil {
 		fmt.Printf("Failed to fetch jobs: %v\n", err)
 		os.Exit(1)
@@ -152,6 +170,7 @@ func main() {
 			URL:       job.URL,
 			DateAdded: job.DateAdded,
 			Status:    job.Status,
+			Tags:      job.Tags,
 		})
 		if job.Status == "Applied" {
 			applied++
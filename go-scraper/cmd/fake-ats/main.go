@@ -0,0 +1,36 @@
+// Command fake-ats serves Greenhouse and Lever API responses from fixture
+// files, with optional latency, rate limiting, 5xx bursts and malformed
+// bodies, so the scraper can run end to end offline. Point the scraper at
+// it with SCRAPER_API_BASE=http://localhost:8089.
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/fakeats"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8089", "Address to listen on")
+	dir := flag.String("fixtures", "testdata/fake-ats", "Fixture directory (<platform>/<slug>.json[.gz], optional faults.yaml)")
+	var f fakeats.Faults
+	flag.DurationVar(&f.Latency, "latency", 0, "Delay before every response")
+	flag.IntVar(&f.RateLimitEvery, "rate-limit-every", 0, "Answer every Nth request to a board with 429")
+	flag.IntVar(&f.RetryAfter, "retry-after", 0, "Retry-After seconds sent with a 429")
+	flag.IntVar(&f.ErrorBurst, "error-burst", 0, "Answer this many consecutive requests to a board with 503")
+	flag.IntVar(&f.ErrorEvery, "error-every", 0, "Repeat the 503 burst every N requests (0: only the first requests)")
+	flag.BoolVar(&f.Malformed, "malformed", false, "Truncate every response body")
+	flag.Parse()
+	logger.InitFromEnv()
+
+	s, err := fakeats.New(*dir, f)
+	if err != nil {
+		logger.Fatal("load fixtures: %v", err)
+	}
+	logger.Info("Serving fixtures from %s on http://%s", *dir, *addr)
+	if err := http.ListenAndServe(*addr, s); err != nil {
+		logger.Fatal("Server failed: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+)
+
+const defaultBackupDir = "data/backups"
+
+// runDB implements "scraper db backup|backups|restore|archive". backup
+// takes a backup and prunes old ones, backups lists them, and restore
+// replaces the database with one, after taking a backup of the current
+// state. archive applies the config's retention rules. It returns the
+// process exit code.
+func runDB(args []string, w io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(w, "usage: scraper db backup|backups|restore|archive [flags]")
+		return 2
+	}
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("db "+cmd, flag.ContinueOnError)
+	dir := fs.String("dir", defaultBackupDir, "Backup directory")
+	keepLast := fs.Int("keep-last", db.DefaultRetention.Last, "Keep this many newest backups (backup only)")
+	keepDaily := fs.Int("keep-daily", db.DefaultRetention.Daily, "Keep the newest backup of this many days (backup only)")
+	keepWeekly := fs.Int("keep-weekly", db.DefaultRetention.Weekly, "Keep the newest backup of this many weeks (backup only)")
+	noSafety := fs.Bool("no-safety-backup", false, "Do not back up the current database before restoring (restore only)")
+	cfgPath := fs.String("config", defaultConfigPath, "Config with the retention rules (archive only)")
+	dryRun := fs.Bool("dry-run", false, "List the jobs that would be archived without moving them (archive only)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// restore validates the backup's schema before migrating anything
+	open := db.Connect
+	if cmd == "restore" {
+		open = db.Open
+	}
+	d, err := open()
+	if err != nil {
+		fmt.Fprintf(w, "db open: %v\n", err)
+		return 1
+	}
+	defer d.Close()
+
+	switch cmd {
+	case "backup":
+		var path string
+		if path, err = d.Backup(*dir); err != nil {
+			break
+		}
+		fmt.Fprintf(w, "Backed up %s to %s\n", d.Path, path)
+		var removed []db.BackupFile
+		removed, err = d.PruneBackups(*dir, db.Retention{Last: *keepLast, Daily: *keepDaily, Weekly: *keepWeekly})
+		for _, b := range removed {
+			fmt.Fprintf(w, "Removed %s\n", b.Path)
+		}
+	case "backups":
+		err = listBackups(d, *dir, w)
+	case "restore":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(w, "usage: scraper db restore [-no-safety-backup] [-dir dir] <backup file>")
+			return 2
+		}
+		src := fs.Arg(0)
+		if _, err = db.CheckBackup(src); err != nil {
+			break
+		}
+		if !*noSafety {
+			var path string
+			if path, err = d.Backup(*dir); err != nil {
+				break
+			}
+			fmt.Fprintf(w, "Backed up the current database to %s\n", path)
+		}
+		if err = d.Restore(src); err == nil {
+			fmt.Fprintf(w, "Restored %s from %s\n", d.Path, src)
+		}
+	case "archive":
+		var cfg *config.Config
+		if cfg, err = config.Load(*cfgPath); err != nil {
+			break
+		}
+		err = archiveJobs(d, cfg, *dryRun, w)
+	default:
+		fmt.Fprintf(w, "unknown db command %q (want backup, backups, restore or archive)\n", cmd)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	return 0
+}
+
+// listBackups prints the backups in dir, newest first.
+func listBackups(d *db.DB, dir string, w io.Writer) error {
+	backups, err := d.ListBackups(dir)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Fprintf(w, "No backups in %s\n", dir)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TAKEN (UTC)\tSIZE\tFILE")
+	for _, b := range backups {
+		fmt.Fprintf(tw, "%s\t%d KB\t%s\n", b.TakenAt.Format("2006-01-02 15:04:05"), (b.Size+1023)/1024, b.Path)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/scraper"
+)
+
+// runCompanies implements "scraper companies [list]" and
+// "scraper companies enable <platform/slug>...". It returns the process
+// exit code.
+func runCompanies(args []string, w io.Writer) int {
+	d, err := db.Connect()
+	if err != nil {
+		fmt.Fprintf(w, "db connect: %v\n", err)
+		return 1
+	}
+	defer d.Close()
+
+	cmd := "list"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "list":
+		err = listCompanyHealth(d, w)
+	case "enable":
+		if len(args) == 0 {
+			fmt.Fprintln(w, "usage: scraper companies enable <platform/slug>...")
+			return 2
+		}
+		err = enableCompanies(d, args, w)
+	default:
+		fmt.Fprintf(w, "unknown companies command %q (want list or enable)\n", cmd)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	return 0
+}
+
+// listCompanyHealth prints the companies that are failing or disabled.
+func listCompanyHealth(d *db.DB, w io.Writer) error {
+	health, err := d.ListCompanyHealth()
+	if err != nil {
+		return err
+	}
+	if len(health) == 0 {
+		fmt.Fprintln(w, "All companies are healthy")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPANY\tSTATE\tFAILURES\tLAST ERROR")
+	for _, h := range health {
+		state := "failing"
+		switch {
+		case h.DisabledAt != nil:
+			state = "disabled since " + h.DisabledAt.Local().Format("2006-01-02")
+		case h.NextAttemptAt != nil && h.NextAttemptAt.After(time.Now()):
+			state = "backing off until " + h.NextAttemptAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "%s/%s\t%s\t%d\t%s\n", h.Platform, h.Slug, state, h.ConsecutiveFailures, h.LastError)
+	}
+	return tw.Flush()
+}
+
+// enableCompanies clears the failures of each named company. A bare slug
+// is looked up on every platform.
+func enableCompanies(d *db.DB, names []string, w io.Writer) error {
+	for _, name := range names {
+		platforms := scraper.Platforms
+		slug := name
+		if i := strings.Index(name, "/"); i >= 0 {
+			platforms, slug = []string{name[:i]}, name[i+1:]
+		}
+		enabled := false
+		for _, p := range platforms {
+			ok, err := d.EnableCompany(p, slug)
+			if err != nil {
+				return err
+			}
+			if ok {
+				fmt.Fprintf(w, "Enabled %s/%s\n", p, slug)
+				enabled = true
+			}
+		}
+		if !enabled {
+			fmt.Fprintf(w, "%s has no recorded failures\n", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
+	"github.com/robfig/cron/v3"
+)
+
+// scheduledTarget is a target with its parsed schedule and next run time.
+type scheduledTarget struct {
+	target
+	Spec    string    `json:"schedule"`
+	NextRun time.Time `json:"next_run"`
+	LastRun time.Time `json:"last_run,omitempty"`
+
+	sched cron.Schedule
+}
+
+// scheduler keeps the next run time of every target.
+type scheduler struct {
+	mu      sync.Mutex
+	entries []*scheduledTarget
+	jitter  time.Duration
+}
+
+func newScheduler(cfg *config.Config, targets []target) (*scheduler, error) {
+	s := &scheduler{}
+	if cfg.Schedule.Jitter != "" {
+		j, err := time.ParseDuration(cfg.Schedule.Jitter)
+		if err != nil {
+			return nil, fmt.Errorf("schedule jitter: %w", err)
+		}
+		s.jitter = j
+	}
+
+	now := time.Now()
+	for _, t := range targets {
+		spec := cfg.Schedule.SpecFor(t.Company)
+		sched, err := cron.ParseStandard(spec)
+		if err != nil {
+			return nil, fmt.Errorf("schedule for %s: %w", t, err)
+		}
+		e := &scheduledTarget{target: t, Spec: spec, sched: sched}
+		e.NextRun = s.next(e, now)
+		s.entries = append(s.entries, e)
+	}
+	if len(s.entries) == 0 {
+		return nil, fmt.Errorf("no companies configured")
+	}
+	return s, nil
+}
+
+// next returns the next run time after now, pushed back by a random jitter
+// so instances and companies sharing a schedule do not fire in lockstep.
+func (s *scheduler) next(e *scheduledTarget, now time.Time) time.Time {
+	t := e.sched.Next(now)
+	if s.jitter > 0 {
+		t = t.Add(time.Duration(rand.Int63n(int64(s.jitter))))
+	}
+	return t
+}
+
+// due returns the targets whose next run has passed, and otherwise the time
+// of the earliest upcoming run.
+func (s *scheduler) due(now time.Time) ([]*scheduledTarget, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*scheduledTarget
+	earliest := time.Time{}
+	for _, e := range s.entries {
+		if !e.NextRun.After(now) {
+			out = append(out, e)
+		} else if earliest.IsZero() || e.NextRun.Before(earliest) {
+			earliest = e.NextRun
+		}
+	}
+	return out, earliest
+}
+
+// advance schedules the next run of targets that just ran.
+func (s *scheduler) advance(ran []*scheduledTarget, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range ran {
+		e.LastRun = now
+		e.NextRun = s.next(e, now)
+	}
+}
+
+// snapshot returns the schedule sorted by next run time.
+func (s *scheduler) snapshot() []scheduledTarget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]scheduledTarget, 0, len(s.entries))
+	for _, e := range s.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].NextRun.Before(out[j].NextRun) })
+	return out
+}
+
+// ServeHTTP reports the next run times as JSON.
+func (s *scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.snapshot())
+}
+
+// runDaemon scrapes each company on its own schedule until interrupted.
+func runDaemon(d *db.DB, cfg *config.Config, targets []target, outPath, statusAddr, reportPath string) error {
+	s, err := newScheduler(cfg, targets)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if statusAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/next-runs", s)
+		srv := &http.Server{Addr: statusAddr, Handler: mux}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("status server: %v", err)
+			}
+		}()
+		defer srv.Close()
+		logger.Info("Serving next run times on http://%s/next-runs", statusAddr)
+	}
+
+	// Retention rules run on their own schedule, between scrapes
+	var archiveSched cron.Schedule
+	var nextArchive time.Time
+	if spec := cfg.Retention.Schedule; spec != "" && len(cfg.Retention.Rules) > 0 {
+		if archiveSched, err = cron.ParseStandard(spec); err != nil {
+			return fmt.Errorf("retention schedule: %w", err)
+		}
+		nextArchive = archiveSched.Next(time.Now())
+		logger.Info("Retention rules run on %q, next at %s", spec, nextArchive.Format(time.RFC3339))
+	}
+
+	logger.Info("Daemon started with %d companies", len(s.entries))
+	for {
+		now := time.Now()
+		if archiveSched != nil && !nextArchive.After(now) {
+			if err := archiveScheduled(d, cfg); err == errLeaseHeld {
+				logger.Info("Another scraper instance is running, retrying retention in 1m")
+				nextArchive = now.Add(time.Minute)
+			} else {
+				if err != nil {
+					logger.Error("retention: %v", err)
+				}
+				nextArchive = archiveSched.Next(now)
+			}
+			continue
+		}
+		due, earliest := s.due(now)
+		if archiveSched != nil && nextArchive.Before(earliest) {
+			earliest = nextArchive
+		}
+		if len(due) == 0 {
+			logger.Info("Next run at %s", earliest.Format(time.RFC3339))
+			timer := time.NewTimer(time.Until(earliest))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				logger.Info("Daemon stopping")
+				return nil
+			case <-timer.C:
+			}
+			continue
+		}
+
+		targets := make([]target, len(due))
+		for i, e := range due {
+			targets[i] = e.target
+		}
+		var report *runReport
+		err := withLease(d, func() (err error) {
+			report, err = runTargets(d, cfg, targets, outPath)
+			return err
+		})
+		if err == errLeaseHeld {
+			// Another instance is running; try again shortly without
+			// advancing the schedule.
+			logger.Info("Another scraper instance is running, retrying in 1m")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Minute):
+			}
+			continue
+		}
+		if err != nil {
+			logger.Error("scheduled run: %v", err)
+		} else if reportPath != "" {
+			if err := report.write(reportPath); err != nil {
+				logger.Error("write report: %v", err)
+			}
+		}
+		s.advance(due, time.Now())
+
+		if ctx.Err() != nil {
+			logger.Info("Daemon stopping")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/discover"
+	"gopkg.in/yaml.v3"
+)
+
+// runDiscover implements "scraper discover <company name or careers URL>":
+// it finds the company's ATS board and prints a config entry for it. It
+// returns the process exit code.
+func runDiscover(args []string, w io.Writer) int {
+	fs := flag.NewFlagSet("discover", flag.ContinueOnError)
+	format := fs.String("format", "table", "Output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	input := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if input == "" {
+		fmt.Fprintln(w, "usage: scraper discover [-format table|json] <company name or careers URL>")
+		return 2
+	}
+
+	res, err := discover.Run(input)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	best, found := res.Best()
+	var entry *config.Company
+	if found {
+		entry = &config.Company{Slug: best.Slug, Platform: best.Platform, Name: best.Name, Host: best.Host}
+		if entry.Name == "" && res.Page == "" {
+			entry.Name = input
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			*discover.Result
+			Entry *config.Company `json:"entry,omitempty"`
+		}{res, entry}); err != nil {
+			fmt.Fprintln(w, err)
+			return 1
+		}
+	} else if err := writeDiscovery(w, res, entry); err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	if !found {
+		return 1
+	}
+	return 0
+}
+
+func writeDiscovery(w io.Writer, res *discover.Result, entry *config.Company) error {
+	if len(res.Candidates) == 0 {
+		fmt.Fprintf(w, "No job board found for %s\n", res.Input)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PLATFORM\tSLUG\tJOBS\tFOUND\tSTATUS")
+	for _, c := range res.Candidates {
+		status, jobs := "ok", fmt.Sprint(c.Jobs)
+		if !c.Verified {
+			status, jobs = c.Error, "-"
+		}
+		if !c.Supported {
+			status = "not supported by the scraper"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Platform, c.Slug, jobs, c.Found, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if entry == nil {
+		fmt.Fprintln(w, "\nNo supported board answered; nothing to add.")
+		return nil
+	}
+
+	fmt.Fprintln(w, "\nAdd to your config:")
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(map[string][]config.Company{"companies": {*entry}})
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
+)
+
+// plannedJob is one line of a dry-run diff.
+type plannedJob struct {
+	Action   string           `json:"action"` // add, change, reopen, close
+	Platform string           `json:"platform"`
+	Company  string           `json:"company"`
+	Title    string           `json:"title"`
+	Location string           `json:"location"`
+	URL      string           `json:"url"`
+	JobID    int              `json:"job_id,omitempty"`
+	Changes  []db.FieldChange `json:"changes,omitempty"`
+	Profiles []string         `json:"profiles,omitempty"`
+}
+
+// dryRunResult is everything a real run would have written.
+type dryRunResult struct {
+	Jobs    []plannedJob      `json:"jobs"`
+	Errors  map[string]string `json:"errors,omitempty"`
+	Skipped map[string]string `json:"skipped,omitempty"` // backing off or disabled
+	Counts  map[string]int    `json:"counts"`
+}
+
+// runDryRun fetches and filters every target exactly like a real run, then
+// compares the results with the database and prints what would be added,
+// changed, reopened or closed. Nothing is written to the DB or CSV.
+func runDryRun(d *db.DB, targets []target, format string, w io.Writer) error {
+	res := dryRunResult{Errors: map[string]string{}, Skipped: map[string]string{}, Counts: map[string]int{}}
+
+	for i, t := range targets {
+		if reason := skipReason(d, t, time.Now()); reason != "" {
+			res.Skipped[t.String()] = reason
+			continue
+		}
+		logger.Info("[%d/%d] fetching %s (dry run)", i+1, len(targets), t)
+		jobs, dropped, err := fetchTarget(t, nil)
+		if err != nil {
+			logger.Warn("error scraping %s: %v", t.Slug, err)
+			res.Errors[t.String()] = err.Error()
+			continue
+		}
+		planned, err := planTarget(d, t, jobs, dropped)
+		if err != nil {
+			return err
+		}
+		res.Jobs = append(res.Jobs, planned...)
+		// be respectful
+		if i < len(targets)-1 {
+			time.Sleep(2 * time.Second)
+		}
+	}
+	for _, j := range res.Jobs {
+		res.Counts[j.Action]++
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	case "table", "":
+		return writeDryRunTable(w, res)
+	default:
+		return fmt.Errorf("unknown format %q (want table or json)", format)
+	}
+}
+
+// planTarget diffs one company's scraped jobs against the database. Jobs
+// the filters dropped are still listed and so are not closed.
+func planTarget(d *db.DB, t target, jobs, dropped []db.Job) ([]plannedJob, error) {
+	var out []plannedJob
+	seen := map[int]bool{}
+	for _, job := range dropped {
+		plan, err := d.PreviewJobSeen(job)
+		if err != nil {
+			return nil, err
+		}
+		seen[plan.ID] = true
+	}
+	for _, job := range jobs {
+		plan, err := d.PreviewJobSeen(job)
+		if err != nil {
+			return nil, err
+		}
+		seen[plan.ID] = true
+
+		pj := plannedJob{Platform: t.Platform, Company: job.Company, Title: job.Title,
+			Location: job.Location, URL: plan.URL, JobID: plan.ID, Changes: plan.Changes, Profiles: job.Profiles}
+		switch {
+		case plan.Result == db.JobInserted:
+			pj.Action = "add"
+		case plan.Result == db.JobUpdated:
+			pj.Action = "change"
+		case plan.Reopen:
+			pj.Action = "reopen"
+		default:
+			continue
+		}
+		out = append(out, pj)
+	}
+
+	var open []db.Job
+	var err error
+	if t.companyID != 0 {
+		open, err = d.ListOpenCompanyJobs(t.Platform, t.companyID)
+	} else {
+		open, err = d.ListOpenJobs(t.Platform, t.DisplayName())
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range open {
+		if !seen[job.ID] {
+			out = append(out, plannedJob{Action: "close", Platform: t.Platform, Company: job.Company,
+				Title: job.Title, Location: job.Location, URL: job.URL, JobID: job.ID})
+		}
+	}
+	return out, nil
+}
+
+func writeDryRunTable(w io.Writer, res dryRunResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tCOMPANY\tTITLE\tLOCATION\tDETAILS")
+	for _, j := range res.Jobs {
+		details := j.URL
+		if len(j.Changes) > 0 {
+			var fields []string
+			for _, c := range j.Changes {
+				if c.Field == "description" {
+					fields = append(fields, "description edited")
+				} else {
+					fields = append(fields, fmt.Sprintf("%s: %q → %q", c.Field, c.Old, c.New))
+				}
+			}
+			details = strings.Join(fields, "; ")
+		}
+		if len(j.Profiles) > 0 {
+			details = "[" + strings.Join(j.Profiles, ",") + "] " + details
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", j.Action, j.Company, j.Title, j.Location, details)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n%d to add, %d to change, %d to reopen, %d to close\n",
+		res.Counts["add"], res.Counts["change"], res.Counts["reopen"], res.Counts["close"])
+	companies := make([]string, 0, len(res.Errors))
+	for company := range res.Errors {
+		companies = append(companies, company)
+	}
+	sort.Strings(companies)
+	for _, company := range companies {
+		fmt.Fprintf(w, "error: %s: %s\n", company, res.Errors[company])
+	}
+	companies = companies[:0]
+	for company := range res.Skipped {
+		companies = append(companies, company)
+	}
+	sort.Strings(companies)
+	for _, company := range companies {
+		fmt.Fprintf(w, "skipped: %s: %s\n", company, res.Skipped[company])
+	}
+	return nil
+}
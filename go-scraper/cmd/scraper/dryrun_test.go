@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/scraper"
+)
+
+// openTestDB connects to a fresh database in a temporary directory.
+func openTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "jobs.db"))
+	d, err := db.Connect()
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// dbSnapshot renders every job row and version so a test can tell whether
+// anything was written.
+func dbSnapshot(t *testing.T, d *db.DB) string {
+	t.Helper()
+	rows, err := d.Conn.Query(`SELECT id, title, COALESCE(last_seen_at, ''), COALESCE(closed_at, ''),
+		(SELECT COUNT(*) FROM job_versions v WHERE v.job_id = job_applications.id)
+		FROM job_applications ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var b strings.Builder
+	for rows.Next() {
+		var id, versions int
+		var title, lastSeen, closed string
+		if err := rows.Scan(&id, &title, &lastSeen, &closed, &versions); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&b, "%d %q %s %s %d\n", id, title, lastSeen, closed, versions)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestRunDryRun(t *testing.T) {
+	const board = `{"jobs": [
+		{"id": 1, "title": "Software Engineer, New Grad", "absolute_url": "https://boards.greenhouse.io/acme/jobs/1", "location": {"name": "Remote"}},
+		{"id": 2, "title": "Data Analyst, New Grad", "absolute_url": "https://boards.greenhouse.io/acme/jobs/2", "location": {"name": "Remote"}},
+		{"id": 3, "title": "Designer Intern", "absolute_url": "https://boards.greenhouse.io/acme/jobs/3", "location": {"name": "Remote"}},
+		{"id": 4, "title": "Support Engineer", "absolute_url": "https://boards.greenhouse.io/acme/jobs/4", "location": {"name": "Remote"}}
+	]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(board))
+	}))
+	defer srv.Close()
+	t.Setenv(scraper.APIBaseEnv, srv.URL)
+
+	d := openTestDB(t)
+	tg := target{Company: config.Company{Slug: "acme", Platform: "greenhouse", Name: "Acme"}}
+
+	// The database knows job 1 as listed, job 2 under an older title, job 4
+	// as closed and a job the board no longer lists; job 3 is new
+	scraped, _, err := parseTarget(tg, []byte(board), time.Now().Add(-time.Hour))
+	if err != nil || len(scraped) != 4 {
+		t.Fatalf("parseTarget = %d jobs, %v; want 4", len(scraped), err)
+	}
+	scraped[1].Title = "Data Analyst"
+	gone := db.Job{Title: "Product Manager", Company: "Acme", Location: "Remote",
+		URL: "https://boards.greenhouse.io/acme/jobs/9", Source: "greenhouse", SourceJobID: "9"}
+	for _, j := range []db.Job{scraped[0], scraped[1], scraped[3], gone} {
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Conn.Exec(`UPDATE job_applications SET closed_at = '2020-01-01 00:00:00' WHERE source_job_id = '4'`); err != nil {
+		t.Fatal(err)
+	}
+	before := dbSnapshot(t, d)
+
+	var out bytes.Buffer
+	if err := runDryRun(d, []target{tg}, "json", &out); err != nil {
+		t.Fatalf("runDryRun: %v", err)
+	}
+	var res dryRunResult
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("decode %s: %v", out.String(), err)
+	}
+
+	actions := map[string]string{}
+	for _, j := range res.Jobs {
+		actions[j.Title] = j.Action
+	}
+	want := map[string]string{
+		"Data Analyst, New Grad": "change",
+		"Designer Intern":        "add",
+		"Support Engineer":       "reopen",
+		"Product Manager":        "close",
+	}
+	if fmt.Sprint(actions) != fmt.Sprint(want) {
+		t.Errorf("actions = %v, want %v", actions, want)
+	}
+	for action, n := range map[string]int{"add": 1, "change": 1, "reopen": 1, "close": 1} {
+		if res.Counts[action] != n {
+			t.Errorf("Counts[%s] = %d, want %d", action, res.Counts[action], n)
+		}
+	}
+	if len(res.Errors) != 0 || len(res.Skipped) != 0 {
+		t.Errorf("errors %v, skipped %v; want none", res.Errors, res.Skipped)
+	}
+
+	if after := dbSnapshot(t, d); after != before {
+		t.Errorf("dry run changed the database:\nbefore:\n%safter:\n%s", before, after)
+	}
+}
+
+func TestDryRunPendingMigrations(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DB_PATH", filepath.Join(dir, "jobs.db"))
+	d, err := db.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := db.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.MigrateDown(2); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	// A dry run reports the pending migrations and applies none of them
+	_, err = connect(true)
+	if !errors.Is(err, db.ErrSchemaPending) {
+		t.Fatalf("connect(dry run) = %v, want ErrSchemaPending", err)
+	}
+	for _, m := range all[len(all)-2:] {
+		if name := fmt.Sprintf("%04d_%s", m.Version, m.Name); !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not name %s", err, name)
+		}
+	}
+	d, err = db.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := d.SchemaVersion()
+	d.Close()
+	if err != nil || v != len(all)-2 {
+		t.Errorf("schema version after dry run = %d, %v; want %d", v, err, len(all)-2)
+	}
+
+	// Nor does it create a database that is not there
+	t.Setenv("DB_PATH", filepath.Join(dir, "missing.db"))
+	if _, err := connect(true); err == nil {
+		t.Error("connect(dry run) opened a missing database")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+		t.Errorf("dry run created the database: %v", err)
+	}
+
+	// Once migrated, the dry run opens it
+	t.Setenv("DB_PATH", filepath.Join(dir, "jobs.db"))
+	if d, err = connect(false); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	if d, err = connect(true); err != nil {
+		t.Fatalf("connect(dry run) after migrating = %v", err)
+	}
+	d.Close()
+}
@@ -1,119 +1,127 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"flag"
-	"io/ioutil"
+	"fmt"
 	"os"
-	"path/filepath"
-	"time"
 
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
 	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
-	"github.com/ajiteshreddy7/yc-go-scraper/internal/exporter"
 	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
-	"github.com/ajiteshreddy7/yc-go-scraper/internal/scraper"
 )
 
-type Config struct {
-	TargetPlatforms map[string][]string `json:"target_platforms"`
-}
+const defaultConfigPath = "config/scraper_config.json"
 
 func main() {
+	// Subcommands; everything else is a scrape run
+	if len(os.Args) > 1 {
+		switch cmd, args := os.Args[1], os.Args[2:]; {
+		case cmd == "config" && len(args) > 0 && args[0] == "validate":
+			os.Exit(runConfigValidate(args[1:], os.Stdout))
+		case cmd == "config" && len(args) > 0 && args[0] == "check":
+			os.Exit(runConfigCheck(args[1:], os.Stdout))
+		case cmd == "companies":
+			os.Exit(runCompanies(args, os.Stdout))
+		case cmd == "db":
+			os.Exit(runDB(args, os.Stdout))
+		case cmd == "discover":
+			os.Exit(runDiscover(args, os.Stdout))
+		case cmd == "migrate":
+			os.Exit(runMigrate(args, os.Stdout))
+		case cmd == "reprocess":
+			os.Exit(runReprocess(args, os.Stdout))
+		case cmd == "yc" && len(args) > 0 && args[0] == "import":
+			os.Exit(runYCImport(args[1:], os.Stdout))
+		}
+	}
+
 	// CLI flags
-	cfgPath := flag.String("config", "config/scraper_config.json", "Path to scraper config JSON")
+	cfgPath := flag.String("config", defaultConfigPath, "Path to scraper config (JSON, YAML or TOML)")
 	outPath := flag.String("out", "data/job_applications.csv", "Path to output CSV file")
+	daemon := flag.Bool("daemon", false, "Keep running and scrape on the configured schedule")
+	statusAddr := flag.String("status-addr", "", "In daemon mode, serve next run times as JSON on this address (e.g. :9090)")
+	reportPath := flag.String("report", "", "Write a JSON run report to this path")
+	dryRun := flag.Bool("dry-run", false, "Show which jobs would be added, changed or closed without writing anything")
+	format := flag.String("format", "table", "Dry-run output format: table or json")
 	flag.Parse()
 
 	// Init logger level from env
 	logger.InitFromEnv()
+	if *dryRun {
+		// Keep stdout clean for the diff
+		logger.SetOutput(os.Stderr)
+	}
 
 	logger.Info("Starting Go Job Scraper")
 
 	// Connect to DB
-	d, err := db.Connect()
+	d, err := connect(*dryRun)
 	if err != nil {
 		logger.Fatal("db connect: %v", err)
 	}
 	defer d.Close()
 
-	if err := d.CreateSchema(); err != nil {
-		logger.Fatal("create schema: %v", err)
-	}
-
 	// Load config
 	if _, err := os.Stat(*cfgPath); os.IsNotExist(err) {
 		logger.Fatal("config file not found: %s", *cfgPath)
 	}
 
-	raw, err := ioutil.ReadFile(*cfgPath)
+	cfg, err := config.Load(*cfgPath)
 	if err != nil {
-		logger.Fatal("read config: %v", err)
+		logger.Fatal("load config: %v", err)
 	}
-
-	var cfg Config
-	if err := json.Unmarshal(raw, &cfg); err != nil {
-		logger.Fatal("unmarshal config: %v", err)
+	targets, err := configTargets(cfg)
+	if err != nil {
+		logger.Fatal("%v", err)
+	}
+	if err := syncCompanies(d, cfg, targets, *dryRun); err != nil {
+		logger.Fatal("sync companies: %v", err)
 	}
 
-	total := 0
-
-	// Process Greenhouse if configured
-	if companies, ok := cfg.TargetPlatforms["greenhouse"]; ok {
-		logger.Info("Found %d greenhouse companies to scrape", len(companies))
-		for i, c := range companies {
-			logger.Info("[%d/%d] scraping %s (Greenhouse)", i+1, len(companies), c)
-			jobs, err := scraper.ScrapeGreenhouse(c)
-			if err != nil {
-				logger.Warn("error scraping %s: %v", c, err)
-				continue
-			}
-			for _, job := range jobs {
-				if err := d.InsertJobTyped(job.Title, job.Company, job.Location, job.Type, job.URL); err != nil {
-					logger.Error("insert job error: %v", err)
-				} else {
-					total++
-				}
-			}
-			// be respectful
-			time.Sleep(2 * time.Second)
+	if *dryRun {
+		if err := runDryRun(d, targets, *format, os.Stdout); err != nil {
+			logger.Fatal("dry run: %v", err)
 		}
-		logger.Info("Processed %d greenhouse jobs", total)
+		return
 	}
 
-	// Process Lever if configured
-	if companies, ok := cfg.TargetPlatforms["lever"]; ok {
-		logger.Info("Found %d lever companies to scrape", len(companies))
-		leverTotal := 0
-		for i, c := range companies {
-			logger.Info("[%d/%d] scraping %s (Lever)", i+1, len(companies), c)
-			jobs, err := scraper.ScrapeLever(c)
-			if err != nil {
-				logger.Warn("error scraping %s: %v", c, err)
-				continue
-			}
-			for _, job := range jobs {
-				if err := d.InsertJobTyped(job.Title, job.Company, job.Location, job.Type, job.URL); err != nil {
-					logger.Error("insert job error: %v", err)
-				} else {
-					leverTotal++
-					total++
-				}
-			}
-			// be respectful
-			time.Sleep(2 * time.Second)
+	if *daemon {
+		if err := runDaemon(d, cfg, targets, *outPath, *statusAddr, *reportPath); err != nil {
+			logger.Fatal("daemon: %v", err)
 		}
-		logger.Info("Processed %d lever jobs", leverTotal)
+		return
 	}
-	
-	logger.Info("Processed %d total jobs", total)
 
-	// Export CSV
-	if err := os.MkdirAll(filepath.Dir(*outPath), 0755); err != nil {
-		logger.Fatal("create output dir: %v", err)
+	var report *runReport
+	err = withLease(d, func() (err error) {
+		report, err = runTargets(d, cfg, targets, *outPath)
+		return err
+	})
+	if err != nil {
+		logger.Fatal("%v", err)
+	}
+	if *reportPath != "" {
+		if err := report.write(*reportPath); err != nil {
+			logger.Fatal("write report: %v", err)
+		}
+		logger.Info("Wrote run report to %s", *reportPath)
 	}
 
-	if err := exporter.ExportCSV(d, *outPath); err != nil {
-		logger.Fatal("export csv: %v", err)
+	// Close the DB explicitly since os.Exit skips deferred calls
+	d.Close()
+	os.Exit(report.ExitCode)
+}
+
+// connect opens the database, migrating it unless this is a dry run, which
+// must not write: it fails instead while migrations are pending.
+func connect(dryRun bool) (*db.DB, error) {
+	if !dryRun {
+		return db.Connect()
+	}
+	d, err := db.ConnectCurrent()
+	if errors.Is(err, db.ErrSchemaPending) {
+		return nil, fmt.Errorf("%w (run \"scraper migrate up\" first)", err)
 	}
-	logger.Info("Exported CSV to %s", *outPath)
+	return d, err
 }
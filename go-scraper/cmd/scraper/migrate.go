@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+)
+
+// runMigrate implements "scraper migrate [status|up|down [-steps n]]". The
+// scraper and dashboard apply pending migrations on startup, so up is only
+// needed to migrate ahead of a deploy. It returns the process exit code.
+func runMigrate(args []string, w io.Writer) int {
+	cmd := "status"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("migrate "+cmd, flag.ContinueOnError)
+	steps := fs.Int("steps", 1, "Number of migrations to roll back (down only)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	d, err := db.Open()
+	if err != nil {
+		fmt.Fprintf(w, "db open: %v\n", err)
+		return 1
+	}
+	defer d.Close()
+
+	switch cmd {
+	case "status":
+		err = migrationStatus(d, w)
+	case "up":
+		var applied []db.Migration
+		applied, err = d.MigrateUp()
+		for _, m := range applied {
+			fmt.Fprintf(w, "Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(w, "Schema is up to date")
+		}
+	case "down":
+		if *steps < 1 {
+			fmt.Fprintln(w, "-steps must be at least 1")
+			return 2
+		}
+		var undone []db.Migration
+		undone, err = d.MigrateDown(*steps)
+		for _, m := range undone {
+			fmt.Fprintf(w, "Rolled back %04d_%s\n", m.Version, m.Name)
+		}
+	default:
+		fmt.Fprintf(w, "unknown migrate command %q (want status, up or down)\n", cmd)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	return 0
+}
+
+// migrationStatus prints every known migration and whether it is applied.
+func migrationStatus(d *db.DB, w io.Writer) error {
+	status, err := d.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	version, err := d.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+	pending := 0
+	for _, s := range status {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format("2006-01-02 15:04")
+		} else {
+			pending++
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	tw.Flush()
+	if version > len(status) {
+		fmt.Fprintf(w, "\nDatabase is at version %d, newer than this binary (%d); upgrade before running it.\n", version, len(status))
+	} else if pending > 0 {
+		fmt.Fprintf(w, "\n%d pending; they are applied on the next start or with \"scraper migrate up\".\n", pending)
+	}
+	return nil
+}
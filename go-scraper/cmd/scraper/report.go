@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+)
+
+// Exit codes let CI tell a healthy run from one that needs attention.
+const (
+	exitOK          = 0 // every company scraped and at least one new job
+	exitFatal       = 1 // setup error (config, database, export); from logger.Fatal
+	exitAllFailed   = 2 // no company could be scraped
+	exitPartialFail = 3 // some companies failed
+	exitNoNewJobs   = 4 // every company scraped but nothing new was found
+)
+
+// companyReport holds the outcome of scraping one company board.
+type companyReport struct {
+	Platform string  `json:"platform"`
+	Company  string  `json:"company"`
+	Fetched  int     `json:"fetched"`
+	New      int     `json:"new"`
+	Updated  int     `json:"updated"`
+	Dupes    int     `json:"duplicates"` // already known and unchanged
+	Closed   int     `json:"closed"`
+	Errors   int     `json:"insert_errors"`
+	Error    string  `json:"error,omitempty"`
+	Skipped  string  `json:"skipped,omitempty"` // why a failing company was not scraped
+	Seconds  float64 `json:"duration_seconds"`
+
+	// Profiles counts the fetched jobs that matched each search profile.
+	Profiles map[string]int `json:"profiles,omitempty"`
+}
+
+// runReport summarizes a scrape run; it is written as JSON by -report.
+type runReport struct {
+	Run        string    `json:"run,omitempty"` // archive run ID, when archiving
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Seconds    float64   `json:"duration_seconds"`
+
+	Companies int `json:"companies"`
+	Failed    int `json:"failed_companies"`
+	Skipped   int `json:"skipped_companies"` // backing off or disabled
+	New       int `json:"new"`
+	Updated   int `json:"updated"`
+	Dupes     int `json:"duplicates"`
+	Closed    int `json:"closed"`
+	Merged    int `json:"merged"`
+
+	Profiles   map[string]int  `json:"profiles,omitempty"`
+	PerCompany []companyReport `json:"per_company"`
+
+	// Disabled lists companies that failed too often, with their last error.
+	Disabled []db.CompanyHealth `json:"disabled,omitempty"`
+
+	ExitCode int `json:"exit_code"`
+}
+
+// add folds a company's outcome into the run totals. Skipped companies
+// are listed but do not count towards the exit code.
+func (r *runReport) add(c companyReport) {
+	if c.Skipped != "" {
+		r.Skipped++
+		r.PerCompany = append(r.PerCompany, c)
+		return
+	}
+	r.Companies++
+	if c.Error != "" {
+		r.Failed++
+	}
+	r.New += c.New
+	r.Updated += c.Updated
+	r.Dupes += c.Dupes
+	r.Closed += c.Closed
+	for p, n := range c.Profiles {
+		if r.Profiles == nil {
+			r.Profiles = map[string]int{}
+		}
+		r.Profiles[p] += n
+	}
+	r.PerCompany = append(r.PerCompany, c)
+}
+
+// finish stamps the end time and decides the exit code.
+func (r *runReport) finish() {
+	r.FinishedAt = time.Now()
+	r.Seconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+	switch {
+	case r.Companies > 0 && r.Failed == r.Companies:
+		r.ExitCode = exitAllFailed
+	case r.Failed > 0:
+		r.ExitCode = exitPartialFail
+	case r.New == 0:
+		r.ExitCode = exitNoNewJobs
+	default:
+		r.ExitCode = exitOK
+	}
+}
+
+// write saves the report as indented JSON.
+func (r *runReport) write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0644)
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestRunReportExitCode(t *testing.T) {
+	ok := companyReport{Company: "acme", New: 2}
+	quiet := companyReport{Company: "beta", Dupes: 3}
+	failed := companyReport{Company: "gamma", Error: "HTTP 500"}
+	skipped := companyReport{Company: "delta", Skipped: "backing off"}
+
+	tests := []struct {
+		name      string
+		companies []companyReport
+		want      int
+	}{
+		{"all failed", []companyReport{failed, failed}, exitAllFailed},
+		{"all failed with skipped", []companyReport{failed, skipped}, exitAllFailed},
+		{"partial failure", []companyReport{ok, failed}, exitPartialFail},
+		{"no new jobs", []companyReport{quiet}, exitNoNewJobs},
+		{"only skipped companies", []companyReport{skipped, skipped}, exitNoNewJobs},
+		{"nothing configured", nil, exitNoNewJobs},
+		{"ok", []companyReport{ok, quiet}, exitOK},
+		{"ok with skipped", []companyReport{ok, skipped}, exitOK},
+	}
+	for _, tt := range tests {
+		var r runReport
+		for _, c := range tt.companies {
+			r.add(c)
+		}
+		r.finish()
+		if r.ExitCode != tt.want {
+			t.Errorf("%s: exit code %d, want %d", tt.name, r.ExitCode, tt.want)
+		}
+	}
+
+	var r runReport
+	r.add(ok)
+	r.add(skipped)
+	r.add(failed)
+	if r.Companies != 2 || r.Skipped != 1 || r.Failed != 1 || r.New != 2 || len(r.PerCompany) != 3 {
+		t.Errorf("totals = %d companies, %d skipped, %d failed, %d new, %d listed; want 2, 1, 1, 2, 3",
+			r.Companies, r.Skipped, r.Failed, r.New, len(r.PerCompany))
+	}
+}
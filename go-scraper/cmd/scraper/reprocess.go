@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/archive"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
+)
+
+// runReprocess implements "scraper reprocess [-run id]": it parses,
+// classifies and filters the board responses archived for a run with the
+// current code and config, and records the results, without network
+// access. Jobs missing from the archive are not closed. It returns the
+// process exit code.
+func runReprocess(args []string, w io.Writer) int {
+	fs := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	cfgPath := fs.String("config", defaultConfigPath, "Path to scraper config (JSON, YAML or TOML)")
+	runID := fs.String("run", "latest", "Archived run to reprocess, or \"latest\"")
+	list := fs.Bool("list", false, "List archived runs and exit")
+	outPath := fs.String("out", "data/job_applications.csv", "Path to output CSV file")
+	reportPath := fs.String("report", "", "Write a JSON run report to this path")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	logger.InitFromEnv()
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	if cfg.Archive.Dir == "" {
+		fmt.Fprintln(w, "no archive configured; set archive.dir in the config or SCRAPER_ARCHIVE_DIR")
+		return 1
+	}
+	arch := archive.New(cfg.Archive.Dir)
+
+	if *list {
+		runs, err := arch.Runs()
+		if err != nil {
+			fmt.Fprintln(w, err)
+			return 1
+		}
+		if len(runs) == 0 {
+			fmt.Fprintf(w, "No archived runs in %s\n", cfg.Archive.Dir)
+		}
+		for _, r := range runs {
+			fmt.Fprintln(w, r)
+		}
+		return 0
+	}
+
+	id := *runID
+	if id == "latest" {
+		if id, err = arch.Latest(); err != nil {
+			fmt.Fprintln(w, err)
+			return 1
+		}
+	}
+	entries, err := arch.Entries(id)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+
+	d, err := db.Connect()
+	if err != nil {
+		fmt.Fprintf(w, "db connect: %v\n", err)
+		return 1
+	}
+	defer d.Close()
+	targets, err := configTargets(cfg)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	if err := syncCompanies(d, cfg, targets, false); err != nil {
+		fmt.Fprintf(w, "sync companies: %v\n", err)
+		return 1
+	}
+	byBoard := map[string]target{}
+	for _, t := range targets {
+		byBoard[t.Platform+"/"+t.Slug] = t
+	}
+
+	var report *runReport
+	err = withLease(d, func() error {
+		report = &runReport{Run: id, StartedAt: time.Now()}
+		for _, e := range entries {
+			t, ok := byBoard[e.Platform+"/"+e.Slug]
+			if !ok {
+				logger.Info("Skipping %s/%s: not an enabled company in the config", e.Platform, e.Slug)
+				continue
+			}
+			report.add(reprocessEntry(d, t, e))
+		}
+		logger.Info("Reprocessed run %s: %d companies, %d new, %d updated, %d unchanged",
+			id, report.Companies, report.New, report.Updated, report.Dupes)
+		return finishRun(d, cfg, *outPath, report)
+	})
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	if *reportPath != "" {
+		if err := report.write(*reportPath); err != nil {
+			fmt.Fprintf(w, "write report: %v\n", err)
+			return 1
+		}
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// reprocessEntry parses one archived board response and records its jobs
+// as seen at the time the response was fetched.
+func reprocessEntry(d *db.DB, t target, e archive.Entry) (rep companyReport) {
+	started := time.Now()
+	rep = companyReport{Platform: t.Platform, Company: t.Slug}
+	defer func() { rep.Seconds = time.Since(started).Seconds() }()
+
+	body, fetched, err := e.Read()
+	if err != nil {
+		rep.Error = err.Error()
+		return rep
+	}
+	jobs, _, err := parseTarget(t, body, fetched)
+	if err != nil {
+		rep.Error = err.Error()
+		logger.Warn("reprocess %s: %v", t, err)
+		return rep
+	}
+	rep.Fetched = len(jobs)
+	if err := recordJobs(d, jobs, &rep); err != nil {
+		rep.Error = "store jobs: " + err.Error()
+		logger.Error("store jobs for %s: %v", t, err)
+	}
+	return rep
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
+)
+
+// archiveJobs applies the configured retention rules and prints the jobs
+// archived, or with dryRun the jobs that would be. It holds the scraper
+// lease so a scrape never reopens a job as it is archived.
+func archiveJobs(d *db.DB, cfg *config.Config, dryRun bool, w io.Writer) error {
+	rules := cfg.Retention.ArchiveRules()
+	if len(rules) == 0 {
+		return fmt.Errorf("no retention rules configured")
+	}
+	var jobs []db.ArchivedJob
+	run := func() (err error) {
+		jobs, err = d.ArchiveJobs(rules, time.Now(), dryRun)
+		return err
+	}
+	var err error
+	if dryRun {
+		err = run()
+	} else {
+		err = withLease(d, run)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(jobs) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tRULE\tCOMPANY\tTITLE\tCLOSED")
+		for _, j := range jobs {
+			closed := "open"
+			if j.ClosedAt != nil {
+				closed = j.ClosedAt.Format("2006-01-02")
+			}
+			title := j.Title
+			if j.MergedInto != nil {
+				title += fmt.Sprintf(" (duplicate of %d)", *j.MergedInto)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", j.ID, j.Rule, j.Company, title, closed)
+		}
+		tw.Flush()
+	}
+	if dryRun {
+		fmt.Fprintf(w, "Would archive %d jobs\n", len(jobs))
+	} else {
+		fmt.Fprintf(w, "Archived %d jobs\n", len(jobs))
+	}
+	return nil
+}
+
+// archiveScheduled applies the retention rules from the daemon and logs
+// the outcome. It returns errLeaseHeld while another instance scrapes.
+func archiveScheduled(d *db.DB, cfg *config.Config) error {
+	var jobs []db.ArchivedJob
+	err := withLease(d, func() (err error) {
+		jobs, err = d.ArchiveJobs(cfg.Retention.ArchiveRules(), time.Now(), false)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("Retention: archived %d jobs", len(jobs))
+	return nil
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/archive"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/exporter"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/scraper"
+)
+
+// platforms lists the supported boards in the order they are scraped.
+var platforms = []struct {
+	name  string
+	label string
+}{
+	{"greenhouse", "Greenhouse"},
+	{"lever", "Lever"},
+}
+
+// target is one company board to scrape, with its resolved job filter.
+type target struct {
+	config.Company
+	filter    scraper.Filter
+	backoff   db.Backoff
+	companyID int // catalog entry, set by syncCompanies
+
+	// profiles replace filter when the config defines search profiles.
+	profiles []profileFilter
+}
+
+// profileFilter is a search profile's filter for one company.
+type profileFilter struct {
+	name   string
+	filter scraper.Filter
+}
+
+// configTargets turns the enabled companies into scrape targets. With
+// profiles configured, companies outside every profile are skipped.
+func configTargets(cfg *config.Config) ([]target, error) {
+	var out []target
+	for _, c := range cfg.EnabledCompanies() {
+		f, err := cfg.FilterFor(c)
+		if err != nil {
+			return nil, err
+		}
+		t := target{Company: c, filter: f, backoff: cfg.Health.Policy()}
+		for _, p := range cfg.Profiles {
+			if !p.Includes(c) {
+				continue
+			}
+			pf, err := cfg.ProfileFilter(p, c)
+			if err != nil {
+				return nil, err
+			}
+			t.profiles = append(t.profiles, profileFilter{name: p.Name, filter: pf})
+		}
+		if len(cfg.Profiles) > 0 && len(t.profiles) == 0 {
+			logger.Info("Skipping %s: not in any profile", c)
+			continue
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// syncCompanies records every configured company in the companies catalog
+// and points each target at its entry, so jobs are stored under the
+// catalog's ID and name. A dry run only reads the catalog.
+func syncCompanies(d *db.DB, cfg *config.Config, targets []target, dryRun bool) error {
+	stored := map[string]db.Company{}
+	if !dryRun {
+		for _, c := range cfg.Companies {
+			legacy := scraper.CompanyName(c.Slug)
+			sc, err := d.UpsertCompany(db.Company{
+				Platform: c.Platform, Slug: c.Slug, Name: c.Name,
+				Website: c.Website, LogoURL: c.LogoURL, Tags: c.Tags, YCBatch: c.YCBatch,
+			}, legacy, legacy)
+			if err != nil {
+				return fmt.Errorf("catalog %s: %w", c, err)
+			}
+			stored[c.String()] = sc
+		}
+	}
+	for i := range targets {
+		t := &targets[i]
+		sc, ok := stored[t.String()]
+		if !ok {
+			var err error
+			if sc, ok, err = d.FindCompany(t.Platform, t.Slug); err != nil {
+				return err
+			}
+		}
+		if ok {
+			t.companyID, t.Name = sc.ID, sc.Name
+		}
+	}
+	return nil
+}
+
+// fetchTarget fetches one company board once, archiving the raw response
+// when run is set, and splits its jobs like parseTarget.
+func fetchTarget(t target, run *archive.Run) (kept, dropped []db.Job, err error) {
+	fetched := time.Now()
+	body, err := scraper.FetchRaw(t.Board())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := run.Put(t.Platform, t.Slug, fetched, body); err != nil {
+		logger.Warn("archive %s: %v", t, err)
+	}
+	return parseTarget(t, body, fetched)
+}
+
+// parseTarget parses a board response fetched at seen and keeps the jobs
+// that pass the target's filter or, with profiles, match at least one
+// profile. Those jobs are tagged with every profile they matched. The
+// postings left out are returned as dropped: they are still listed, so
+// they must not be closed.
+func parseTarget(t target, body []byte, seen time.Time) (kept, dropped []db.Job, err error) {
+	jobs, err := scraper.Parse(t.Board(), body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, j := range jobs {
+		dj := toDBJob(j)
+		dj.CompanyID, dj.SeenAt = t.companyID, seen
+		if t.profiles == nil {
+			if t.filter.Match(j) {
+				kept = append(kept, dj)
+			} else {
+				dropped = append(dropped, dj)
+			}
+			continue
+		}
+		matched := []string{}
+		for _, p := range t.profiles {
+			if p.filter.Match(j) {
+				matched = append(matched, p.name)
+			}
+		}
+		if len(matched) > 0 {
+			dj.Profiles = matched
+			kept = append(kept, dj)
+		} else {
+			dropped = append(dropped, dj)
+		}
+	}
+	return kept, dropped, nil
+}
+
+// scrapeTarget fetches one company board, records its jobs and closes the
+// ones that are no longer listed.
+func scrapeTarget(d *db.DB, t target, run *archive.Run) (rep companyReport) {
+	started := time.Now()
+	rep = companyReport{Platform: t.Platform, Company: t.Slug}
+	defer func() { rep.Seconds = time.Since(started).Seconds() }()
+
+	if reason := skipReason(d, t, started); reason != "" {
+		rep.Skipped = reason
+		return rep
+	}
+
+	jobs, dropped, err := fetchTarget(t, run)
+	if err != nil {
+		rep.Error = err.Error()
+		recordFailure(d, t, err)
+		return rep
+	}
+	if err := d.RecordScrapeSuccess(t.Platform, t.Slug); err != nil {
+		logger.Error("record success for %s: %v", t, err)
+	}
+	rep.Fetched = len(jobs)
+	if err := recordJobs(d, jobs, &rep); err != nil {
+		// Nothing was stored, so closing unseen jobs would close them all
+		rep.Error = "store jobs: " + err.Error()
+		logger.Error("store jobs for %s: %v", t, err)
+		return rep
+	}
+	// Filtered-out postings are still listed; without this they would close
+	if _, err := d.MarkJobsListed(dropped); err != nil {
+		rep.Error = "mark listed jobs: " + err.Error()
+		logger.Error("mark listed jobs for %s: %v", t, err)
+		return rep
+	}
+	rep.Closed = closeUnseen(d, t, started)
+	return rep
+}
+
+// skipReason explains why a failing company is not scraped now, or returns
+// "" if it should be.
+func skipReason(d *db.DB, t target, now time.Time) string {
+	h, err := d.GetCompanyHealth(t.Platform, t.Slug)
+	if err != nil {
+		logger.Error("company health for %s: %v", t, err)
+		return ""
+	}
+	switch {
+	case h.DisabledAt != nil:
+		return fmt.Sprintf("disabled after %d failures: %s", h.ConsecutiveFailures, h.LastError)
+	case h.NextAttemptAt != nil && h.NextAttemptAt.After(now):
+		return fmt.Sprintf("backing off until %s after %d failures",
+			h.NextAttemptAt.Local().Format(time.RFC3339), h.ConsecutiveFailures)
+	}
+	return ""
+}
+
+// recordFailure counts a failed scrape towards the company's backoff.
+func recordFailure(d *db.DB, t target, scrapeErr error) {
+	h, err := d.RecordScrapeFailure(t.Platform, t.Slug, scrapeErr.Error(), t.backoff)
+	if err != nil {
+		logger.Error("record failure for %s: %v", t, err)
+		return
+	}
+	if h.DisabledAt != nil {
+		logger.Warn("Disabled %s after %d consecutive failures; re-enable with: scraper companies enable %s",
+			t, h.ConsecutiveFailures, t)
+	} else if h.NextAttemptAt != nil {
+		logger.Info("%s has failed %d times in a row, next attempt after %s",
+			t, h.ConsecutiveFailures, h.NextAttemptAt.Local().Format(time.RFC3339))
+	}
+}
+
+const (
+	leaseName = "scraper"
+	leaseTTL  = 10 * time.Minute
+)
+
+var errLeaseHeld = errors.New("another scraper instance is running")
+
+// withLease runs fn while holding the scraper lease, so a daemon and a
+// one-off run (or two daemons) sharing a database never scrape at once.
+func withLease(d *db.DB, fn func() error) error {
+	host, _ := os.Hostname()
+	holder := fmt.Sprintf("%s:%d", host, os.Getpid())
+
+	ok, err := d.AcquireLease(leaseName, holder, leaseTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errLeaseHeld
+	}
+	defer d.ReleaseLease(leaseName, holder)
+
+	// Keep the lease alive for long runs
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(leaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := d.AcquireLease(leaseName, holder, leaseTTL); err != nil {
+					logger.Warn("renew lease: %v", err)
+				}
+			}
+		}
+	}()
+
+	return fn()
+}
+
+// runTargets scrapes the given targets one after another, archiving the
+// raw responses when the config sets an archive directory, then finishes
+// the run with finishRun.
+func runTargets(d *db.DB, cfg *config.Config, targets []target, outPath string) (*runReport, error) {
+	report := &runReport{StartedAt: time.Now()}
+	var run *archive.Run
+	if cfg.Archive.Dir != "" {
+		run = archive.New(cfg.Archive.Dir).NewRun(report.StartedAt)
+		report.Run = run.ID
+		logger.Info("Archiving board responses as run %s", run.ID)
+	}
+	total := 0
+	for _, p := range platforms {
+		var companies []target
+		for _, t := range targets {
+			if t.Platform == p.name {
+				companies = append(companies, t)
+			}
+		}
+		if len(companies) == 0 {
+			continue
+		}
+
+		logger.Info("Found %d %s companies to scrape", len(companies), p.name)
+		platformTotal := 0
+		for i, t := range companies {
+			logger.Info("[%d/%d] scraping %s (%s)", i+1, len(companies), t.Slug, p.label)
+			rep := scrapeTarget(d, t, run)
+			report.add(rep)
+			if rep.Skipped != "" {
+				logger.Info("Skipping %s: %s", t.Slug, rep.Skipped)
+				continue
+			}
+			if rep.Error != "" {
+				logger.Warn("error scraping %s: %s", t.Slug, rep.Error)
+				continue
+			}
+			platformTotal += rep.Fetched - rep.Errors
+			// be respectful
+			time.Sleep(2 * time.Second)
+		}
+		total += platformTotal
+		logger.Info("Processed %d %s jobs", platformTotal, p.name)
+	}
+
+	logger.Info("Processed %d total jobs: %d new, %d updated, %d unchanged, %d closed",
+		total, report.New, report.Updated, report.Dupes, report.Closed)
+
+	if err := finishRun(d, cfg, outPath, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// finishRun merges duplicate listings, notes disabled companies in the
+// report and re-exports the CSV, plus one CSV per profile that sets an
+// output path. The CSVs carry export.user's statuses when it is set.
+func finishRun(d *db.DB, cfg *config.Config, outPath string, report *runReport) error {
+	// Fold the same role listed on several boards into one job
+	if merged, err := d.MergeDuplicates(); err != nil {
+		logger.Error("merge duplicates: %v", err)
+	} else if merged > 0 {
+		report.Merged = merged
+		logger.Info("Merged %d duplicate listings", merged)
+	}
+
+	// List companies that need attention
+	if health, err := d.ListCompanyHealth(); err != nil {
+		logger.Error("list company health: %v", err)
+	} else {
+		for _, h := range health {
+			if h.DisabledAt != nil {
+				report.Disabled = append(report.Disabled, h)
+			}
+		}
+	}
+
+	// Export CSV
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
+	}
+
+	var userID int
+	if cfg.Export.User != "" {
+		var err error
+		if userID, _, _, err = d.GetUserByUsername(cfg.Export.User); err != nil {
+			return fmt.Errorf("export user %q: %w", cfg.Export.User, err)
+		}
+	}
+	if err := exporter.ExportCSV(d, outPath, userID); err != nil {
+		return fmt.Errorf("export csv: %w", err)
+	}
+	logger.Info("Exported CSV to %s", outPath)
+
+	for _, p := range cfg.Profiles {
+		if p.Output == "" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p.Output), 0755); err != nil {
+			return fmt.Errorf("create output dir: %w", err)
+		}
+		if err := exporter.ExportProfileCSV(d, p.Name, p.Output, userID); err != nil {
+			return fmt.Errorf("export %s csv: %w", p.Name, err)
+		}
+		logger.Info("Exported %s profile CSV to %s", p.Name, p.Output)
+	}
+
+	report.finish()
+	return nil
+}
+
+// recordJobs stores one company's scraped jobs in a single transaction,
+// refreshing last_seen_at for known jobs, and tallies the results. It
+// returns an error, and stores nothing, when the batch could not be written.
+func recordJobs(d *db.DB, jobs []db.Job, rep *companyReport) error {
+	for _, job := range jobs {
+		for _, p := range job.Profiles {
+			if rep.Profiles == nil {
+				rep.Profiles = map[string]int{}
+			}
+			rep.Profiles[p]++
+		}
+	}
+	counts, err := d.UpsertJobs(context.Background(), jobs)
+	if err != nil {
+		return err
+	}
+	for i, job := range jobs {
+		if err := counts.Errors[i]; err != nil {
+			logger.Error("insert job error: %v", err)
+		} else if counts.Results[i] == db.JobUpdated {
+			logger.Info("Posting changed: %s (%s)", job.Title, job.URL)
+		}
+	}
+	rep.New += counts.Inserted
+	rep.Updated += counts.Updated
+	rep.Dupes += counts.Unchanged
+	rep.Errors += counts.Failed
+	return nil
+}
+
+// toDBJob converts a scraped job into the record stored by the DB layer.
+func toDBJob(job scraper.Job) db.Job {
+	return db.Job{
+		Title:       job.Title,
+		Company:     job.Company,
+		Location:    job.Location,
+		Type:        job.Type,
+		URL:         job.URL,
+		Description: job.Description,
+		Source:      job.Source,
+		SourceJobID: job.SourceJobID,
+	}
+}
+
+// closeUnseen marks jobs from a successfully scraped company board as closed
+// when the board no longer lists them, and returns how many it closed.
+func closeUnseen(d *db.DB, t target, started time.Time) int {
+	var (
+		closed int64
+		err    error
+	)
+	if t.companyID != 0 {
+		closed, err = d.CloseUnseenCompanyJobs(t.Platform, t.companyID, started)
+	} else {
+		closed, err = d.CloseUnseenJobs(t.Platform, t.DisplayName(), started)
+	}
+	if err != nil {
+		logger.Error("close unseen jobs for %s: %v", t, err)
+		return 0
+	}
+	if closed > 0 {
+		logger.Info("Marked %d %s jobs as closed", closed, t.DisplayName())
+	}
+	return int(closed)
+}
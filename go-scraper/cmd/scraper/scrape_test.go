@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/scraper"
+)
+
+func TestScrapeTargetCloses(t *testing.T) {
+	const board = `{"jobs": [
+		{"id": 1, "title": "Software Engineer, New Grad", "absolute_url": "https://boards.greenhouse.io/acme/jobs/1", "location": {"name": "Remote"}},
+		{"id": 2, "title": "Staff Engineer", "absolute_url": "https://boards.greenhouse.io/acme/jobs/2", "location": {"name": "Remote"}}
+	]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(board))
+	}))
+	defer srv.Close()
+	t.Setenv(scraper.APIBaseEnv, srv.URL)
+
+	d := openTestDB(t)
+	tg := target{Company: config.Company{Slug: "acme", Platform: "greenhouse", Name: "Acme"}, filter: scraper.DefaultFilter()}
+
+	// Job 2 was stored under a looser filter and is still listed, job 9 is
+	// gone, and Acme's Lever board is scraped separately
+	stored := []db.Job{
+		{Title: "Software Engineer, New Grad", Company: "Acme", URL: "https://boards.greenhouse.io/acme/jobs/1", Source: "greenhouse", SourceJobID: "1"},
+		{Title: "Staff Engineer", Company: "Acme", URL: "https://boards.greenhouse.io/acme/jobs/2", Source: "greenhouse", SourceJobID: "2"},
+		{Title: "Product Manager", Company: "Acme", URL: "https://boards.greenhouse.io/acme/jobs/9", Source: "greenhouse", SourceJobID: "9"},
+		{Title: "Data Analyst, New Grad", Company: "Acme", URL: "https://jobs.lever.co/acme/abc", Source: "lever", SourceJobID: "abc"},
+	}
+	for _, j := range stored {
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Conn.Exec(`UPDATE job_applications SET last_seen_at = '2020-01-01 00:00:00'`); err != nil {
+		t.Fatal(err)
+	}
+
+	rep := scrapeTarget(d, tg, nil)
+	if rep.Error != "" || rep.Fetched != 1 || rep.Closed != 1 {
+		t.Fatalf("scrapeTarget = %+v; want 1 fetched and 1 closed", rep)
+	}
+	for _, j := range stored {
+		var closed bool
+		if err := d.Conn.QueryRow(`SELECT closed_at IS NOT NULL FROM job_applications WHERE url = $1`, j.URL).Scan(&closed); err != nil {
+			t.Fatal(err)
+		}
+		if want := j.SourceJobID == "9"; closed != want {
+			t.Errorf("%s (%s) closed = %v, want %v", j.Title, j.Source, closed, want)
+		}
+	}
+}
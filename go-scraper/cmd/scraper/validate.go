@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/scraper"
+)
+
+// runConfigValidate implements "scraper config validate": it loads the
+// config with its includes and environment overrides and prints every
+// problem found. It returns the process exit code.
+func runConfigValidate(args []string, w io.Writer) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	cfgPath := fs.String("config", defaultConfigPath, "Path to scraper config (JSON, YAML or TOML)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, problems, err := config.Check(*cfgPath)
+	if err != nil {
+		fmt.Fprintf(w, "%s: %v\n", *cfgPath, err)
+		return 1
+	}
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "%d problem(s) found\n", len(problems))
+		return 1
+	}
+	fmt.Fprintf(w, "%s: ok, %d companies (%d enabled)\n", *cfgPath, len(cfg.Companies), len(cfg.EnabledCompanies()))
+	return 0
+}
+
+// runConfigCheck implements "scraper config check": after validating the
+// config it fetches every enabled company's board and reports the ones
+// that no longer resolve. It returns the process exit code.
+func runConfigCheck(args []string, w io.Writer) int {
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	cfgPath := fs.String("config", defaultConfigPath, "Path to scraper config (JSON, YAML or TOML)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPANY\tJOBS\tSTATUS")
+	for i, c := range cfg.EnabledCompanies() {
+		if i > 0 {
+			// be respectful
+			time.Sleep(500 * time.Millisecond)
+		}
+		jobs, err := scraper.Fetch(c.Board())
+		if err != nil {
+			failed++
+			fmt.Fprintf(tw, "%s\t-\t%v\n", c, err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\tok\n", c, len(jobs))
+	}
+	tw.Flush()
+	if failed > 0 {
+		fmt.Fprintf(w, "%d companies did not resolve; try: scraper discover <company>\n", failed)
+		return 1
+	}
+	return 0
+}
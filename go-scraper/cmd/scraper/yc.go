@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/config"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/discover"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/ycdir"
+	"gopkg.in/yaml.v3"
+)
+
+// runYCImport implements "scraper yc import <directory.json|csv>": it stores
+// YC directory metadata on the matching catalog companies and suggests
+// config entries for unmatched companies whose job board is known. It
+// returns the process exit code.
+func runYCImport(args []string, w io.Writer) int {
+	fs := flag.NewFlagSet("yc import", flag.ContinueOnError)
+	cfgPath := fs.String("config", defaultConfigPath, "Scraper config; companies already in it are not suggested")
+	suggestPath := fs.String("suggest", "", "Write suggested config entries to this YAML file instead of stdout")
+	dryRun := fs.Bool("dry-run", false, "Report matches without updating the database")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(w, "usage: scraper yc import [-config path] [-suggest file.yaml] [-dry-run] <directory.json|csv>")
+		return 2
+	}
+
+	entries, err := ycdir.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+
+	d, err := db.Connect()
+	if err != nil {
+		fmt.Fprintf(w, "db connect: %v\n", err)
+		return 1
+	}
+	defer d.Close()
+
+	companies, err := d.ListCompanies()
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	records := make([]ycdir.Record, len(companies))
+	for i, c := range companies {
+		records[i] = ycdir.Record{ID: c.ID, Name: c.Name, Slug: c.Slug, Website: c.Website}
+	}
+	ix := ycdir.NewIndex(records)
+
+	// Boards already configured or in the catalog are never suggested
+	known := map[string]bool{}
+	for _, c := range companies {
+		known[c.Platform+"/"+strings.ToLower(c.Slug)] = true
+	}
+	if _, err := os.Stat(*cfgPath); err == nil {
+		cfg, err := config.Load(*cfgPath)
+		if err != nil {
+			fmt.Fprintln(w, err)
+			return 1
+		}
+		for _, c := range cfg.Companies {
+			known[c.Platform+"/"+strings.ToLower(c.Slug)] = true
+		}
+	}
+
+	var updated, matched int
+	var suggestions []config.Company
+	for _, e := range entries {
+		ids := ix.Match(e)
+		if len(ids) == 0 {
+			if s, ok := suggestCompany(e, known); ok {
+				suggestions = append(suggestions, s)
+			}
+			continue
+		}
+		matched++
+		if *dryRun {
+			updated += len(ids)
+			continue
+		}
+		for _, id := range ids {
+			err := d.SetCompanyDirectory(id, db.Company{YCBatch: e.Batch, Industry: e.Industry, TeamSize: e.TeamSize,
+				YCStatus: e.Status, Website: e.Website, LogoURL: e.LogoURL})
+			if err != nil {
+				fmt.Fprintf(w, "update %s: %v\n", e.Name, err)
+				return 1
+			}
+			updated++
+		}
+	}
+
+	verb := "Updated"
+	if *dryRun {
+		verb = "Would update"
+	}
+	fmt.Fprintf(w, "Read %d directory companies; %d matched the catalog. %s %d of %d catalog companies.\n",
+		len(entries), matched, verb, updated, len(companies))
+	if len(suggestions) == 0 {
+		return 0
+	}
+
+	out := w
+	if *suggestPath != "" {
+		f, err := os.Create(*suggestPath)
+		if err != nil {
+			fmt.Fprintln(w, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+		fmt.Fprintf(w, "Wrote %d suggested companies to %s\n", len(suggestions), *suggestPath)
+	} else {
+		fmt.Fprintf(w, "\n%d companies with a known job board are not scraped yet. Add to your config:\n", len(suggestions))
+	}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string][]config.Company{"companies": suggestions}); err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	if err := enc.Close(); err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	return 0
+}
+
+// suggestCompany returns a config entry for a directory company whose ATS
+// links name a board the scraper supports and does not already know.
+func suggestCompany(e ycdir.Company, known map[string]bool) (config.Company, bool) {
+	for _, c := range discover.Detect(strings.Join(e.ATSLinks, "\n")) {
+		if !c.Supported || known[c.Platform+"/"+strings.ToLower(c.Slug)] {
+			continue
+		}
+		known[c.Platform+"/"+strings.ToLower(c.Slug)] = true
+		return config.Company{Slug: c.Slug, Platform: c.Platform, Name: e.Name, Host: c.Host,
+			Website: e.Website, YCBatch: e.Batch}, true
+	}
+	return config.Company{}, false
+}
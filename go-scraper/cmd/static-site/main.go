@@ -13,17 +13,30 @@ import (
 
 	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
 	"github.com/ajiteshreddy7/yc-go-scraper/internal/logger"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/ycdir"
 )
 
 type Job struct {
 	ID        int
 	Title     string
 	Company   string
+	Website   string // from the companies catalog, when linked
+	Batch     string // YC directory metadata of the company, when imported
+	BatchKey  int
+	Industry  string
+	TeamSize  int
 	Location  string
 	Type      string
 	URL       string
 	DateAdded time.Time
 	Status    string
+
+	// Lifecycle from the scraper: FirstSeenAt is when the posting was first
+	// listed, ClosedAt stays nil while it is still up and OpenDays counts
+	// the whole days it has been listed.
+	FirstSeenAt time.Time
+	ClosedAt    *time.Time
+	OpenDays    int
 }
 
 // deriveLevels returns canonical level labels found in a job title
@@ -166,11 +179,35 @@ const indexHTML = `<!DOCTYPE html>
                         <option value="{{.}}">{{.}}</option>
                         {{end}}
                     </select>
+                    {{if .HasStatus}}
                     <select id="status">
                         <option value="">All Statuses</option>
                         <option value="Not Applied">Not Applied</option>
                         <option value="Applied">Applied</option>
                     </select>
+                    {{end}}
+                    {{if .Batches}}
+                    <select id="batch">
+                        <option value="">Any YC Batch</option>
+                        {{range .Batches}}
+                        <option value="{{.Key}}">{{.Name}} or later</option>
+                        {{end}}
+                    </select>
+                    {{end}}
+                    {{if .Industries}}
+                    <select id="industry">
+                        <option value="">All Industries</option>
+                        {{range .Industries}}
+                        <option value="{{.}}">{{.}}</option>
+                        {{end}}
+                    </select>
+                    <select id="team-size">
+                        <option value="">Any Team Size</option>
+                        <option value="25">Fewer than 25</option>
+                        <option value="100">Fewer than 100</option>
+                        <option value="500">Fewer than 500</option>
+                    </select>
+                    {{end}}
                 </div>
             </div>
             
@@ -187,6 +224,7 @@ const indexHTML = `<!DOCTYPE html>
                     <div class="stat-number" id="filtered-count">0</div>
                     <div class="stat-label">Matching Jobs</div>
                 </div>
+                {{if .HasStatus}}
                 <div class="stat-card">
                     <div class="stat-number" id="not-applied-count">0</div>
                     <div class="stat-label">Not Applied</div>
@@ -195,6 +233,7 @@ const indexHTML = `<!DOCTYPE html>
                     <div class="stat-number" id="applied-count">0</div>
                     <div class="stat-label">Applied</div>
                 </div>
+                {{end}}
             </div>
             
             <div class="jobs-list" id="jobs-list">
@@ -205,9 +244,12 @@ const indexHTML = `<!DOCTYPE html>
     
     <script>
         const allJobs = {{.JobsJSON}};
+        // Statuses are only published when the site was built for a user
+        const hasStatus = {{.HasStatus}};
         
         // Load applied jobs from localStorage and update statuses
         (function initializeAppliedJobs() {
+            if (!hasStatus) return;
             const appliedJobs = JSON.parse(localStorage.getItem('appliedJobs') || '{}');
             allJobs.forEach(job => {
                 if (appliedJobs[job.URL]) {
@@ -225,7 +267,10 @@ const indexHTML = `<!DOCTYPE html>
             document.getElementById('search').value = '';
             document.getElementById('company').value = '';
             document.getElementById('location').value = '';
-            document.getElementById('status').value = '';
+            ['status', 'batch', 'industry', 'team-size'].forEach(id => {
+                const el = document.getElementById(id);
+                if (el) el.value = '';
+            });
             document.getElementById('select-all').checked = true;
             document.querySelectorAll('#levels input[type="checkbox"]').forEach(cb => cb.checked = true);
             document.getElementById('results').style.display = 'none';
@@ -237,7 +282,8 @@ const indexHTML = `<!DOCTYPE html>
             const selectedLevels = Array.from(document.querySelectorAll('#levels input:checked')).map(cb => cb.value.toLowerCase());
             const company = document.getElementById('company').value;
             const location = document.getElementById('location').value;
-            const status = document.getElementById('status').value;
+            const status = selectedStatus();
+            const companyFilter = readCompanyFilter();
             
             let filtered = allJobs.filter(job => {
                 // Search filter
@@ -262,6 +308,9 @@ const indexHTML = `<!DOCTYPE html>
                 // Status filter
                 if (status && job.Status !== status) return false;
                 
+                // YC batch, industry and team size
+                if (!matchesCompany(job, companyFilter)) return false;
+                
                 return true;
             });
             
@@ -286,18 +335,21 @@ const indexHTML = `<!DOCTYPE html>
             }
             
             jobsList.innerHTML = jobs.map((job, index) => 
-                '<div class="job-item ' + (job.Status === 'Applied' ? 'status-applied' : '') + '" data-index="' + index + '">' +
+                '<div class="job-item ' + (hasStatus && job.Status === 'Applied' ? 'status-applied' : '') + '" data-index="' + index + '">' +
                     '<div class="job-info">' +
                         '<div class="job-title">' + escapeHtml(job.Title) + '</div>' +
                         '<div class="job-meta">' +
-                            '<strong>' + escapeHtml(job.Company) + '</strong> &bull; ' + escapeHtml(job.Location) + '<br>' +
-                            'Added: ' + new Date(job.DateAdded).toLocaleDateString() + ' &bull; Status: <span id="status-' + index + '">' + job.Status + '</span>' +
+                            '<strong>' + (job.Website ? '<a href="' + escapeHtml(job.Website) + '" target="_blank">' + escapeHtml(job.Company) + '</a>' : escapeHtml(job.Company)) + '</strong> &bull; ' + escapeHtml(job.Location) + '<br>' +
+                            'Added: ' + new Date(job.DateAdded).toLocaleDateString() +
+                            ' &bull; ' + (job.ClosedAt ? 'Was open ' : 'Open ') + job.OpenDays + (job.OpenDays === 1 ? ' day' : ' days') +
+                            (hasStatus ? ' &bull; Status: <span id="status-' + index + '">' + job.Status + '</span>' : '') +
                         '</div>' +
                         '<span class="job-level">' + escapeHtml(job.Levels) + '</span>' +
                     '</div>' +
                     '<div class="job-actions">' +
                         '<a href="' + escapeHtml(job.URL) + '" target="_blank" class="btn-apply">Apply &rarr;</a>' +
-                        (job.Status === 'Not Applied' 
+                        (!hasStatus ? ''
+                            : job.Status === 'Not Applied' 
                             ? '<button class="btn-mark-applied" onclick="markApplied(' + index + ')">✓ Mark Applied</button>'
                             : '<span class="applied-badge">✓ Applied</span>') +
                     '</div>' +
@@ -344,7 +396,8 @@ const indexHTML = `<!DOCTYPE html>
             const selectedLevels = Array.from(document.querySelectorAll('#levels input:checked')).map(cb => cb.value.toLowerCase());
             const company = document.getElementById('company').value;
             const location = document.getElementById('location').value;
-            const status = document.getElementById('status').value;
+            const status = selectedStatus();
+            const companyFilter = readCompanyFilter();
             
             return allJobs.filter(job => {
                 if (search) {
@@ -359,19 +412,47 @@ const indexHTML = `<!DOCTYPE html>
                 if (company && job.Company !== company) return false;
                 if (location && job.Location !== location) return false;
                 if (status && job.Status !== status) return false;
+                if (!matchesCompany(job, companyFilter)) return false;
                 return true;
             });
         }
         
         function updateStats(jobs) {
+            document.getElementById('filtered-count').textContent = jobs.length;
+            if (!hasStatus) return;
+            
             const notApplied = jobs.filter(j => j.Status === 'Not Applied').length;
             const applied = jobs.filter(j => j.Status === 'Applied').length;
-            
-            document.getElementById('filtered-count').textContent = jobs.length;
             document.getElementById('not-applied-count').textContent = notApplied;
             document.getElementById('applied-count').textContent = applied;
         }
         
+        // The status select only exists when the site was built for a user
+        function selectedStatus() {
+            const el = document.getElementById('status');
+            return el ? el.value : '';
+        }
+        
+        // The YC directory selects only exist once a directory was imported
+        function readCompanyFilter() {
+            const value = id => {
+                const el = document.getElementById(id);
+                return el ? el.value : '';
+            };
+            return {
+                batchKey: Number(value('batch')) || 0,
+                industry: value('industry'),
+                teamLt: Number(value('team-size')) || 0
+            };
+        }
+        
+        function matchesCompany(job, f) {
+            if (f.batchKey && job.BatchKey < f.batchKey) return false;
+            if (f.industry && job.Industry !== f.industry) return false;
+            if (f.teamLt && (!job.TeamSize || job.TeamSize >= f.teamLt)) return false;
+            return true;
+        }
+        
         function escapeHtml(text) {
             const div = document.createElement('div');
             div.textContent = text;
@@ -383,7 +464,8 @@ const indexHTML = `<!DOCTYPE html>
             const selectedLevels = Array.from(document.querySelectorAll('#levels input:checked')).map(cb => cb.value.toLowerCase());
             const company = document.getElementById('company').value;
             const location = document.getElementById('location').value;
-            const status = document.getElementById('status').value;
+            const status = selectedStatus();
+            const companyFilter = readCompanyFilter();
             
             let filtered = allJobs.filter(job => {
                 if (search) {
@@ -398,20 +480,22 @@ const indexHTML = `<!DOCTYPE html>
                 if (company && job.Company !== company) return false;
                 if (location && job.Location !== location) return false;
                 if (status && job.Status !== status) return false;
+                if (!matchesCompany(job, companyFilter)) return false;
                 return true;
             });
             
-            let csv = 'Date,Company,Title,Location,Level,Status,URL\n';
+            let csv = hasStatus ? 'Date,Company,Title,Location,Level,Status,URL\n' : 'Date,Company,Title,Location,Level,URL\n';
             filtered.forEach(job => {
-                const row = [
+                const fields = [
                     new Date(job.DateAdded).toLocaleDateString(),
                     job.Company,
                     job.Title,
                     job.Location,
-                    job.Levels,
-                    job.Status,
-                    job.URL
-                ].map(field => '"' + String(field).replace(/"/g, '""') + '"');
+                    job.Levels
+                ];
+                if (hasStatus) fields.push(job.Status);
+                fields.push(job.URL);
+                const row = fields.map(field => '"' + String(field).replace(/"/g, '""') + '"');
                 csv += row.join(',') + '\n';
             });
             
@@ -435,6 +519,7 @@ const indexHTML = `<!DOCTYPE html>
 func main() {
     outDir := flag.String("out", "public", "Output directory for static site")
     demo := flag.Bool("demo", false, "Generate demo site using sample data without DB")
+    user := flag.String("user", "", "publish this dashboard user's application statuses")
     flag.Parse()
 
     logger.InitFromEnv()
@@ -450,8 +535,12 @@ func main() {
     levelSet := map[string]bool{}
     companySet := map[string]bool{}
     locationSet := map[string]bool{}
+    batchSet := map[string]int{}
+    industrySet := map[string]bool{}
     notApplied := 0
     applied := 0
+    // Without a user there is no application state to publish
+    hasStatus := *demo || *user != ""
 
     if *demo {
         // Demo mode: create sample jobs without connecting to DB
@@ -463,6 +552,8 @@ func main() {
         }
 
         for _, job := range sample {
+            job.FirstSeenAt = job.DateAdded
+            job.OpenDays = db.Job{FirstSeenAt: job.FirstSeenAt}.OpenDays()
             // Derive levels
             levels := deriveLevels(job.Title)
             for _, lv := range levels {
@@ -473,12 +564,15 @@ func main() {
                 levelsStr = "General"
             }
 
-            statusClass := "not-applied"
-            if job.Status == "Applied" {
-                statusClass = "applied"
-                applied++
-            } else {
-                notApplied++
+            statusClass := ""
+            if hasStatus {
+                statusClass = "not-applied"
+                if job.Status == "Applied" {
+                    statusClass = "applied"
+                    applied++
+                } else {
+                    notApplied++
+                }
             }
 
             jobs = append(jobs, JobWithLevels{
@@ -497,21 +591,35 @@ func main() {
         }
         defer d.Close()
 
-        // Fetch all jobs
-        rows, err := d.Conn.Query(`SELECT id, title, company, location, type, url, date_added, status FROM job_applications ORDER BY date_added DESC`)
+        filter := db.JobFilter{}
+        if *user != "" {
+            if filter.UserID, _, _, err = d.GetUserByUsername(*user); err != nil {
+                logger.Fatal("unknown user %q: %v", *user, err)
+            }
+        }
+
+        // Open jobs from the shared query, named after their catalog entry when linked
+        list, err := d.ListJobs(filter, 0, 0)
         if err != nil {
             logger.Fatal("query jobs: %v", err)
         }
-        defer rows.Close()
-
-        for rows.Next() {
-            var job Job
-            var typ string
-            if err := rows.Scan(&job.ID, &job.Title, &job.Company, &job.Location, &typ, &job.URL, &job.DateAdded, &job.Status); err != nil {
-                logger.Error("scan row: %v", err)
-                continue
+        catalog, err := d.ListCompanies()
+        if err != nil {
+            logger.Fatal("list companies: %v", err)
+        }
+        byID := map[int]db.Company{}
+        for _, c := range catalog {
+            byID[c.ID] = c
+        }
+
+        for _, lj := range list {
+            job := Job{ID: lj.ID, Title: lj.Title, Company: lj.Company, Location: lj.Location, Type: lj.Type,
+                URL: lj.URL, DateAdded: lj.DateAdded, Status: lj.Status,
+                FirstSeenAt: lj.FirstSeenAt, ClosedAt: lj.ClosedAt, OpenDays: lj.OpenDays()}
+            if c, ok := byID[lj.CompanyID]; ok {
+                job.Company, job.Website, job.Batch, job.BatchKey = c.Name, c.Website, c.YCBatch, ycdir.BatchKey(c.YCBatch)
+                job.Industry, job.TeamSize = c.Industry, c.TeamSize
             }
-            job.Type = typ
 
             // Derive levels
             levels := deriveLevels(job.Title)
@@ -523,12 +631,15 @@ func main() {
                 levelsStr = "General"
             }
 
-            statusClass := "not-applied"
-            if job.Status == "Applied" {
-                statusClass = "applied"
-                applied++
-            } else {
-                notApplied++
+            statusClass := ""
+            if hasStatus {
+                statusClass = "not-applied"
+                if job.Status == "Applied" {
+                    statusClass = "applied"
+                    applied++
+                } else {
+                    notApplied++
+                }
             }
 
             jobs = append(jobs, JobWithLevels{
@@ -539,6 +650,12 @@ func main() {
 
             companySet[job.Company] = true
             locationSet[job.Location] = true
+            if job.BatchKey > 0 {
+                batchSet[job.Batch] = job.BatchKey
+            }
+            if job.Industry != "" {
+                industrySet[job.Industry] = true
+            }
         }
     }
 
@@ -561,6 +678,23 @@ func main() {
 	}
 	sort.Strings(locations)
 
+	// YC batches newest first, for "batch or later" filters
+	type batchOption struct {
+		Name string
+		Key  int
+	}
+	var batches []batchOption
+	for name, key := range batchSet {
+		batches = append(batches, batchOption{name, key})
+	}
+	sort.Slice(batches, func(i, j int) bool { return batches[i].Key > batches[j].Key })
+
+	var industries []string
+	for k := range industrySet {
+		industries = append(industries, k)
+	}
+	sort.Strings(industries)
+
 	// Generate index.html
 	tmpl := template.Must(template.New("index").Parse(indexHTML))
 
@@ -575,7 +709,10 @@ func main() {
 		Levels     []string
 		Companies  []string
 		Locations  []string
+		Batches    []batchOption
+		Industries []string
 		TotalJobs  int
+		HasStatus  bool
 		NotApplied int
 		Applied    int
 		JobsJSON   template.JS
@@ -584,7 +721,10 @@ func main() {
 		Levels:     levels,
 		Companies:  companies,
 		Locations:  locations,
+		Batches:    batches,
+		Industries: industries,
 		TotalJobs:  len(jobs),
+		HasStatus:  hasStatus,
 		NotApplied: notApplied,
 		Applied:    applied,
 		JobsJSON:   template.JS(jobsJSON),
@@ -3,8 +3,11 @@ module github.com/ajiteshreddy7/yc-go-scraper
 go 1.24.0
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/gorilla/sessions v1.4.0
+	github.com/robfig/cron/v3 v3.0.1
 	golang.org/x/crypto v0.43.0
+	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.39.1
 )
 
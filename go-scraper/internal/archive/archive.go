@@ -0,0 +1,171 @@
+// Package archive keeps the raw response of every job board fetched during
+// a scrape run, gzip-compressed, so a run can be parsed and filtered again
+// later without network access.
+//
+// Responses are stored as <dir>/<run>/<platform>/<slug>.json.gz, where run
+// is the UTC start time of the scrape run, e.g. 20261019T030000Z.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runIDFormat names runs after their start time, so they sort by age.
+const runIDFormat = "20060102T150405Z"
+
+const ext = ".json.gz"
+
+// Archive is a directory of archived scrape runs.
+type Archive struct {
+	Dir string
+}
+
+// New returns the archive kept in dir.
+func New(dir string) *Archive {
+	return &Archive{Dir: dir}
+}
+
+// Run is one scrape run being archived. A nil *Run archives nothing, so
+// callers need not check whether archiving is enabled.
+type Run struct {
+	ID  string
+	dir string
+}
+
+// NewRun starts archiving a run that began at started.
+func (a *Archive) NewRun(started time.Time) *Run {
+	id := started.UTC().Format(runIDFormat)
+	return &Run{ID: id, dir: filepath.Join(a.Dir, id)}
+}
+
+// Put stores a board's raw response, fetched at the given time.
+func (r *Run) Put(platform, slug string, fetched time.Time, body []byte) error {
+	if r == nil {
+		return nil
+	}
+	path := filepath.Join(r.dir, platform, url.PathEscape(slug)+ext)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = slug + ".json"
+	zw.ModTime = fetched
+	if _, err := zw.Write(body); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	// Write then rename, so a crash never leaves a truncated response
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Runs lists the archived run IDs, oldest first.
+func (a *Archive) Runs() ([]string, error) {
+	entries, err := os.ReadDir(a.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		if _, err := time.Parse(runIDFormat, e.Name()); e.IsDir() && err == nil {
+			out = append(out, e.Name())
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Latest returns the most recent run ID.
+func (a *Archive) Latest() (string, error) {
+	runs, err := a.Runs()
+	if err != nil {
+		return "", err
+	}
+	if len(runs) == 0 {
+		return "", fmt.Errorf("no archived runs in %s", a.Dir)
+	}
+	return runs[len(runs)-1], nil
+}
+
+// Entry is one archived board response.
+type Entry struct {
+	Run      string
+	Platform string
+	Slug     string
+	path     string
+}
+
+// Entries lists the board responses archived for a run.
+func (a *Archive) Entries(run string) ([]Entry, error) {
+	if _, err := time.Parse(runIDFormat, run); err != nil {
+		return nil, fmt.Errorf("invalid run id %q", run)
+	}
+	dir := filepath.Join(a.Dir, run)
+	platforms, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no archived run %s in %s", run, a.Dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Entry
+	for _, p := range platforms {
+		if !p.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(dir, p.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if !strings.HasSuffix(f.Name(), ext) {
+				continue
+			}
+			slug, err := url.PathUnescape(strings.TrimSuffix(f.Name(), ext))
+			if err != nil {
+				continue
+			}
+			out = append(out, Entry{Run: run, Platform: p.Name(), Slug: slug,
+				path: filepath.Join(dir, p.Name(), f.Name())})
+		}
+	}
+	return out, nil
+}
+
+// Read returns an archived response and when it was fetched.
+func (e Entry) Read() ([]byte, time.Time, error) {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", e.path, err)
+	}
+	defer zr.Close()
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", e.path, err)
+	}
+	return body, zr.ModTime, nil
+}
@@ -0,0 +1,53 @@
+package archive
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	a := New(t.TempDir())
+	if runs, err := a.Runs(); err != nil || len(runs) != 0 {
+		t.Fatalf("Runs on empty archive = %v, %v", runs, err)
+	}
+
+	var none *Run
+	if err := none.Put("greenhouse", "acme", time.Now(), []byte("{}")); err != nil {
+		t.Errorf("nil Run Put = %v", err)
+	}
+
+	fetched := time.Date(2026, 10, 19, 3, 0, 5, 0, time.UTC)
+	older := a.NewRun(fetched.Add(-24 * time.Hour))
+	run := a.NewRun(fetched)
+	if run.ID != "20261019T030005Z" {
+		t.Errorf("run ID = %s", run.ID)
+	}
+	for _, r := range []*Run{older, run} {
+		if err := r.Put("greenhouse", "acme", fetched, []byte(`{"jobs":[]}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := run.Put("lever", "odd/slug", fetched, []byte(`[]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if latest, err := a.Latest(); err != nil || latest != run.ID {
+		t.Errorf("Latest = %s, %v; want %s", latest, err, run.ID)
+	}
+	entries, err := a.Entries(run.ID)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Entries = %+v, %v", entries, err)
+	}
+	for _, e := range entries {
+		body, at, err := e.Read()
+		if err != nil || !at.Equal(fetched) || len(body) == 0 {
+			t.Errorf("Read(%s/%s) = %q, %v, %v", e.Platform, e.Slug, body, at, err)
+		}
+		if e.Platform == "lever" && e.Slug != "odd/slug" {
+			t.Errorf("slug = %q, want it unescaped", e.Slug)
+		}
+	}
+	if _, err := a.Entries("20200101T000000Z"); err == nil {
+		t.Error("Entries of a missing run succeeded")
+	}
+}
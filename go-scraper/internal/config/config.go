@@ -0,0 +1,317 @@
+// Package config loads and validates the scraper configuration.
+//
+// A config file may be JSON, YAML or TOML (picked by extension), may pull in
+// other files through "include", and can be overridden by SCRAPER_*
+// environment variables. The legacy {"target_platforms": {...}} layout is
+// still accepted alongside the structured "companies" list.
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/scraper"
+)
+
+// Config is the full scraper configuration.
+type Config struct {
+	Include   []string  `yaml:"include" json:"include,omitempty"`
+	Filters   Filters   `yaml:"filters" json:"filters"`
+	Companies []Company `yaml:"companies" json:"companies"`
+	Profiles  []Profile `yaml:"profiles" json:"profiles,omitempty"`
+	Schedule  Schedule  `yaml:"schedule" json:"schedule"`
+	Health    Health    `yaml:"health" json:"health"`
+	Archive   Archive   `yaml:"archive" json:"archive"`
+	Export    Export    `yaml:"export" json:"export"`
+	Retention Retention `yaml:"retention" json:"retention"`
+	Pipeline  Pipeline  `yaml:"pipeline" json:"pipeline"`
+
+	// TargetPlatforms is the original layout: company slugs per platform.
+	TargetPlatforms map[string][]string `yaml:"target_platforms" json:"target_platforms,omitempty"`
+
+	// pos maps key paths such as "companies[2].slug" to where they were set.
+	pos map[string]Position
+}
+
+// Company is one board to scrape.
+type Company struct {
+	Slug     string   `yaml:"slug" json:"slug"`
+	Platform string   `yaml:"platform" json:"platform"`
+	Name     string   `yaml:"name,omitempty" json:"name,omitempty"`
+	Enabled  *bool    `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	Tags     []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Host     string   `yaml:"host,omitempty" json:"host,omitempty"` // board API host override
+	Schedule string   `yaml:"schedule,omitempty" json:"schedule,omitempty"`
+	Filters  *Filters `yaml:"filters,omitempty" json:"filters,omitempty"`
+
+	// Catalog metadata shown in the dashboard and static site.
+	Website string `yaml:"website,omitempty" json:"website,omitempty"`
+	LogoURL string `yaml:"logo_url,omitempty" json:"logo_url,omitempty"`
+	YCBatch string `yaml:"yc_batch,omitempty" json:"yc_batch,omitempty"` // e.g. "W21"
+}
+
+// Filters override the default early-career, US-only job filter. Unset
+// fields inherit from the level above (company → top-level → default).
+type Filters struct {
+	EarlyCareer   *bool    `yaml:"early_career" json:"early_career,omitempty"`
+	USOnly        *bool    `yaml:"us_only" json:"us_only,omitempty"`
+	IncludeTitles []string `yaml:"include_titles" json:"include_titles,omitempty"` // regular expressions
+	ExcludeTitles []string `yaml:"exclude_titles" json:"exclude_titles,omitempty"` // regular expressions
+	Locations     []string `yaml:"locations" json:"locations,omitempty"`           // substrings
+}
+
+// Profile is a named search over some or all companies with its own
+// filters and export file. Each board is fetched once per run and its jobs
+// are matched against every profile that covers the company.
+type Profile struct {
+	Name      string   `yaml:"name" json:"name"`
+	Companies []string `yaml:"companies" json:"companies,omitempty"` // slugs or "platform/slug"
+	Tags      []string `yaml:"tags" json:"tags,omitempty"`           // companies with any of these tags
+	Filters   Filters  `yaml:"filters" json:"filters"`
+	Output    string   `yaml:"output" json:"output,omitempty"` // CSV export path
+}
+
+// Schedule controls when each company is scraped in daemon mode. Specs are
+// standard 5-field cron expressions or descriptors such as "@hourly" and
+// "@every 6h".
+type Schedule struct {
+	Default   string            `yaml:"default" json:"default,omitempty"`
+	Jitter    string            `yaml:"jitter" json:"jitter,omitempty"`       // e.g. "10m"
+	Platforms map[string]string `yaml:"platforms" json:"platforms,omitempty"` // per platform
+	Companies map[string]string `yaml:"companies" json:"companies,omitempty"` // per slug or "platform/slug"
+}
+
+// Health controls how failing company boards are backed off and disabled.
+type Health struct {
+	Backoff      string `yaml:"backoff" json:"backoff,omitempty"`             // skip after the first failure, doubled each time; default 12h
+	MaxBackoff   string `yaml:"max_backoff" json:"max_backoff,omitempty"`     // default 168h
+	DisableAfter *int   `yaml:"disable_after" json:"disable_after,omitempty"` // consecutive failures; default 5, 0 never disables
+}
+
+// Archive controls whether raw board responses are kept for reprocessing.
+type Archive struct {
+	Dir string `yaml:"dir" json:"dir,omitempty"` // relative to the working directory; empty disables archiving
+}
+
+// Export controls the CSVs the scraper writes after each run.
+type Export struct {
+	User string `yaml:"user" json:"user,omitempty"` // dashboard user whose statuses fill a Status column; empty leaves it out
+}
+
+// Retention moves old postings out of the jobs table into the archive, see
+// db.ArchiveJobs. Jobs the user has acted on are never archived.
+type Retention struct {
+	Schedule string          `yaml:"schedule" json:"schedule,omitempty"` // daemon mode; empty runs only with "scraper db archive"
+	Rules    []RetentionRule `yaml:"rules" json:"rules,omitempty"`
+}
+
+// RetentionRule selects jobs to archive. Every condition set must hold.
+type RetentionRule struct {
+	Name       string `yaml:"name" json:"name"`
+	ClosedDays int    `yaml:"closed_days,omitempty" json:"closed_days,omitempty"` // closed at least this many days ago
+	AgeDays    int    `yaml:"age_days,omitempty" json:"age_days,omitempty"`       // first seen at least this many days ago, open or closed
+}
+
+// ArchiveRules returns the rules for db.ArchiveJobs.
+func (r Retention) ArchiveRules() []db.ArchiveRule {
+	var out []db.ArchiveRule
+	for _, rule := range r.Rules {
+		out = append(out, db.ArchiveRule{
+			Name:      rule.Name,
+			ClosedFor: time.Duration(rule.ClosedDays) * 24 * time.Hour,
+			Age:       time.Duration(rule.AgeDays) * 24 * time.Hour,
+		})
+	}
+	return out
+}
+
+// Pipeline replaces the dashboard's application stages, see
+// db.DefaultPipeline. Every job starts at "Not Applied".
+type Pipeline struct {
+	Stages []string `yaml:"stages" json:"stages,omitempty"`
+	// Transitions lists the stages each stage, or "Not Applied", may move
+	// to; without it any move is allowed.
+	Transitions map[string][]string `yaml:"transitions" json:"transitions,omitempty"`
+}
+
+// Application returns the configured pipeline, or db.DefaultPipeline when
+// there is none.
+func (p Pipeline) Application() db.Pipeline {
+	if len(p.Stages) == 0 {
+		return db.DefaultPipeline
+	}
+	return db.Pipeline{Stages: p.Stages, Transitions: p.Transitions}
+}
+
+// Defaults for Health.
+const (
+	DefaultBackoff      = 12 * time.Hour
+	DefaultMaxBackoff   = 7 * 24 * time.Hour
+	DefaultDisableAfter = 5
+)
+
+// Policy returns the backoff policy. Values are assumed valid; Load has
+// already checked them.
+func (h Health) Policy() db.Backoff {
+	b := db.Backoff{Base: DefaultBackoff, Max: DefaultMaxBackoff, DisableAfter: DefaultDisableAfter}
+	if d, err := time.ParseDuration(h.Backoff); err == nil {
+		b.Base = d
+	}
+	if d, err := time.ParseDuration(h.MaxBackoff); err == nil {
+		b.Max = d
+	}
+	if h.DisableAfter != nil {
+		b.DisableAfter = *h.DisableAfter
+	}
+	return b
+}
+
+// DefaultSchedule is used when no schedule is configured for a company.
+const DefaultSchedule = "0 3 * * *"
+
+// String returns "platform/slug".
+func (c Company) String() string {
+	return c.Platform + "/" + c.Slug
+}
+
+// IsEnabled reports whether the company should be scraped.
+func (c Company) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// Board returns the scraper board for the company.
+func (c Company) Board() scraper.Board {
+	return scraper.Board{Platform: c.Platform, Slug: c.Slug, Name: c.Name, Host: c.Host}
+}
+
+// DisplayName returns the company name stored on its jobs.
+func (c Company) DisplayName() string {
+	return c.Board().DisplayName()
+}
+
+// EnabledCompanies returns the companies to scrape, grouped by platform in
+// scraping order. Legacy target_platforms entries are included: Load folds
+// them into Companies.
+func (cfg *Config) EnabledCompanies() []Company {
+	var out []Company
+	for _, p := range scraper.Platforms {
+		for _, c := range cfg.Companies {
+			if c.Platform == p && c.IsEnabled() {
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// FilterFor builds the job filter for a company.
+func (cfg *Config) FilterFor(c Company) (scraper.Filter, error) {
+	f := scraper.DefaultFilter()
+	if err := cfg.Filters.apply(&f); err != nil {
+		return f, err
+	}
+	if c.Filters != nil {
+		if err := c.Filters.apply(&f); err != nil {
+			return f, fmt.Errorf("%s: %w", c, err)
+		}
+	}
+	return f, nil
+}
+
+// Includes reports whether a profile covers a company. A profile that lists
+// neither companies nor tags covers every company.
+func (p Profile) Includes(c Company) bool {
+	if len(p.Companies) == 0 && len(p.Tags) == 0 {
+		return true
+	}
+	for _, name := range p.Companies {
+		if c.is(name) {
+			return true
+		}
+	}
+	for _, t := range p.Tags {
+		for _, ct := range c.Tags {
+			if t == ct {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProfileFilter builds the job filter for a company within a profile: the
+// company's filter with the profile's overrides on top.
+func (cfg *Config) ProfileFilter(p Profile, c Company) (scraper.Filter, error) {
+	f, err := cfg.FilterFor(c)
+	if err != nil {
+		return f, err
+	}
+	if err := p.Filters.apply(&f); err != nil {
+		return f, fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	return f, nil
+}
+
+// apply overlays the set fields onto f.
+func (o Filters) apply(f *scraper.Filter) error {
+	if o.EarlyCareer != nil {
+		f.EarlyCareer = *o.EarlyCareer
+	}
+	if o.USOnly != nil {
+		f.USOnly = *o.USOnly
+	}
+	if o.IncludeTitles != nil {
+		res, err := compileAll(o.IncludeTitles)
+		if err != nil {
+			return err
+		}
+		f.IncludeTitles = res
+	}
+	if o.ExcludeTitles != nil {
+		res, err := compileAll(o.ExcludeTitles)
+		if err != nil {
+			return err
+		}
+		f.ExcludeTitles = res
+	}
+	if o.Locations != nil {
+		f.Locations = o.Locations
+	}
+	return nil
+}
+
+func compileAll(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// SpecFor returns the most specific schedule for a company: its own
+// schedule, then "platform/slug" or slug in schedule.companies, then the
+// platform, then the default.
+func (s Schedule) SpecFor(c Company) string {
+	if c.Schedule != "" {
+		return c.Schedule
+	}
+	if spec, ok := s.Companies[c.String()]; ok {
+		return spec
+	}
+	if spec, ok := s.Companies[c.Slug]; ok {
+		return spec
+	}
+	if spec, ok := s.Platforms[c.Platform]; ok {
+		return spec
+	}
+	if s.Default != "" {
+		return s.Default
+	}
+	return DefaultSchedule
+}
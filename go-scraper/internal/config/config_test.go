@@ -0,0 +1,262 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFormats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"c.json": `{"target_platforms": {"lever": ["plaid"]}, "companies": [{"slug": "stripe", "platform": "greenhouse", "name": "Stripe, Inc."}]}`,
+		"c.yaml": "target_platforms:\n  lever: [plaid]\ncompanies:\n  - slug: stripe\n    platform: greenhouse\n    name: Stripe, Inc.\n",
+		"c.toml": "[target_platforms]\nlever = [\"plaid\"]\n\n[[companies]]\nslug = \"stripe\"\nplatform = \"greenhouse\"\nname = \"Stripe, Inc.\"\n",
+	}
+	for name, src := range files {
+		cfg, err := Load(writeFile(t, dir, name, src))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got := cfg.EnabledCompanies()
+		if len(got) != 2 || got[0].String() != "greenhouse/stripe" || got[1].String() != "lever/plaid" {
+			t.Fatalf("%s: companies = %v", name, got)
+		}
+		if got[0].DisplayName() != "Stripe, Inc." || got[1].DisplayName() != "Plaid" {
+			t.Errorf("%s: display names = %q, %q", name, got[0].DisplayName(), got[1].DisplayName())
+		}
+	}
+}
+
+func TestCheckReportsPositions(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "c.yaml", `companies:
+  - slug: stripe
+    platform: greenhouse
+    colour: blue
+  - slug: plaid
+    platform: workday
+filters:
+  exclude_titles: ["(senior"]
+`)
+	_, problems, err := Check(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		path + `:4:5: unknown key "colour" in companies[0]`,
+		path + `:6:15: unsupported platform "workday"`,
+		path + `:8:20: invalid pattern "(senior"`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %v", problems)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(problems[i].String(), w) {
+			t.Errorf("problem %d = %q, want prefix %q", i, problems[i], w)
+		}
+	}
+}
+
+func TestTOMLPositions(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "c.toml", "[[companies]]\nslug = \"a\"\nplatform = \"lever\"\n\n[[companies]]\nslug = \"b\"\nplatform = \"lever\"\nschedule = \"nope\"\n")
+	_, problems, err := Check(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Line != 8 {
+		t.Fatalf("problems = %v, want one on line 8", problems)
+	}
+}
+
+func TestIncludesAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "companies/a.yaml", "companies:\n  - {slug: airbnb, platform: greenhouse}\nschedule:\n  default: \"@hourly\"\n")
+	writeFile(t, dir, "companies/b.yaml", "companies:\n  - {slug: plaid, platform: lever, enabled: false}\n")
+	path := writeFile(t, dir, "main.yaml", "include: [companies/*.yaml]\nschedule:\n  default: \"@daily\"\ncompanies:\n  - {slug: stripe, platform: greenhouse}\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(cfg.EnabledCompanies()); n != 2 {
+		t.Errorf("enabled companies = %d, want 2", n)
+	}
+	if cfg.Schedule.Default != "@daily" {
+		t.Errorf("schedule.default = %q, the including file should win", cfg.Schedule.Default)
+	}
+
+	t.Setenv("SCRAPER_ONLY", "airbnb")
+	t.Setenv("SCRAPER_SCHEDULE_DEFAULT", "@weekly")
+	t.Setenv("SCRAPER_FILTERS_US_ONLY", "false")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.EnabledCompanies()
+	if len(got) != 1 || got[0].Slug != "airbnb" {
+		t.Errorf("SCRAPER_ONLY: enabled = %v", got)
+	}
+	if cfg.Schedule.Default != "@weekly" {
+		t.Errorf("schedule.default = %q, want env override", cfg.Schedule.Default)
+	}
+	f, err := cfg.FilterFor(got[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.USOnly || !f.EarlyCareer {
+		t.Errorf("filter = %+v, want US-only off and early-career on", f)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "b.yaml", "include: [a.yaml]\n")
+	path := writeFile(t, dir, "a.yaml", "include: [b.yaml]\n")
+	_, problems, err := Check(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0].Msg, "include cycle") {
+		t.Fatalf("problems = %v", problems)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "c.yaml", `companies:
+  - {slug: stripe, platform: greenhouse, tags: [fintech]}
+  - {slug: plaid, platform: lever, tags: [fintech]}
+  - {slug: netflix, platform: lever}
+profiles:
+  - name: internships
+    filters: {early_career: false, include_titles: ["(?i)intern"]}
+    output: out/internships.csv
+  - name: fintech
+    tags: [fintech]
+  - name: streaming
+    companies: [lever/netflix, nope]
+`)
+	_, problems, err := Check(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0].Msg, `unknown company "nope"`) || problems[0].Line != 12 {
+		t.Fatalf("problems = %v", problems)
+	}
+
+	cfg, _, _ := Check(path)
+	netflix := cfg.Companies[2]
+	if cfg.Profiles[1].Includes(netflix) || !cfg.Profiles[2].Includes(netflix) || !cfg.Profiles[0].Includes(netflix) {
+		t.Error("Includes should honour tags, companies and the empty default")
+	}
+	f, err := cfg.ProfileFilter(cfg.Profiles[0], netflix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.EarlyCareer || !f.USOnly || len(f.IncludeTitles) != 1 {
+		t.Errorf("ProfileFilter = %+v", f)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "c.yaml", `companies:
+  - slug: stripe
+    platform: greenhouse
+retention:
+  schedule: "0 4 * * *"
+  rules:
+    - name: stale
+      closed_days: 90
+    - name: stale
+      age_days: 365
+    - name: empty
+`)
+	_, problems, err := Check(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		path + `:9:13: duplicate retention rule "stale"`,
+		path + `:11:7: retention rule "empty" needs closed_days or age_days`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %v", problems)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(problems[i].String(), w) {
+			t.Errorf("problem %d = %q, want prefix %q", i, problems[i], w)
+		}
+	}
+
+	cfg, err := Load(writeFile(t, dir, "ok.yaml", `companies:
+  - slug: stripe
+    platform: greenhouse
+retention:
+  rules:
+    - name: stale
+      closed_days: 90
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := cfg.Retention.ArchiveRules()
+	if len(rules) != 1 || rules[0].Name != "stale" || rules[0].ClosedFor != 90*24*time.Hour || rules[0].Age != 0 {
+		t.Errorf("ArchiveRules = %+v", rules)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "c.yaml", `companies:
+  - slug: stripe
+    platform: greenhouse
+pipeline:
+  stages: [Applied, Interview, Applied, Not Applied]
+  transitions:
+    Applied: [Interview, Hired]
+`)
+	_, problems, err := Check(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		path + `:5:32: duplicate pipeline stage "Applied"`,
+		path + `:5:41: "Not Applied" is where every job starts`,
+		path + `:7:26: transition to unknown stage "Hired"`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %v", problems)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(problems[i].String(), w) {
+			t.Errorf("problem %d = %q, want prefix %q", i, problems[i], w)
+		}
+	}
+
+	cfg, err := Load(writeFile(t, dir, "ok.yaml", "companies:\n  - {slug: stripe, platform: greenhouse}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.Pipeline.Application(); len(p.Stages) != len(db.DefaultPipeline.Stages) {
+		t.Errorf("Application without a pipeline = %+v, want the default", p)
+	}
+}
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads, merges and validates a config file, applying environment
+// overrides. Validation failures are returned as Problems.
+func Load(path string) (*Config, error) {
+	cfg, problems, err := Check(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, Problems(problems)
+	}
+	return cfg, nil
+}
+
+// Check is Load that returns every problem found instead of failing on the
+// first. The error is only set when the file cannot be read at all.
+func Check(path string) (*Config, []Problem, error) {
+	l := &loader{stack: map[string]bool{}}
+	cfg, err := l.load(path, Position{File: path})
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.foldLegacy()
+	l.problems = append(l.problems, cfg.applyEnv(os.LookupEnv)...)
+	l.problems = append(l.problems, cfg.validate()...)
+	return cfg, l.problems, nil
+}
+
+type loader struct {
+	stack    map[string]bool // files being loaded, to catch include cycles
+	problems []Problem
+}
+
+// load parses one file and the files it includes. from is where the file
+// was referenced, for reporting problems with the reference itself.
+func (l *loader) load(path string, from Position) (*Config, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if l.stack[abs] {
+		l.problems = append(l.problems, Problem{from, fmt.Sprintf("include cycle through %s", path)})
+		return &Config{pos: map[string]Position{}}, nil
+	}
+	l.stack[abs] = true
+	defer delete(l.stack, abs)
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	node, problem := parse(path, src)
+	cfg := &Config{pos: map[string]Position{}}
+	if problem != nil {
+		l.problems = append(l.problems, *problem)
+		return cfg, nil
+	}
+	if node == nil {
+		return cfg, nil // empty file
+	}
+
+	l.problems = append(l.problems, walk(node, reflect.TypeOf(Config{}), "", path, cfg.pos)...)
+	if err := node.Decode(cfg); err != nil {
+		l.problems = append(l.problems, decodeProblems(path, err)...)
+	}
+
+	for i, pattern := range cfg.Include {
+		ref := cfg.pos[fmt.Sprintf("include[%d]", i)]
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			l.problems = append(l.problems, Problem{ref, fmt.Sprintf("bad include pattern: %v", err)})
+			continue
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			l.problems = append(l.problems, Problem{ref, fmt.Sprintf("included file %s does not exist", pattern)})
+			continue
+		}
+		for _, m := range matches {
+			inc, err := l.load(m, ref)
+			if err != nil {
+				l.problems = append(l.problems, Problem{ref, err.Error()})
+				continue
+			}
+			cfg.merge(inc)
+		}
+	}
+	return cfg, nil
+}
+
+// parse turns JSON, YAML or TOML source into a YAML node tree so every
+// format is validated the same way. JSON is parsed as YAML, which it is a
+// subset of; TOML is decoded and re-encoded with positions recovered from
+// the source.
+func parse(path string, src []byte) (*yaml.Node, *Problem) {
+	var doc yaml.Node
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		var m map[string]interface{}
+		if _, err := toml.Decode(string(src), &m); err != nil {
+			p := &Problem{Position{File: path}, err.Error()}
+			var perr toml.ParseError
+			if errors.As(err, &perr) {
+				p.Line, p.Col = perr.Position.Line, perr.Position.Col
+				p.Msg = perr.Message
+			}
+			return nil, p
+		}
+		if err := doc.Encode(m); err != nil {
+			return nil, &Problem{Position{File: path}, err.Error()}
+		}
+		tomlPositions(&doc, strings.Split(string(src), "\n"), "", 1)
+		return &doc, nil
+	}
+
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, syntaxProblem(path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+var lineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+func syntaxProblem(path string, err error) *Problem {
+	p := &Problem{Position{File: path}, err.Error()}
+	if m := lineRe.FindStringSubmatch(err.Error()); m != nil {
+		p.Line, _ = strconv.Atoi(m[1])
+		p.Msg = m[2]
+	}
+	return p
+}
+
+// decodeProblems splits a yaml decode error into one problem per field.
+func decodeProblems(path string, err error) []Problem {
+	var terr *yaml.TypeError
+	if !errors.As(err, &terr) {
+		return []Problem{*syntaxProblem(path, err)}
+	}
+	var out []Problem
+	for _, e := range terr.Errors {
+		out = append(out, *syntaxProblem(path, errors.New(e)))
+	}
+	return out
+}
+
+// walk records the position of every key in pos and reports keys that do
+// not exist in the schema.
+func walk(n *yaml.Node, t reflect.Type, path, file string, pos map[string]Position) []Problem {
+	pos[path] = Position{file, n.Line, n.Column}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var out []Problem
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return nil // reported by Decode
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			f, ok := fieldByTag(t, k.Value)
+			if !ok {
+				out = append(out, Problem{Position{file, k.Line, k.Column},
+					fmt.Sprintf("unknown key %q%s", k.Value, where(path))})
+				continue
+			}
+			out = append(out, walk(v, f.Type, join(path, k.Value), file, pos)...)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			out = append(out, walk(v, t.Elem(), join(path, k.Value), file, pos)...)
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, v := range n.Content {
+			out = append(out, walk(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i), file, pos)...)
+		}
+	}
+	return out
+}
+
+func fieldByTag(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name := strings.Split(f.Tag.Get("yaml"), ",")[0]; name != "" && name == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func where(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " in " + path
+}
+
+// tomlPositions fills in line numbers for a node tree encoded from TOML by
+// finding each key in the source at or after the line of its parent.
+func tomlPositions(n *yaml.Node, lines []string, table string, from int) {
+	if n.Kind == yaml.DocumentNode {
+		for _, c := range n.Content {
+			tomlPositions(c, lines, table, from)
+		}
+		return
+	}
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		name := join(table, k.Value)
+		line := tomlKeyLine(lines, name, k.Value, from)
+		k.Line, k.Column = line, 1
+		v.Line, v.Column = line, 1
+		switch v.Kind {
+		case yaml.MappingNode:
+			tomlPositions(v, lines, name, line)
+		case yaml.SequenceNode:
+			// Arrays of tables: one [[key]] header per element
+			next := line
+			for _, item := range v.Content {
+				item.Line, item.Column = next, 1
+				if item.Kind == yaml.MappingNode {
+					tomlPositions(item, lines, name, next)
+					next = tomlKeyLine(lines, name, k.Value, next+1)
+				}
+			}
+		}
+	}
+}
+
+// tomlKeyLine returns the first line at or after from that assigns key or
+// opens the table with the full dotted name, falling back to from.
+func tomlKeyLine(lines []string, name, key string, from int) int {
+	for i := from - 1; i >= 0 && i < len(lines); i++ {
+		s := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(s, "[") {
+			if strings.Trim(s, "[] ") == name {
+				return i + 1
+			}
+			continue
+		}
+		if eq := strings.Index(s, "="); eq > 0 && strings.Trim(strings.TrimSpace(s[:eq]), `"`) == key {
+			return i + 1
+		}
+	}
+	return from
+}
@@ -0,0 +1,519 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/scraper"
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/ycdir"
+	"github.com/robfig/cron/v3"
+)
+
+// Position is where a setting came from: a file location, or the name of
+// an environment variable.
+type Position struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Position) String() string {
+	switch {
+	case p.Line > 0 && p.Col > 0:
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+	case p.Line > 0:
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	return p.File
+}
+
+// Problem is one invalid or unknown setting.
+type Problem struct {
+	Position
+	Msg string
+}
+
+func (p Problem) String() string {
+	return p.Position.String() + ": " + p.Msg
+}
+
+// Problems is returned by Load when the config is invalid.
+type Problems []Problem
+
+func (ps Problems) Error() string {
+	lines := make([]string, len(ps))
+	for i, p := range ps {
+		lines[i] = p.String()
+	}
+	return "invalid config:\n  " + strings.Join(lines, "\n  ")
+}
+
+// merge folds an included config into cfg. Companies are appended; for
+// everything else the including file wins.
+func (cfg *Config) merge(inc *Config) {
+	offsets := map[string]int{"companies": len(cfg.Companies), "profiles": len(cfg.Profiles)}
+	cfg.Companies = append(cfg.Companies, inc.Companies...)
+	cfg.Profiles = append(cfg.Profiles, inc.Profiles...)
+	for p, slugs := range inc.TargetPlatforms {
+		if cfg.TargetPlatforms == nil {
+			cfg.TargetPlatforms = map[string][]string{}
+		}
+		cfg.TargetPlatforms[p] = append(cfg.TargetPlatforms[p], slugs...)
+	}
+
+	f, g := &cfg.Filters, inc.Filters
+	if f.EarlyCareer == nil {
+		f.EarlyCareer = g.EarlyCareer
+	}
+	if f.USOnly == nil {
+		f.USOnly = g.USOnly
+	}
+	if f.IncludeTitles == nil {
+		f.IncludeTitles = g.IncludeTitles
+	}
+	if f.ExcludeTitles == nil {
+		f.ExcludeTitles = g.ExcludeTitles
+	}
+	if f.Locations == nil {
+		f.Locations = g.Locations
+	}
+
+	s, t := &cfg.Schedule, inc.Schedule
+	if s.Default == "" {
+		s.Default = t.Default
+	}
+	if s.Jitter == "" {
+		s.Jitter = t.Jitter
+	}
+	s.Platforms = mergeMap(s.Platforms, t.Platforms)
+	s.Companies = mergeMap(s.Companies, t.Companies)
+
+	h, k := &cfg.Health, inc.Health
+	if h.Backoff == "" {
+		h.Backoff = k.Backoff
+	}
+	if h.MaxBackoff == "" {
+		h.MaxBackoff = k.MaxBackoff
+	}
+	if h.DisableAfter == nil {
+		h.DisableAfter = k.DisableAfter
+	}
+	if cfg.Archive.Dir == "" {
+		cfg.Archive.Dir = inc.Archive.Dir
+	}
+	if cfg.Export.User == "" {
+		cfg.Export.User = inc.Export.User
+	}
+	if cfg.Retention.Schedule == "" {
+		cfg.Retention.Schedule = inc.Retention.Schedule
+	}
+	if len(cfg.Retention.Rules) == 0 {
+		cfg.Retention.Rules = inc.Retention.Rules
+	}
+	if len(cfg.Pipeline.Stages) == 0 {
+		cfg.Pipeline = inc.Pipeline
+	}
+
+	for path, p := range inc.pos {
+		for list, offset := range offsets {
+			if strings.HasPrefix(path, list+"[") {
+				var i int
+				fmt.Sscanf(path[len(list):], "[%d]", &i)
+				path = fmt.Sprintf("%s[%d]%s", list, i+offset, path[strings.Index(path, "]")+1:])
+			}
+		}
+		if _, ok := cfg.pos[path]; !ok {
+			cfg.pos[path] = p
+		}
+	}
+}
+
+func mergeMap(dst, src map[string]string) map[string]string {
+	for k, v := range src {
+		if dst == nil {
+			dst = map[string]string{}
+		}
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+	return dst
+}
+
+// foldLegacy turns target_platforms entries into companies, supported
+// platforms first so scraping order is unchanged.
+func (cfg *Config) foldLegacy() {
+	names := append([]string(nil), scraper.Platforms...)
+	var other []string
+	for p := range cfg.TargetPlatforms {
+		if !supported(p) {
+			other = append(other, p)
+		}
+	}
+	sort.Strings(other)
+	for _, p := range append(names, other...) {
+		for i, slug := range cfg.TargetPlatforms[p] {
+			path := fmt.Sprintf("target_platforms.%s[%d]", p, i)
+			cfg.pos[fmt.Sprintf("companies[%d]", len(cfg.Companies))] = cfg.at(path)
+			cfg.Companies = append(cfg.Companies, Company{Slug: slug, Platform: p})
+		}
+	}
+}
+
+// EnvPrefix starts every environment override.
+const EnvPrefix = "SCRAPER_"
+
+// applyEnv overrides top-level settings from the environment, e.g.
+// SCRAPER_SCHEDULE_DEFAULT or SCRAPER_FILTERS_US_ONLY (lists are comma
+// separated). SCRAPER_DISABLE disables the listed companies and
+// SCRAPER_ONLY disables every company not listed.
+func (cfg *Config) applyEnv(lookup func(string) (string, bool)) []Problem {
+	var out []Problem
+	for _, field := range []struct {
+		path string
+		v    reflect.Value
+	}{
+		{"filters", reflect.ValueOf(&cfg.Filters).Elem()},
+		{"schedule", reflect.ValueOf(&cfg.Schedule).Elem()},
+		{"health", reflect.ValueOf(&cfg.Health).Elem()},
+		{"archive", reflect.ValueOf(&cfg.Archive).Elem()},
+		{"export", reflect.ValueOf(&cfg.Export).Elem()},
+	} {
+		t := field.v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			name := EnvPrefix + strings.ToUpper(field.path+"_"+key)
+			val, ok := lookup(name)
+			if !ok {
+				continue
+			}
+			pos := Position{File: "$" + name}
+			if err := setFromEnv(field.v.Field(i), val); err != nil {
+				out = append(out, Problem{pos, err.Error()})
+				continue
+			}
+			cfg.pos[field.path+"."+key] = pos
+		}
+	}
+
+	if val, ok := lookup(EnvPrefix + "DISABLE"); ok {
+		for _, name := range splitList(val) {
+			if !cfg.setEnabled(name, false) {
+				out = append(out, Problem{Position{File: "$" + EnvPrefix + "DISABLE"}, fmt.Sprintf("no company %q", name)})
+			}
+		}
+	}
+	if val, ok := lookup(EnvPrefix + "ONLY"); ok {
+		only := splitList(val)
+		keep := map[int]bool{}
+		for _, name := range only {
+			found := false
+			for i, c := range cfg.Companies {
+				if c.is(name) {
+					keep[i], found = true, true
+				}
+			}
+			if !found {
+				out = append(out, Problem{Position{File: "$" + EnvPrefix + "ONLY"}, fmt.Sprintf("no company %q", name)})
+			}
+		}
+		off := false
+		for i := range cfg.Companies {
+			if !keep[i] {
+				cfg.Companies[i].Enabled = &off
+			}
+		}
+	}
+	return out
+}
+
+// setFromEnv sets a string, *bool, *int or []string field from an env value.
+func setFromEnv(v reflect.Value, val string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Ptr:
+		switch v.Type().Elem().Kind() {
+		case reflect.Bool:
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("want true or false, got %q", val)
+			}
+			v.Set(reflect.ValueOf(&b))
+		case reflect.Int:
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("want a number, got %q", val)
+			}
+			v.Set(reflect.ValueOf(&n))
+		default:
+			return fmt.Errorf("cannot be set from the environment")
+		}
+	case reflect.Slice:
+		v.Set(reflect.ValueOf(splitList(val)))
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// is reports whether name refers to the company, as "slug" or "platform/slug".
+func (c Company) is(name string) bool {
+	return name == c.Slug || name == c.String()
+}
+
+func (cfg *Config) setEnabled(name string, on bool) bool {
+	found := false
+	for i := range cfg.Companies {
+		if cfg.Companies[i].is(name) {
+			cfg.Companies[i].Enabled = &on
+			found = true
+		}
+	}
+	return found
+}
+
+// at returns where a setting was made, falling back to its closest parent.
+func (cfg *Config) at(path string) Position {
+	for path != "" {
+		if p, ok := cfg.pos[path]; ok {
+			return p
+		}
+		if i := strings.LastIndexAny(path, ".["); i >= 0 {
+			path = path[:i]
+		} else {
+			path = ""
+		}
+	}
+	return cfg.pos[""]
+}
+
+var slugRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func supported(platform string) bool {
+	for _, p := range scraper.Platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks values the schema alone cannot.
+func (cfg *Config) validate() []Problem {
+	var out []Problem
+	bad := func(path, format string, args ...interface{}) {
+		out = append(out, Problem{cfg.at(path), fmt.Sprintf(format, args...)})
+	}
+
+	seen := map[string]string{}
+	for i, c := range cfg.Companies {
+		path := fmt.Sprintf("companies[%d]", i)
+		switch {
+		case c.Platform == "":
+			bad(path, "company %q has no platform", c.Slug)
+		case !supported(c.Platform):
+			bad(path+".platform", "unsupported platform %q (want one of %s)", c.Platform, strings.Join(scraper.Platforms, ", "))
+		}
+		switch {
+		case c.Slug == "":
+			bad(path, "company has no slug")
+		case !slugRe.MatchString(c.Slug):
+			bad(path+".slug", "invalid slug %q", c.Slug)
+		}
+		if prev, ok := seen[c.String()]; ok && c.Slug != "" {
+			bad(path, "duplicate company %s (first defined at %s)", c, prev)
+		} else {
+			seen[c.String()] = cfg.at(path).String()
+		}
+		if c.Host != "" {
+			if u, err := url.Parse("https://" + c.Host); err != nil || u.Host != c.Host {
+				bad(path+".host", "invalid host %q (want a bare hostname such as api.eu.lever.co)", c.Host)
+			}
+		}
+		for _, f := range []struct{ key, val string }{{"website", c.Website}, {"logo_url", c.LogoURL}} {
+			if u, err := url.Parse(f.val); f.val != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+				bad(path+"."+f.key, "invalid %s %q (want an http or https URL)", f.key, f.val)
+			}
+		}
+		if _, ok := ycdir.ParseBatch(c.YCBatch); c.YCBatch != "" && !ok {
+			bad(path+".yc_batch", "invalid yc_batch %q (want e.g. W24 or Winter 2024)", c.YCBatch)
+		}
+		if c.Schedule != "" {
+			if _, err := cron.ParseStandard(c.Schedule); err != nil {
+				bad(path+".schedule", "invalid schedule %q: %v", c.Schedule, err)
+			}
+		}
+		for j, tag := range c.Tags {
+			if strings.TrimSpace(tag) == "" {
+				bad(fmt.Sprintf("%s.tags[%d]", path, j), "empty tag")
+			}
+		}
+		if c.Filters != nil {
+			out = append(out, cfg.validateFilters(path+".filters", *c.Filters)...)
+		}
+	}
+
+	out = append(out, cfg.validateFilters("filters", cfg.Filters)...)
+
+	names := map[string]bool{}
+	for i, p := range cfg.Profiles {
+		path := fmt.Sprintf("profiles[%d]", i)
+		switch {
+		case p.Name == "":
+			bad(path, "profile has no name")
+		case !slugRe.MatchString(p.Name):
+			bad(path+".name", "invalid profile name %q", p.Name)
+		case names[p.Name]:
+			bad(path+".name", "duplicate profile %q", p.Name)
+		}
+		names[p.Name] = true
+		for j, name := range p.Companies {
+			found := false
+			for _, c := range cfg.Companies {
+				found = found || c.is(name)
+			}
+			if !found {
+				bad(fmt.Sprintf("%s.companies[%d]", path, j), "profile %s lists unknown company %q", p.Name, name)
+			}
+		}
+		out = append(out, cfg.validateFilters(path+".filters", p.Filters)...)
+	}
+
+	s := cfg.Schedule
+	if s.Default != "" {
+		if _, err := cron.ParseStandard(s.Default); err != nil {
+			bad("schedule.default", "invalid schedule %q: %v", s.Default, err)
+		}
+	}
+	if s.Jitter != "" {
+		if d, err := time.ParseDuration(s.Jitter); err != nil || d < 0 {
+			bad("schedule.jitter", "invalid jitter %q (want a duration such as 10m)", s.Jitter)
+		}
+	}
+	for p, spec := range s.Platforms {
+		if !supported(p) {
+			bad("schedule.platforms."+p, "unsupported platform %q", p)
+		}
+		if _, err := cron.ParseStandard(spec); err != nil {
+			bad("schedule.platforms."+p, "invalid schedule %q: %v", spec, err)
+		}
+	}
+	for name, spec := range s.Companies {
+		found := false
+		for _, c := range cfg.Companies {
+			found = found || c.is(name)
+		}
+		if !found {
+			bad("schedule.companies."+name, "schedule for unknown company %q", name)
+		}
+		if _, err := cron.ParseStandard(spec); err != nil {
+			bad("schedule.companies."+name, "invalid schedule %q: %v", spec, err)
+		}
+	}
+
+	for _, d := range []struct{ key, val string }{
+		{"backoff", cfg.Health.Backoff}, {"max_backoff", cfg.Health.MaxBackoff},
+	} {
+		if d.val == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(d.val); err != nil || v <= 0 {
+			bad("health."+d.key, "invalid %s %q (want a duration such as 12h)", d.key, d.val)
+		}
+	}
+	if n := cfg.Health.DisableAfter; n != nil && *n < 0 {
+		bad("health.disable_after", "disable_after must be 0 (never) or more, got %d", *n)
+	}
+
+	if spec := cfg.Retention.Schedule; spec != "" {
+		if _, err := cron.ParseStandard(spec); err != nil {
+			bad("retention.schedule", "invalid schedule %q: %v", spec, err)
+		}
+	}
+	rules := map[string]bool{}
+	for i, r := range cfg.Retention.Rules {
+		path := fmt.Sprintf("retention.rules[%d]", i)
+		switch {
+		case r.Name == "":
+			bad(path, "retention rule needs a name")
+		case rules[r.Name]:
+			bad(path+".name", "duplicate retention rule %q", r.Name)
+		}
+		rules[r.Name] = true
+		if r.ClosedDays < 0 || r.AgeDays < 0 {
+			bad(path, "closed_days and age_days must not be negative")
+		} else if r.ClosedDays == 0 && r.AgeDays == 0 {
+			bad(path, "retention rule %q needs closed_days or age_days", r.Name)
+		}
+	}
+
+	stages := map[string]bool{db.StatusNotApplied: true}
+	for i, st := range cfg.Pipeline.Stages {
+		path := fmt.Sprintf("pipeline.stages[%d]", i)
+		switch {
+		case st == "":
+			bad(path, "empty pipeline stage")
+		case st == db.StatusNotApplied:
+			bad(path, "%q is where every job starts and is not a stage", st)
+		case stages[st]:
+			bad(path, "duplicate pipeline stage %q", st)
+		}
+		stages[st] = true
+	}
+	if len(cfg.Pipeline.Transitions) > 0 && len(cfg.Pipeline.Stages) == 0 {
+		bad("pipeline.transitions", "pipeline transitions need pipeline.stages")
+	} else {
+		var froms []string
+		for from := range cfg.Pipeline.Transitions {
+			froms = append(froms, from)
+		}
+		sort.Strings(froms)
+		for _, from := range froms {
+			tos := cfg.Pipeline.Transitions[from]
+			path := "pipeline.transitions." + from
+			if !stages[from] {
+				bad(path, "transition from unknown stage %q", from)
+			}
+			for j, to := range tos {
+				if !stages[to] {
+					bad(fmt.Sprintf("%s[%d]", path, j), "transition to unknown stage %q", to)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func (cfg *Config) validateFilters(path string, f Filters) []Problem {
+	var out []Problem
+	for _, list := range []struct {
+		key      string
+		patterns []string
+	}{{"include_titles", f.IncludeTitles}, {"exclude_titles", f.ExcludeTitles}} {
+		for i, p := range list.patterns {
+			if _, err := regexp.Compile(p); err != nil {
+				out = append(out, Problem{cfg.at(fmt.Sprintf("%s.%s[%d]", path, list.key, i)),
+					fmt.Sprintf("invalid pattern %q: %v", p, err)})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// Backups are standalone copies of the database named
+// <name>-YYYYMMDD-HHMMSS.mmm.db after the database file and the UTC time
+// they were taken, so a directory of them sorts by age.
+const backupTimeFormat = "20060102-150405.000"
+
+// BackupFile is a backup in a backup directory.
+type BackupFile struct {
+	Path    string
+	TakenAt time.Time
+	Size    int64
+}
+
+// Retention says which backups PruneBackups keeps: the Last newest, plus
+// the newest backup of each of the Daily most recent days and of each of
+// the Weekly most recent weeks that have one. A rule of 0 keeps nothing by
+// itself; the zero Retention keeps every backup.
+type Retention struct {
+	Last   int
+	Daily  int
+	Weekly int
+}
+
+// DefaultRetention keeps a week of daily backups and a month of weekly ones.
+var DefaultRetention = Retention{Last: 3, Daily: 7, Weekly: 4}
+
+// Backup writes a consistent copy of the live database to dir, checks its
+// integrity, and returns its path. It uses VACUUM INTO, so the copy is
+// compacted and readers and writers carry on while it is taken.
+func (d *DB) Backup(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(filepath.Base(d.Path), filepath.Ext(d.Path))
+	path := filepath.Join(dir, name+"-"+time.Now().UTC().Format(backupTimeFormat)+".db")
+	if _, err := d.Conn.Exec(`VACUUM INTO $1`, path); err != nil {
+		return "", fmt.Errorf("backup to %s: %w", path, err)
+	}
+	if _, err := CheckBackup(path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// CheckBackup verifies that path is an intact jobs database this binary can
+// use and returns its schema version. It fails with ErrSchemaTooNew for a
+// backup taken by a newer binary.
+func CheckBackup(path string) (int, error) {
+	// sql.Open would create a missing file
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	conn, err := sql.Open("sqlite", path+"?_pragma=query_only(1)")
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(problems) > 0 {
+		return 0, fmt.Errorf("%s failed the integrity check: %s", path, strings.Join(problems, "; "))
+	}
+
+	var version int
+	if err := conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("%s is not a jobs database: %w", path, err)
+	}
+	all, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if version > len(all) {
+		return version, fmt.Errorf("%w: %s is at version %d, this binary knows up to %d", ErrSchemaTooNew, path, version, len(all))
+	}
+	return version, nil
+}
+
+// Restore replaces the contents of the live database with a backup, after
+// checking it with CheckBackup, then applies any migrations the backup
+// predates. The copy goes through SQLite's online backup API on the writer
+// connection, so other connections see either the old database or the
+// restored one, never a mix.
+func (d *DB) Restore(path string) error {
+	if _, err := CheckBackup(path); err != nil {
+		return err
+	}
+	conn, err := d.Conn.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	err = conn.Raw(func(driverConn interface{}) error {
+		rc, ok := driverConn.(interface {
+			NewRestore(string) (*sqlite.Backup, error)
+		})
+		if !ok {
+			return fmt.Errorf("sqlite driver does not support restore")
+		}
+		b, err := rc.NewRestore(path)
+		if err != nil {
+			return err
+		}
+		if _, err := b.Step(-1); err != nil {
+			b.Finish()
+			return err
+		}
+		return b.Finish()
+	})
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("restore from %s: %w", path, err)
+	}
+	_, err = d.MigrateUp()
+	return err
+}
+
+// ListBackups returns the backups of the database in dir, newest first.
+// Other files are ignored.
+func (d *DB) ListBackups(dir string) ([]BackupFile, error) {
+	name := strings.TrimSuffix(filepath.Base(d.Path), filepath.Ext(d.Path))
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []BackupFile
+	for _, e := range entries {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(e.Name(), name+"-"), ".db")
+		if e.IsDir() || stamp == e.Name() || !strings.HasSuffix(e.Name(), ".db") {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, BackupFile{Path: filepath.Join(dir, e.Name()), TakenAt: t, Size: info.Size()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TakenAt.After(out[j].TakenAt) })
+	return out, nil
+}
+
+// PruneBackups deletes the backups in dir that r does not keep and returns
+// them.
+func (d *DB) PruneBackups(dir string, r Retention) ([]BackupFile, error) {
+	backups, err := d.ListBackups(dir)
+	if err != nil || r == (Retention{}) {
+		return nil, err
+	}
+	keep := make([]bool, len(backups))
+	for i := 0; i < r.Last && i < len(backups); i++ {
+		keep[i] = true
+	}
+	// Backups are newest first, so the first of each period is its newest
+	for _, rule := range []struct {
+		n      int
+		period func(time.Time) string
+	}{
+		{r.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{r.Weekly, func(t time.Time) string { y, w := t.ISOWeek(); return fmt.Sprintf("%d-W%02d", y, w) }},
+	} {
+		seen := map[string]bool{}
+		for i, b := range backups {
+			p := rule.period(b.TakenAt)
+			if len(seen) >= rule.n {
+				break
+			}
+			if !seen[p] {
+				seen[p] = true
+				keep[i] = true
+			}
+		}
+	}
+
+	var removed []BackupFile
+	for i, b := range backups {
+		if keep[i] {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, b)
+	}
+	return removed, nil
+}
@@ -0,0 +1,285 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/ycdir"
+)
+
+// Company is one entry in the companies catalog. Jobs reference it through
+// Job.CompanyID; the job's Company string is kept in sync with Name.
+type Company struct {
+	ID       int
+	Platform string
+	Slug     string
+	Name     string
+	Website  string
+	LogoURL  string
+	Tags     []string
+	YCBatch  string
+	Notes    string // edited in the dashboard, never overwritten by the scraper
+
+	// From the YC directory import
+	Industry string
+	TeamSize int
+	YCStatus string
+
+	OpenJobs  int // filled by ListCompanies
+	UpdatedAt time.Time
+}
+
+const companyColumns = `id, platform, slug, name, COALESCE(website, ''), COALESCE(logo_url, ''),
+	COALESCE(tags, ''), COALESCE(yc_batch, ''), COALESCE(notes, ''),
+	COALESCE(industry, ''), COALESCE(team_size, 0), COALESCE(yc_status, ''), updated_at`
+
+func scanCompany(row interface{ Scan(...interface{}) error }, extra ...interface{}) (Company, error) {
+	var (
+		c    Company
+		tags string
+	)
+	dest := append([]interface{}{&c.ID, &c.Platform, &c.Slug, &c.Name, &c.Website, &c.LogoURL,
+		&tags, &c.YCBatch, &c.Notes, &c.Industry, &c.TeamSize, &c.YCStatus, &c.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return c, err
+	}
+	if tags != "" {
+		c.Tags = strings.Split(tags, ",")
+	}
+	return c, nil
+}
+
+// UpsertCompany adds a company to the catalog or refreshes its metadata,
+// and returns the stored record. Empty fields keep their stored value; a
+// new company without a name is named defaultName.
+//
+// Jobs of the company that predate the catalog are linked to it by name:
+// any unlinked job whose company string is the catalog name or one of
+// aliases (such as the old title-cased slug) is adopted. Linked jobs take
+// the catalog name.
+func (d *DB) UpsertCompany(c Company, defaultName string, aliases ...string) (Company, error) {
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return c, err
+	}
+	defer tx.Rollback()
+
+	name := c.Name
+	if name == "" {
+		name = defaultName
+	}
+	var tags interface{}
+	if len(c.Tags) > 0 {
+		tags = strings.Join(c.Tags, ",")
+	}
+	batch, batchKey := normalizeBatch(c.YCBatch)
+	_, err = tx.Exec(`INSERT INTO companies(platform, slug, name, website, logo_url, tags, yc_batch, yc_batch_key)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$9)
+		ON CONFLICT (platform, slug) DO UPDATE SET
+			name = COALESCE(NULLIF($8, ''), companies.name),
+			website = COALESCE(excluded.website, companies.website),
+			logo_url = COALESCE(excluded.logo_url, companies.logo_url),
+			tags = COALESCE(excluded.tags, companies.tags),
+			yc_batch = COALESCE(excluded.yc_batch, companies.yc_batch),
+			yc_batch_key = COALESCE(excluded.yc_batch_key, companies.yc_batch_key),
+			updated_at = CURRENT_TIMESTAMP`,
+		c.Platform, c.Slug, name, nullIfEmpty(c.Website), nullIfEmpty(c.LogoURL), tags, nullIfEmpty(batch), c.Name, nullIfZero(batchKey))
+	if err != nil {
+		return c, err
+	}
+	stored, err := scanCompany(tx.QueryRow(`SELECT `+companyColumns+` FROM companies WHERE platform = $1 AND slug = $2`,
+		c.Platform, c.Slug))
+	if err != nil {
+		return c, err
+	}
+
+	for _, alias := range append([]string{stored.Name}, aliases...) {
+		_, err = tx.Exec(`UPDATE job_applications SET company_id = $1
+			WHERE company_id IS NULL AND company = $2 COLLATE NOCASE AND (source IS NULL OR source = $3)`,
+			stored.ID, alias, stored.Platform)
+		if err != nil {
+			return c, err
+		}
+	}
+	_, err = tx.Exec(`UPDATE job_applications SET company = $1 WHERE company_id = $2 AND company <> $1`,
+		stored.Name, stored.ID)
+	if err != nil {
+		return c, err
+	}
+	return stored, tx.Commit()
+}
+
+// GetCompany returns a catalog entry by ID.
+func (d *DB) GetCompany(id int) (Company, error) {
+	return scanCompany(d.Read.QueryRow(`SELECT `+companyColumns+` FROM companies WHERE id = $1`, id))
+}
+
+// FindCompany returns the catalog entry for a board, and false if there is
+// none.
+func (d *DB) FindCompany(platform, slug string) (Company, bool, error) {
+	c, err := scanCompany(d.Read.QueryRow(`SELECT `+companyColumns+` FROM companies
+		WHERE platform = $1 AND slug = $2`, platform, slug))
+	if err == sql.ErrNoRows {
+		return c, false, nil
+	}
+	return c, err == nil, err
+}
+
+// ListCompanies returns the catalog ordered by name, with the number of
+// open jobs of each.
+func (d *DB) ListCompanies() ([]Company, error) {
+	rows, err := d.Read.Query(`SELECT ` + companyColumns + `,
+		(SELECT COUNT(*) FROM job_applications j
+		 WHERE j.company_id = companies.id AND j.closed_at IS NULL AND j.merged_into IS NULL)
+		FROM companies ORDER BY name COLLATE NOCASE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Company
+	for rows.Next() {
+		var open int
+		c, err := scanCompany(rows, &open)
+		if err != nil {
+			return nil, err
+		}
+		c.OpenJobs = open
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SetCompanyNotes replaces a company's notes.
+func (d *DB) SetCompanyNotes(id int, notes string) error {
+	res, err := d.Conn.Exec(`UPDATE companies SET notes = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`,
+		nullIfEmpty(notes), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// normalizeBatch returns the short form of a YC batch ("Winter 2024" is
+// "W24") and its chronological key. Unrecognized batches are kept as given,
+// with key 0.
+func normalizeBatch(s string) (string, int) {
+	b, ok := ycdir.ParseBatch(s)
+	if !ok {
+		return strings.TrimSpace(s), 0
+	}
+	return b.String(), b.Key()
+}
+
+// SetCompanyDirectory stores YC directory metadata on a catalog entry. The
+// batch, industry, team size and status replace the stored values when
+// given; the website and logo are only filled in if missing, since the
+// config may set them deliberately.
+func (d *DB) SetCompanyDirectory(id int, c Company) error {
+	batch, batchKey := normalizeBatch(c.YCBatch)
+	res, err := d.Conn.Exec(`UPDATE companies SET
+			yc_batch = COALESCE($1, yc_batch),
+			yc_batch_key = COALESCE($2, yc_batch_key),
+			industry = COALESCE($3, industry),
+			team_size = COALESCE($4, team_size),
+			yc_status = COALESCE($5, yc_status),
+			website = COALESCE(website, $6),
+			logo_url = COALESCE(logo_url, $7),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8`,
+		nullIfEmpty(batch), nullIfZero(batchKey), nullIfEmpty(c.Industry), nullIfZero(c.TeamSize),
+		nullIfEmpty(c.YCStatus), nullIfEmpty(c.Website), nullIfEmpty(c.LogoURL), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CompanyFacets lists the distinct batches (newest first) and industries in
+// the catalog, for filter menus.
+func (d *DB) CompanyFacets() (batches, industries []string, err error) {
+	rows, err := d.Read.Query(`SELECT yc_batch FROM companies WHERE yc_batch_key IS NOT NULL
+		GROUP BY yc_batch ORDER BY MAX(yc_batch_key) DESC`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b string
+		if err := rows.Scan(&b); err != nil {
+			return nil, nil, err
+		}
+		batches = append(batches, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = d.Read.Query(`SELECT DISTINCT industry FROM companies WHERE industry IS NOT NULL ORDER BY industry`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i string
+		if err := rows.Scan(&i); err != nil {
+			return nil, nil, err
+		}
+		industries = append(industries, i)
+	}
+	return batches, industries, rows.Err()
+}
+
+// CompanyFilter selects jobs by their company's YC directory metadata.
+type CompanyFilter struct {
+	Batch       string // "W24" for that batch, "W24+" for it and later ones
+	Industry    string
+	MaxTeamSize int // team size below this; 0 for any size
+}
+
+// IsZero reports whether the filter matches every job.
+func (f CompanyFilter) IsZero() bool {
+	return f.Batch == "" && f.Industry == "" && f.MaxTeamSize == 0
+}
+
+// Clause returns a condition on job_applications.company_id for the filter,
+// numbering its placeholders from $first, and their values. It returns ""
+// for the zero filter.
+func (f CompanyFilter) Clause(first int) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, v interface{}) {
+		conds = append(conds, fmt.Sprintf(cond, first+len(args)))
+		args = append(args, v)
+	}
+	if f.Batch != "" {
+		batch := strings.TrimSuffix(f.Batch, "+")
+		name, key := normalizeBatch(batch)
+		switch {
+		case key == 0:
+			add("yc_batch = $%d", name)
+		case strings.HasSuffix(f.Batch, "+"):
+			add("yc_batch_key >= $%d", key)
+		default:
+			add("yc_batch_key = $%d", key)
+		}
+	}
+	if f.Industry != "" {
+		add("industry = $%d COLLATE NOCASE", f.Industry)
+	}
+	if f.MaxTeamSize > 0 {
+		add("team_size < $%d", f.MaxTeamSize)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "company_id IN (SELECT id FROM companies WHERE " + strings.Join(conds, " AND ") + ")", args
+}
@@ -2,10 +2,14 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/dedupe"
 	"golang.org/x/crypto/bcrypt"
 	_ "modernc.org/sqlite"
 )
@@ -19,27 +23,246 @@ type Job struct {
 	Type      string
 	URL       string
 	DateAdded time.Time
-	Status    string
+
+	// Status, Starred and Hidden are the listing user's state for the
+	// job; see UserJob.
+	Status  string
+	Starred bool
+	Hidden  bool
+	Tags    []string // the listing user's tags, sorted
+
+	// Description is the plain-text posting body, when the source provides one.
+	Description string
+
+	// Source and SourceJobID identify the posting on the board it was
+	// scraped from (e.g. "greenhouse", "4012345"), independent of its URL.
+	Source      string
+	SourceJobID string
+
+	// MergedInto is set when this row was merged into another job as a
+	// duplicate listing of the same role.
+	MergedInto *int
+
+	// CompanyID references the companies catalog; 0 when unknown.
+	CompanyID int
+
+	// Snippet is set by ListJobs when the filter has a Search: text around
+	// the best match, with matched terms between SnippetOpen and
+	// SnippetClose.
+	Snippet string
+
+	// Profiles are the search profiles the job matched on its last scrape.
+	// RecordJobSeen replaces the stored tags when it is non-nil.
+	Profiles []string
+
+	// SeenAt is when the board listed the job; RecordJobSeen uses the
+	// current time when it is zero. Reprocessing an archived run sets it to
+	// the run's fetch time, so an old run never reopens a job closed since
+	// or undoes later edits.
+	SeenAt time.Time
+
+	// Lifecycle timestamps maintained by the scraper. ClosedAt is nil while
+	// the posting is still listed on the company's board.
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+	ClosedAt    *time.Time
+}
+
+// FieldChange records one field of a posting that was edited upstream.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// JobVersion is one entry in a job's change history.
+type JobVersion struct {
+	ID        int
+	JobID     int
+	ChangedAt time.Time
+	Changes   []FieldChange
+}
+
+// UpsertResult describes what RecordJobSeen did with a scraped job.
+type UpsertResult int
+
+const (
+	JobInserted UpsertResult = iota
+	JobUpdated
+	JobUnchanged
+)
+
+// IsClosed reports whether the posting has been taken down.
+func (j Job) IsClosed() bool {
+	return j.ClosedAt != nil
+}
+
+// OpenFor returns how long the posting has been (or was) listed.
+func (j Job) OpenFor() time.Duration {
+	start := j.FirstSeenAt
+	if start.IsZero() {
+		start = j.DateAdded
+	}
+	end := time.Now()
+	if j.ClosedAt != nil {
+		end = *j.ClosedAt
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// OpenDays returns OpenFor rounded down to whole days, for templates.
+func (j Job) OpenDays() int {
+	return int(j.OpenFor().Hours() / 24)
 }
 
-// JobFilter is used to define search and pagination parameters for job listing.
-// (Currently a placeholder, but essential for main.go compilation).
+// JobFilter selects jobs. It is the one query path for every job list:
+// the dashboard, its CSV download, the JSON export and the static site.
+// The zero filter matches every open job that is not a merged duplicate.
 type JobFilter struct {
-	Status string
+	// UserID is the user whose statuses and flags the filter and the
+	// results use; 0 lists every job as Not Applied.
+	UserID int
+
+	Status  string // exact status of UserID's, e.g. "Not Applied"; empty for any
+	Starred bool   // only jobs UserID starred
+	// IncludeHidden returns jobs UserID hid, which are otherwise left out.
+	IncludeHidden bool
+	// Tags keeps jobs UserID tagged with any of these tags.
+	Tags []string
+
+	// Search is full-text search input over the title, company, location
+	// and description: words, "quoted phrases" and prefix* terms.
 	Search string
+
+	Company   string // exact company name
+	CompanyID int    // companies catalog entry
+	Location  string // exact location
+
+	// Levels keeps jobs whose title matches any of these levels, e.g.
+	// "Intern" or "New Grad"; see LevelKeywords.
+	Levels []string
+
+	// AddedAfter and AddedBefore bound date_added; zero means unbounded.
+	AddedAfter  time.Time
+	AddedBefore time.Time
+
+	Sort JobSort
+
+	// IncludeClosed returns postings that have been taken down as well.
+	IncludeClosed bool
+
+	// IncludeMerged returns duplicate listings merged into another job,
+	// which are otherwise hidden.
+	IncludeMerged bool
+
+	// Profile limits results to jobs tagged with that search profile.
+	Profile string
+
+	// Companies limits results by YC batch, industry and team size.
+	Companies CompanyFilter
 }
 
+// JobSort is the order of a job list.
+type JobSort string
+
+const (
+	SortNewest  JobSort = "newest" // most recently added first, also the default
+	SortOldest  JobSort = "oldest"
+	SortTitle   JobSort = "title"
+	SortCompany JobSort = "company"
+
+	// SortRelevance ranks search matches best first; without a Search it
+	// is SortNewest.
+	SortRelevance JobSort = "relevance"
+)
+
+// LevelKeywords maps each job level to the title keywords that identify it.
+var LevelKeywords = map[string][]string{
+	"Intern":      {"intern"},
+	"New Grad":    {"new grad", "new graduate"},
+	"Entry Level": {"entry level", "entry-level"},
+	"Junior":      {"junior"},
+	"Associate":   {"associate"},
+	"Apprentice":  {"apprentice"},
+	"Fellow":      {"fellow"},
+	"Co-op":       {"co-op", "co op", "coop"},
+}
+
+// DB is a handle on the jobs database, meant to be opened once per process
+// and shared. SQLite allows one writer at a time, so writes and
+// transactions go through Conn, a single connection; in WAL mode readers
+// do not wait for it, so plain queries use the Read pool.
 type DB struct {
-	Conn *sql.DB
+	Conn *sql.DB // the writer connection
+	Read *sql.DB // query-only connections
+	Path string  // the database file
 }
 
-// Connect opens a SQLite connection using DB_PATH env var or default.
-// Also ensures that required directories exist.
+// readPoolSize is the number of connections in the Read pool.
+const readPoolSize = 4
+
+// busyTimeout is how long a connection waits for another process's write
+// lock before failing with "database is locked".
+const busyTimeout = 5 * time.Second
+
+// Connect opens the database at DB_PATH (default data/jobs.db), creating
+// its directory if needed, and applies any pending schema migrations. It
+// fails with ErrSchemaTooNew if a newer binary has migrated the database.
 func Connect() (*DB, error) {
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "data/jobs.db"
+	db, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.MigrateUp(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// ConnectCurrent opens an existing database without changing it, for runs
+// that must not write, such as a dry run. It fails with ErrSchemaPending,
+// naming the migrations, if the schema is behind this binary, and with
+// ErrSchemaTooNew if it is ahead.
+func ConnectCurrent() (*DB, error) {
+	// Open would create a missing file
+	if _, err := os.Stat(pathFromEnv()); err != nil {
+		return nil, err
+	}
+	db, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := db.PendingMigrations()
+	if err == nil && len(pending) > 0 {
+		names := make([]string, len(pending))
+		for i, m := range pending {
+			names[i] = fmt.Sprintf("%04d_%s", m.Version, m.Name)
+		}
+		err = fmt.Errorf("%w: %s", ErrSchemaPending, strings.Join(names, ", "))
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
 	}
+	return db, nil
+}
+
+// pathFromEnv returns DB_PATH, or the default data/jobs.db.
+func pathFromEnv() string {
+	if p := os.Getenv("DB_PATH"); p != "" {
+		return p
+	}
+	return "data/jobs.db"
+}
+
+// Open opens the database like Connect but leaves its schema alone, for
+// tools that inspect or change the schema version themselves.
+func Open() (*DB, error) {
+	dbPath := pathFromEnv()
 
 	// Create data directory if it doesn't exist
 	dir := filepath.Dir(dbPath)
@@ -47,66 +270,57 @@ func Connect() (*DB, error) {
 		return nil, err
 	}
 
-	conn, err := sql.Open("sqlite", dbPath)
+	// WAL lets readers and the writer (possibly another process, such as
+	// the scraper next to the dashboard) work at once. journal_mode is
+	// stored in the file; the other pragmas apply per connection, so they
+	// go in the DSN, which the driver runs on every new connection.
+	pragmas := fmt.Sprintf("?_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)", busyTimeout.Milliseconds())
+	conn, err := sql.Open("sqlite", dbPath+pragmas+"&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)")
 	if err != nil {
 		return nil, err
 	}
-
-	// SQLite tuning for concurrent read-safe performance
 	conn.SetMaxOpenConns(1)
 	conn.SetMaxIdleConns(1)
 	conn.SetConnMaxLifetime(time.Minute * 5)
-
 	if err := conn.Ping(); err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	db := &DB{Conn: conn}
-
-	// Initialize schemas if they don't exist
-	if err := db.CreateSchema(); err != nil {
+	read, err := sql.Open("sqlite", dbPath+pragmas+"&_pragma=query_only(1)")
+	if err != nil {
+		conn.Close()
 		return nil, err
 	}
-	if err := db.CreateUserSchema(); err != nil {
+	read.SetMaxOpenConns(readPoolSize)
+	read.SetMaxIdleConns(readPoolSize)
+	if err := read.Ping(); err != nil {
+		read.Close()
+		conn.Close()
 		return nil, err
 	}
-
-	return db, nil
+	return &DB{Conn: conn, Read: read, Path: dbPath}, nil
 }
 
-// Close closes the database connection.
+// Close closes the writer and the read pool.
 func (d *DB) Close() error {
-	return d.Conn.Close()
+	rerr := d.Read.Close()
+	if err := d.Conn.Close(); err != nil {
+		return err
+	}
+	return rerr
 }
 
 // -------------------- JOBS TABLE --------------------
 
-// CreateSchema ensures the job_applications table exists.
-func (d *DB) CreateSchema() error {
-	q := `
-	CREATE TABLE IF NOT EXISTS job_applications (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT,
-		company TEXT,
-		location TEXT,
-		salary TEXT,
-		type TEXT,
-		url TEXT UNIQUE,
-		date_added DATETIME DEFAULT CURRENT_TIMESTAMP,
-		status TEXT DEFAULT 'Not Applied'
-	);
-	`
-	_, err := d.Conn.Exec(q)
-	return err
-}
-
 // InsertJob inserts a job record using a map, ignores duplicate URLs.
 func (d *DB) InsertJob(job map[string]interface{}) error {
 	q := `INSERT INTO job_applications(title, company, location, type, url)
 			 VALUES($1,$2,$3,$4,$5)
 			 ON CONFLICT (url) DO NOTHING;`
+	url, _ := job["URL"].(string)
 	_, err := d.Conn.Exec(q,
-		job["Title"], job["Company"], job["Location"], job["Type"], job["URL"])
+		job["Title"], job["Company"], job["Location"], job["Type"], dedupe.CanonicalURL(url))
 	return err
 }
 
@@ -115,20 +329,203 @@ func (d *DB) InsertJobTyped(title, company, location, typ, url string) error {
 	q := `INSERT INTO job_applications(title, company, location, type, url)
 			 VALUES($1,$2,$3,$4,$5)
 			 ON CONFLICT (url) DO NOTHING;`
-	_, err := d.Conn.Exec(q, title, company, location, typ, url)
+	_, err := d.Conn.Exec(q, title, company, location, typ, dedupe.CanonicalURL(url))
 	return err
 }
 
-// ListJobs retrieves job records based on filters, for the dashboard display.
-func (d *DB) ListJobs(filter JobFilter, page, pageSize int) ([]Job, error) {
-	q := `
-	SELECT id, title, company, location, type, url, date_added, status
+// JobPlan describes what RecordJobSeen would do with a scraped job.
+type JobPlan struct {
+	Result  UpsertResult
+	ID      int    // matched job, 0 when the job would be inserted
+	URL     string // canonical URL to store
+	Changes []FieldChange
+	Reopen  bool // the matched job is currently marked closed
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// planJob matches a scraped job against stored jobs, on (Source,
+// SourceJobID) when set and then on canonical URL, and works out which
+// fields changed. It does not write anything.
+func planJob(q queryer, job Job) (JobPlan, error) {
+	plan := JobPlan{URL: dedupe.CanonicalURL(job.URL)}
+
+	var (
+		oldTitle, oldCompany, oldLoc sql.NullString
+		oldType, oldDesc, oldURL     sql.NullString
+		closedAt, lastSeen           *time.Time
+	)
+	const sel = `SELECT id, title, company, location, type, description, url, closed_at, last_seen_at FROM job_applications `
+	err := sql.ErrNoRows
+	if job.Source != "" && job.SourceJobID != "" {
+		err = q.QueryRow(sel+`WHERE source = $1 AND source_job_id = $2`, job.Source, job.SourceJobID).
+			Scan(&plan.ID, &oldTitle, &oldCompany, &oldLoc, &oldType, &oldDesc, &oldURL, &closedAt, &lastSeen)
+	}
+	if err == sql.ErrNoRows {
+		err = q.QueryRow(sel+`WHERE url = $1`, plan.URL).
+			Scan(&plan.ID, &oldTitle, &oldCompany, &oldLoc, &oldType, &oldDesc, &oldURL, &closedAt, &lastSeen)
+	}
+	if err == sql.ErrNoRows {
+		plan.Result = JobInserted
+		return plan, nil
+	}
+	if err != nil {
+		return plan, err
+	}
+	plan.Reopen = closedAt != nil && (job.SeenAt.IsZero() || closedAt.Before(job.SeenAt))
+
+	diff := func(field, old, new string) {
+		// Sources that omit a field should not erase what we already have.
+		if new != "" && old != new {
+			plan.Changes = append(plan.Changes, FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+	diff("title", oldTitle.String, job.Title)
+	diff("company", oldCompany.String, job.Company)
+	diff("location", oldLoc.String, job.Location)
+	diff("type", oldType.String, job.Type)
+	diff("description", oldDesc.String, job.Description)
+	if plan.URL != oldURL.String {
+		// Only move the URL if no other row already holds it.
+		var taken int
+		err := q.QueryRow(`SELECT COUNT(*) FROM job_applications WHERE url = $1 AND id <> $2`, plan.URL, plan.ID).Scan(&taken)
+		if err != nil {
+			return plan, err
+		}
+		if taken == 0 {
+			diff("url", oldURL.String, plan.URL)
+		} else {
+			plan.URL = oldURL.String
+		}
+	}
+
+	// A replayed scrape older than the last sighting must not undo edits
+	if !job.SeenAt.IsZero() && lastSeen != nil && lastSeen.After(job.SeenAt) {
+		plan.Changes, plan.URL = nil, oldURL.String
+	}
+
+	plan.Result = JobUnchanged
+	if len(plan.Changes) > 0 {
+		plan.Result = JobUpdated
+	}
+	return plan, nil
+}
+
+// PreviewJobSeen reports what RecordJobSeen would do with job without
+// writing anything.
+func (d *DB) PreviewJobSeen(job Job) (JobPlan, error) {
+	return planJob(d.Conn, job)
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullIfZero(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// GetJob retrieves a single job by ID, including its description. The
+// per-user fields are left empty; see GetUserJob.
+func (d *DB) GetJob(id int) (Job, error) {
+	var (
+		job  Job
+		desc sql.NullString
+	)
+	err := d.Read.QueryRow(`
+	SELECT id, title, company, location, type, url, date_added,
+		first_seen_at, last_seen_at, closed_at, description,
+		COALESCE(source, ''), COALESCE(source_job_id, ''), merged_into, COALESCE(company_id, 0)
+	FROM job_applications WHERE id = $1`, id).Scan(
+		&job.ID, &job.Title, &job.Company, &job.Location, &job.Type, &job.URL,
+		&job.DateAdded, &job.FirstSeenAt, &job.LastSeenAt, &job.ClosedAt, &desc,
+		&job.Source, &job.SourceJobID, &job.MergedInto, &job.CompanyID,
+	)
+	job.Description = desc.String
+	return job, err
+}
+
+// ListJobVersions returns the change history of a job, oldest first.
+func (d *DB) ListJobVersions(jobID int) ([]JobVersion, error) {
+	rows, err := d.Read.Query(`
+	SELECT id, job_id, changed_at, changes
+	FROM job_versions WHERE job_id = $1
+	ORDER BY changed_at, id`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []JobVersion
+	for rows.Next() {
+		var (
+			v   JobVersion
+			raw string
+		)
+		if err := rows.Scan(&v.ID, &v.JobID, &v.ChangedAt, &raw); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(raw), &v.Changes); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// CloseUnseenJobs marks every open job scraped from platform for company
+// that was not seen since the given time as closed. It should only be
+// called after a successful scrape of that company, otherwise a network
+// error would close everything. Another platform's board for a company of
+// the same name is left alone.
+func (d *DB) CloseUnseenJobs(platform, company string, since time.Time) (int64, error) {
+	return d.closeUnseen("company = $1 AND source = $2", company, platform, since)
+}
+
+// CloseUnseenCompanyJobs is CloseUnseenJobs for a company in the catalog.
+// Jobs stored before source tracking belong to the entry's own board.
+func (d *DB) CloseUnseenCompanyJobs(platform string, companyID int, since time.Time) (int64, error) {
+	return d.closeUnseen("company_id = $1 AND (source = $2 OR source IS NULL)", companyID, platform, since)
+}
+
+func (d *DB) closeUnseen(scope string, value interface{}, platform string, since time.Time) (int64, error) {
+	q := `UPDATE job_applications
+			 SET closed_at = CURRENT_TIMESTAMP
+			 WHERE ` + scope + ` AND closed_at IS NULL AND last_seen_at < $3`
+	res, err := d.Conn.Exec(q, value, platform, sqliteTime(since))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListOpenJobs returns the open jobs scraped from platform for a company,
+// which is what a scrape of that board is compared against. It covers the
+// same jobs as CloseUnseenJobs.
+func (d *DB) ListOpenJobs(platform, company string) ([]Job, error) {
+	return d.listOpen("company = $1 AND source = $2", company, platform)
+}
+
+// ListOpenCompanyJobs is ListOpenJobs for a company in the catalog.
+func (d *DB) ListOpenCompanyJobs(platform string, companyID int) ([]Job, error) {
+	return d.listOpen("company_id = $1 AND (source = $2 OR source IS NULL)", companyID, platform)
+}
+
+func (d *DB) listOpen(scope string, value interface{}, platform string) ([]Job, error) {
+	rows, err := d.Conn.Query(`
+	SELECT id, COALESCE(title, ''), company, COALESCE(location, ''), COALESCE(type, ''), url
 	FROM job_applications
-	ORDER BY date_added DESC
-	LIMIT $1 OFFSET $2
-	`
-	offset := (page - 1) * pageSize
-	rows, err := d.Conn.Query(q, pageSize, offset)
+	WHERE `+scope+` AND closed_at IS NULL
+	ORDER BY id`, value, platform)
 	if err != nil {
 		return nil, err
 	}
@@ -137,47 +534,121 @@ func (d *DB) ListJobs(filter JobFilter, page, pageSize int) ([]Job, error) {
 	var jobs []Job
 	for rows.Next() {
 		var job Job
-		// Note: The Scan order MUST match the SELECT column order
-		err := rows.Scan(
-			&job.ID,
-			&job.Title,
-			&job.Company,
-			&job.Location,
-			&job.Type,
-			&job.URL,
-			&job.DateAdded,
-			&job.Status,
-		)
-		if err != nil {
-			// Log error and continue if a single row is problematic
-			continue
+		if err := rows.Scan(&job.ID, &job.Title, &job.Company, &job.Location, &job.Type, &job.URL); err != nil {
+			return nil, err
 		}
 		jobs = append(jobs, job)
 	}
+	return jobs, rows.Err()
+}
+
+// sqliteTime formats t the way CURRENT_TIMESTAMP does, so the two compare
+// correctly as strings.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
 
-	if rows.Err() != nil {
-		return nil, rows.Err()
+// Where returns the WHERE clause for the filter, numbering its placeholders
+// from $first, and their values. It returns "" when the filter matches
+// every job.
+func (f JobFilter) Where(first int) (string, []interface{}) {
+	return f.where(first, false)
+}
+
+// where is Where, leaving out the search condition when the caller joins
+// the search hits itself.
+func (f JobFilter) where(first int, joinedSearch bool) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", first+len(args)-1)
 	}
 
-	return jobs, nil
+	// Duplicate listings are shown on their canonical job's page
+	if !f.IncludeMerged {
+		clauses = append(clauses, "merged_into IS NULL")
+	}
+	if !f.IncludeClosed {
+		clauses = append(clauses, "closed_at IS NULL")
+	}
+	if f.Status != "" {
+		clauses = append(clauses, userStatus(arg(f.UserID))+" = "+arg(f.Status))
+	}
+	if f.Starred {
+		clauses = append(clauses, userFlag("starred", arg(f.UserID))+" = 1")
+	}
+	if !f.IncludeHidden && f.UserID > 0 {
+		clauses = append(clauses, userFlag("hidden", arg(f.UserID))+" = 0")
+	}
+	var tags []string
+	for _, t := range f.Tags {
+		if t, err := NormalizeTag(t); err == nil {
+			tags = append(tags, arg(t))
+		}
+	}
+	if len(tags) > 0 {
+		clauses = append(clauses, "id IN (SELECT job_id FROM job_tags WHERE user_id = "+arg(f.UserID)+
+			" AND tag IN ("+strings.Join(tags, ", ")+"))")
+	}
+	if q := ftsQuery(f.Search); q != "" && !joinedSearch {
+		clauses = append(clauses, "id IN (SELECT rowid FROM job_search WHERE job_search MATCH "+arg(q)+")")
+	}
+	if f.Company != "" {
+		clauses = append(clauses, "company = "+arg(f.Company))
+	}
+	if f.CompanyID > 0 {
+		clauses = append(clauses, "company_id = "+arg(f.CompanyID))
+	}
+	if f.Location != "" {
+		clauses = append(clauses, "location = "+arg(f.Location))
+	}
+	var levels []string
+	for _, lv := range f.Levels {
+		for _, kw := range LevelKeywords[lv] {
+			levels = append(levels, "title LIKE "+arg("%"+kw+"%")+" COLLATE NOCASE")
+		}
+	}
+	if len(levels) > 0 {
+		clauses = append(clauses, "("+strings.Join(levels, " OR ")+")")
+	}
+	if !f.AddedAfter.IsZero() {
+		clauses = append(clauses, "date_added >= "+arg(sqliteTime(f.AddedAfter)))
+	}
+	if !f.AddedBefore.IsZero() {
+		clauses = append(clauses, "date_added < "+arg(sqliteTime(f.AddedBefore)))
+	}
+	if f.Profile != "" {
+		clauses = append(clauses, "id IN (SELECT job_id FROM job_profiles WHERE profile = "+arg(f.Profile)+")")
+	}
+	if clause, cargs := f.Companies.Clause(first + len(args)); clause != "" {
+		clauses = append(clauses, clause)
+		args = append(args, cargs...)
+	}
+
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(clauses, " AND "), args
 }
 
-// -------------------- USERS TABLE --------------------
+// CountJobs returns how many jobs match the filter.
+func (d *DB) CountJobs(filter JobFilter) (int, error) {
+	where, args := filter.Where(1)
+	var n int
+	err := d.Read.QueryRow(`SELECT COUNT(*) FROM job_applications `+where, args...).Scan(&n)
+	return n, err
+}
 
-// CreateUserSchema ensures a users table exists for authentication.
-func (d *DB) CreateUserSchema() error {
-	q := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT UNIQUE NOT NULL,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-	_, err := d.Conn.Exec(q)
-	return err
+// ListJobs returns one page (from 1) of the jobs matching the filter. A
+// pageSize of 0 or less returns every match.
+func (d *DB) ListJobs(filter JobFilter, page, pageSize int) ([]Job, error) {
+	p, err := d.ListJobsPage(filter, PageRequest{Page: page, Size: pageSize})
+	return p.Jobs, err
 }
 
+// -------------------- USERS TABLE --------------------
+
 // CreateUser registers a new user with bcrypt password hashing.
 func (d *DB) CreateUser(username, password string) error {
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -192,7 +663,7 @@ func (d *DB) CreateUser(username, password string) error {
 // AuthenticateUser checks if username/password is valid.
 func (d *DB) AuthenticateUser(username, password string) (bool, error) {
 	var storedHash string
-	err := d.Conn.QueryRow(`SELECT password_hash FROM users WHERE username=$1`, username).Scan(&storedHash)
+	err := d.Read.QueryRow(`SELECT password_hash FROM users WHERE username=$1`, username).Scan(&storedHash)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil // user not found
@@ -214,7 +685,7 @@ func (d *DB) GetUserByUsername(username string) (int, string, time.Time, error)
 		u         string
 		createdAt time.Time
 	)
-	err := d.Conn.QueryRow(
+	err := d.Read.QueryRow(
 		`SELECT id, username, created_at FROM users WHERE username=$1`,
 		username,
 	).Scan(&id, &u, &createdAt)
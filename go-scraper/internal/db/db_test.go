@@ -0,0 +1,1383 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// openTestDB connects to a fresh database in a temporary directory.
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "jobs.db"))
+	d, err := Connect()
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// testUser creates a user and returns its ID.
+func testUser(t *testing.T, d *DB, name string) int {
+	t.Helper()
+	if err := d.CreateUser(name, "password"); err != nil {
+		t.Fatal(err)
+	}
+	id, _, _, err := d.GetUserByUsername(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestRecordJobSeenVersions(t *testing.T) {
+	d := openTestDB(t)
+
+	res, err := d.RecordJobSeen(Job{Title: "SWE Intern", Company: "Acme", Location: "Remote", Type: "Eng", URL: "https://x/1", Description: "Go"})
+	if err != nil || res != JobInserted {
+		t.Fatalf("first RecordJobSeen = %v, %v; want JobInserted", res, err)
+	}
+	res, err = d.RecordJobSeen(Job{Title: "SWE Intern", Company: "Acme", Location: "Remote", Type: "Eng", URL: "https://x/1", Description: "Go"})
+	if err != nil || res != JobUnchanged {
+		t.Fatalf("repeat RecordJobSeen = %v, %v; want JobUnchanged", res, err)
+	}
+	res, err = d.RecordJobSeen(Job{Title: "SWE New Grad", Company: "Acme", Location: "Remote", Type: "", URL: "https://x/1", Description: "Go"})
+	if err != nil || res != JobUpdated {
+		t.Fatalf("edited RecordJobSeen = %v, %v; want JobUpdated", res, err)
+	}
+
+	job, err := d.GetJob(1)
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if job.Title != "SWE New Grad" || job.Type != "Eng" {
+		t.Errorf("GetJob = %q/%q, want edited title and kept type", job.Title, job.Type)
+	}
+
+	versions, err := d.ListJobVersions(1)
+	if err != nil {
+		t.Fatalf("ListJobVersions: %v", err)
+	}
+	if len(versions) != 1 || len(versions[0].Changes) != 1 || versions[0].Changes[0].Field != "title" {
+		t.Errorf("ListJobVersions = %+v, want a single title change", versions)
+	}
+}
+
+func TestCloseUnseenJobs(t *testing.T) {
+	d := openTestDB(t)
+
+	for _, url := range []string{"https://x/1", "https://x/2"} {
+		if _, err := d.RecordJobSeen(Job{Title: "Engineer", Company: "Acme", Location: "Remote", Type: "Eng", URL: url, Source: "greenhouse"}); err != nil {
+			t.Fatalf("RecordJobSeen: %v", err)
+		}
+	}
+	if _, err := d.Conn.Exec(`UPDATE job_applications SET last_seen_at = '2020-01-01 00:00:00'`); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	if _, err := d.RecordJobSeen(Job{Title: "Engineer", Company: "Acme", Location: "Remote", Type: "Eng", URL: "https://x/1", Source: "greenhouse"}); err != nil {
+		t.Fatalf("RecordJobSeen: %v", err)
+	}
+	closed, err := d.CloseUnseenJobs("greenhouse", "Acme", started)
+	if err != nil || closed != 1 {
+		t.Fatalf("CloseUnseenJobs = %d, %v; want 1", closed, err)
+	}
+
+	open, err := d.ListJobs(JobFilter{}, 1, 10)
+	if err != nil || len(open) != 1 || open[0].URL != "https://x/1" {
+		t.Errorf("ListJobs = %+v, %v; want only the seen job", open, err)
+	}
+
+	// Reappearing postings are reopened
+	if _, err := d.RecordJobSeen(Job{Title: "Engineer", Company: "Acme", Location: "Remote", Type: "Eng", URL: "https://x/2", Source: "greenhouse"}); err != nil {
+		t.Fatalf("RecordJobSeen: %v", err)
+	}
+	open, _ = d.ListJobs(JobFilter{}, 1, 10)
+	if len(open) != 2 {
+		t.Errorf("ListJobs after reopen = %d jobs, want 2", len(open))
+	}
+}
+
+func TestCloseUnseenScope(t *testing.T) {
+	d := openTestDB(t)
+
+	jobs := []Job{
+		{Title: "Engineer", Company: "Acme", URL: "https://x/1", Source: "greenhouse", SourceJobID: "1"},
+		{Title: "Staff Engineer", Company: "Acme", URL: "https://x/2", Source: "greenhouse", SourceJobID: "2"},
+		{Title: "Engineer", Company: "Acme", URL: "https://y/1", Source: "lever", SourceJobID: "a"},
+	}
+	for _, j := range jobs {
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Conn.Exec(`UPDATE job_applications SET last_seen_at = '2020-01-01 00:00:00'`); err != nil {
+		t.Fatal(err)
+	}
+
+	// The board still lists job 2 but the filters dropped it
+	started := time.Now()
+	listed := jobs[1]
+	listed.Title = "Staff Engineer II"
+	if n, err := d.MarkJobsListed([]Job{listed, {Title: "New", Company: "Acme", URL: "https://x/3"}}); err != nil || n != 1 {
+		t.Fatalf("MarkJobsListed = %d, %v; want 1", n, err)
+	}
+	if open, err := d.ListOpenJobs("greenhouse", "Acme"); err != nil || len(open) != 2 {
+		t.Errorf("ListOpenJobs(greenhouse) = %+v, %v; want the two greenhouse jobs", open, err)
+	}
+	if closed, err := d.CloseUnseenJobs("greenhouse", "Acme", started); err != nil || closed != 1 {
+		t.Fatalf("CloseUnseenJobs = %d, %v; want 1", closed, err)
+	}
+
+	for id, wantClosed := range map[int]bool{1: true, 2: false, 3: false} {
+		got, err := d.GetJob(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got.ClosedAt != nil) != wantClosed {
+			t.Errorf("job %d (%s) closed = %v, want %v", id, got.Source, got.ClosedAt != nil, wantClosed)
+		}
+		if id == 2 && got.Title != "Staff Engineer" {
+			t.Errorf("MarkJobsListed changed the title to %q", got.Title)
+		}
+	}
+	if n, _ := d.CountJobs(JobFilter{IncludeClosed: true}); n != 3 {
+		t.Errorf("MarkJobsListed inserted a job: %d jobs, want 3", n)
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	d := openTestDB(t)
+	first := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	job := Job{Title: "Engineer", Company: "Acme", Location: "Remote", Type: "Eng", URL: "https://x/1", Source: "greenhouse", SeenAt: first}
+
+	check := func(when string, firstSeen, lastSeen time.Time, closed bool) {
+		t.Helper()
+		got, err := d.GetJob(1)
+		if err != nil {
+			t.Fatalf("%s: GetJob: %v", when, err)
+		}
+		if !got.FirstSeenAt.Equal(firstSeen) || !got.LastSeenAt.Equal(lastSeen) || (got.ClosedAt != nil) != closed {
+			t.Errorf("%s: first seen %v, last seen %v, closed %v; want %v, %v, %v",
+				when, got.FirstSeenAt, got.LastSeenAt, got.ClosedAt, firstSeen, lastSeen, closed)
+		}
+	}
+
+	if _, err := d.RecordJobSeen(job); err != nil {
+		t.Fatal(err)
+	}
+	check("inserted", first, first, false)
+
+	// Seeing the job again only moves last_seen_at
+	later := first.Add(24 * time.Hour)
+	job.SeenAt = later
+	if _, err := d.RecordJobSeen(job); err != nil {
+		t.Fatal(err)
+	}
+	check("seen again", first, later, false)
+
+	// A scrape that started after the last sighting closes it, once
+	if closed, err := d.CloseUnseenJobs("greenhouse", "Acme", later.Add(time.Hour)); err != nil || closed != 1 {
+		t.Fatalf("CloseUnseenJobs = %d, %v; want 1", closed, err)
+	}
+	if closed, err := d.CloseUnseenJobs("greenhouse", "Acme", later.Add(2*time.Hour)); err != nil || closed != 0 {
+		t.Errorf("second CloseUnseenJobs = %d, %v; want 0", closed, err)
+	}
+	check("closed", first, later, true)
+	if open, err := d.ListOpenJobs("greenhouse", "Acme"); err != nil || len(open) != 0 {
+		t.Errorf("ListOpenJobs = %+v, %v; want none", open, err)
+	}
+
+	// Reappearing now reopens it and keeps the first sighting
+	job.SeenAt = time.Time{}
+	if _, err := d.RecordJobSeen(job); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := d.GetJob(1)
+	if got.ClosedAt != nil || !got.FirstSeenAt.Equal(first) || !got.LastSeenAt.After(later) {
+		t.Errorf("reopened: first seen %v, last seen %v, closed %v; want %v, after %v, open",
+			got.FirstSeenAt, got.LastSeenAt, got.ClosedAt, first, later)
+	}
+}
+
+func TestRecordJobSeenIdentity(t *testing.T) {
+	d := openTestDB(t)
+
+	job := Job{Title: "Engineer", Company: "Acme", URL: "https://boards.greenhouse.io/acme/jobs/7?gh_src=x", Source: "greenhouse", SourceJobID: "7"}
+	if _, err := d.RecordJobSeen(job); err != nil {
+		t.Fatal(err)
+	}
+	// Same posting under a new URL is an edit, not a new job
+	job.URL = "https://acme.com/careers?gh_jid=7"
+	res, err := d.RecordJobSeen(job)
+	if err != nil || res != JobUpdated {
+		t.Fatalf("RecordJobSeen = %v, %v; want JobUpdated", res, err)
+	}
+	got, _ := d.GetJob(1)
+	if got.URL != "https://acme.com/careers?gh_jid=7" {
+		t.Errorf("URL = %q, want the new URL", got.URL)
+	}
+}
+
+func TestMergeDuplicates(t *testing.T) {
+	d := openTestDB(t)
+
+	for _, j := range []Job{
+		{Title: "Software Engineer, New Grad", Company: "Acme", Location: "Remote - US", URL: "https://a/1"},
+		{Title: "Software Engineer - New Grad (2025)", Company: "Acme Inc", Location: "US, Remote", URL: "https://b/1"},
+		{Title: "Data Analyst", Company: "Acme", Location: "Remote - US", URL: "https://a/2"},
+	} {
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	merged, err := d.MergeDuplicates()
+	if err != nil || merged != 1 {
+		t.Fatalf("MergeDuplicates = %d, %v; want 1", merged, err)
+	}
+	sources, err := d.ListJobSources(1)
+	if err != nil || len(sources) != 1 || sources[0].ID != 2 {
+		t.Fatalf("ListJobSources = %+v, %v; want job 2", sources, err)
+	}
+
+	if err := d.UnmergeJob(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.UnmergeJob(99); err != sql.ErrNoRows {
+		t.Errorf("UnmergeJob(99) = %v, want sql.ErrNoRows", err)
+	}
+	if merged, _ := d.MergeDuplicates(); merged != 0 {
+		t.Errorf("MergeDuplicates after unmerge = %d, want 0", merged)
+	}
+	jobs, _ := d.ListJobs(JobFilter{}, 1, 10)
+	if len(jobs) != 3 {
+		t.Errorf("ListJobs = %d jobs, want 3", len(jobs))
+	}
+}
+
+func TestMergeDuplicatesNoChains(t *testing.T) {
+	d := openTestDB(t)
+
+	for _, j := range []Job{
+		{Title: "Software Engineer, New Grad", Company: "Acme", Location: "Remote", URL: "https://a/1"},
+		{Title: "Software Engineer - New Grad", Company: "Acme", Location: "Remote", URL: "https://b/1"},
+		{Title: "Software Engineer (New Grad)", Company: "Acme", Location: "Remote", URL: "https://c/1"},
+	} {
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Job 1 is closed while 3 merges into 2, then reopens and takes over
+	if _, err := d.Conn.Exec(`UPDATE job_applications SET closed_at = CURRENT_TIMESTAMP WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if merged, err := d.MergeDuplicates(); err != nil || merged != 1 {
+		t.Fatalf("MergeDuplicates = %d, %v; want 1", merged, err)
+	}
+	if _, err := d.Conn.Exec(`UPDATE job_applications SET closed_at = NULL WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if merged, err := d.MergeDuplicates(); err != nil || merged != 1 {
+		t.Fatalf("MergeDuplicates = %d, %v; want 1", merged, err)
+	}
+
+	sources, err := d.ListJobSources(1)
+	if err != nil || len(sources) != 2 || sources[0].ID != 2 || sources[1].ID != 3 {
+		t.Fatalf("ListJobSources(1) = %+v, %v; want jobs 2 and 3", sources, err)
+	}
+	if sources, _ := d.ListJobSources(2); len(sources) != 0 {
+		t.Errorf("ListJobSources(2) = %+v, want none", sources)
+	}
+}
+
+func TestMergeDuplicatesSameBoard(t *testing.T) {
+	d := openTestDB(t)
+
+	for _, j := range []Job{
+		{Title: "Software Engineer, New Grad", Company: "Acme", Location: "Remote", URL: "https://a/1", Source: "greenhouse", SourceJobID: "1"},
+		{Title: "Software Engineer - New Grad", Company: "Acme", Location: "Remote", URL: "https://a/2", Source: "greenhouse", SourceJobID: "2"},
+		{Title: "Software Engineer (New Grad)", Company: "Acme", Location: "Remote", URL: "https://b/1", Source: "lever", SourceJobID: "x"},
+		{Title: "Software Engineer New Grad", Company: "Acme", Location: "Remote", URL: "https://b/2", Source: "lever", SourceJobID: "y"},
+	} {
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each Lever posting joins a different Greenhouse one
+	if merged, err := d.MergeDuplicates(); err != nil || merged != 2 {
+		t.Fatalf("MergeDuplicates = %d, %v; want 2", merged, err)
+	}
+	for id, want := range map[int]int{1: 3, 2: 4} {
+		sources, err := d.ListJobSources(id)
+		if err != nil || len(sources) != 1 || sources[0].ID != want {
+			t.Errorf("ListJobSources(%d) = %+v, %v; want job %d", id, sources, err, want)
+		}
+	}
+}
+
+func TestAcquireLease(t *testing.T) {
+	d := openTestDB(t)
+
+	if ok, err := d.AcquireLease("scraper", "a", time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLease(a) = %v, %v; want true", ok, err)
+	}
+	if ok, _ := d.AcquireLease("scraper", "b", time.Minute); ok {
+		t.Error("AcquireLease(b) succeeded while a holds the lease")
+	}
+	if ok, _ := d.AcquireLease("scraper", "a", time.Minute); !ok {
+		t.Error("AcquireLease(a) could not renew its own lease")
+	}
+	if err := d.ReleaseLease("scraper", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := d.AcquireLease("scraper", "b", time.Minute); !ok {
+		t.Error("AcquireLease(b) failed after release")
+	}
+}
+
+func TestRecordJobSeenProfiles(t *testing.T) {
+	d := openTestDB(t)
+
+	job := Job{Title: "SWE Intern", Company: "Acme", Location: "Toronto", URL: "https://x/1", Profiles: []string{"internships", "swe"}}
+	if _, err := d.RecordJobSeen(job); err != nil {
+		t.Fatalf("RecordJobSeen: %v", err)
+	}
+	job.Profiles = []string{"internships"}
+	if _, err := d.RecordJobSeen(job); err != nil {
+		t.Fatalf("RecordJobSeen: %v", err)
+	}
+	job.Profiles = nil
+	if _, err := d.RecordJobSeen(job); err != nil {
+		t.Fatalf("RecordJobSeen: %v", err)
+	}
+
+	got, err := d.JobProfiles(1)
+	if err != nil || len(got) != 1 || got[0] != "internships" {
+		t.Fatalf("JobProfiles = %v, %v; want [internships]", got, err)
+	}
+	jobs, err := d.ListJobs(JobFilter{Profile: "swe"}, 1, 10)
+	if err != nil || len(jobs) != 0 {
+		t.Errorf("ListJobs(swe) = %d jobs, %v; want none", len(jobs), err)
+	}
+	jobs, err = d.ListJobs(JobFilter{Profile: "internships"}, 1, 10)
+	if err != nil || len(jobs) != 1 {
+		t.Errorf("ListJobs(internships) = %d jobs, %v; want 1", len(jobs), err)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Base: time.Hour, Max: 5 * time.Hour}
+	for failures, want := range map[int]time.Duration{0: 0, 1: time.Hour, 2: 2 * time.Hour, 3: 4 * time.Hour, 4: 5 * time.Hour, 40: 5 * time.Hour} {
+		if got := b.Delay(failures); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestCompanyHealth(t *testing.T) {
+	d := openTestDB(t)
+	b := Backoff{Base: time.Hour, Max: 24 * time.Hour, DisableAfter: 3}
+
+	for i := 1; i <= 3; i++ {
+		h, err := d.RecordScrapeFailure("greenhouse", "gone", "404", b)
+		if err != nil {
+			t.Fatalf("RecordScrapeFailure: %v", err)
+		}
+		if h.ConsecutiveFailures != i || h.NextAttemptAt == nil || (h.DisabledAt != nil) != (i == 3) {
+			t.Fatalf("after %d failures: %+v", i, h)
+		}
+	}
+	if err := d.RecordScrapeSuccess("lever", "fine"); err != nil {
+		t.Fatalf("RecordScrapeSuccess: %v", err)
+	}
+	list, err := d.ListCompanyHealth()
+	if err != nil || len(list) != 1 || list[0].Slug != "gone" || list[0].LastError != "404" {
+		t.Fatalf("ListCompanyHealth = %+v, %v", list, err)
+	}
+
+	if ok, err := d.EnableCompany("greenhouse", "gone"); !ok || err != nil {
+		t.Fatalf("EnableCompany = %v, %v", ok, err)
+	}
+	h, err := d.GetCompanyHealth("greenhouse", "gone")
+	if err != nil || h.DisabledAt != nil || h.ConsecutiveFailures != 0 || h.NextAttemptAt != nil {
+		t.Errorf("after EnableCompany: %+v, %v", h, err)
+	}
+}
+
+func TestUpsertCompany(t *testing.T) {
+	d := openTestDB(t)
+
+	// A job scraped before the catalog existed carries the title-cased slug
+	if _, err := d.RecordJobSeen(Job{Title: "Engineer", Company: "Paloaltonetworks", URL: "https://x/1", Source: "greenhouse"}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := d.UpsertCompany(Company{Platform: "greenhouse", Slug: "paloaltonetworks", Name: "Palo Alto Networks", Tags: []string{"security"}}, "Paloaltonetworks", "Paloaltonetworks")
+	if err != nil {
+		t.Fatalf("UpsertCompany: %v", err)
+	}
+	job, _ := d.GetJob(1)
+	if job.CompanyID != c.ID || job.Company != "Palo Alto Networks" {
+		t.Fatalf("GetJob = %d/%q, want linked to %d and renamed", job.CompanyID, job.Company, c.ID)
+	}
+
+	// Re-upserting without a name or tags keeps them, and notes survive
+	if err := d.SetCompanyNotes(c.ID, "referral from Sam"); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = d.UpsertCompany(Company{Platform: "greenhouse", Slug: "paloaltonetworks", YCBatch: "W05"}, "Paloaltonetworks"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "Palo Alto Networks" || len(c.Tags) != 1 || c.YCBatch != "W05" || c.Notes != "referral from Sam" {
+		t.Errorf("UpsertCompany = %+v, want stored name, tags and notes kept", c)
+	}
+	if err := d.SetCompanyNotes(99, "x"); err != sql.ErrNoRows {
+		t.Errorf("SetCompanyNotes(missing) = %v, want sql.ErrNoRows", err)
+	}
+
+	list, err := d.ListCompanies()
+	if err != nil || len(list) != 1 || list[0].OpenJobs != 1 {
+		t.Fatalf("ListCompanies = %+v, %v; want one company with one open job", list, err)
+	}
+	if _, found, _ := d.FindCompany("lever", "paloaltonetworks"); found {
+		t.Error("FindCompany matched a different platform")
+	}
+
+	closed, err := d.CloseUnseenCompanyJobs("greenhouse", c.ID, time.Now().Add(time.Hour))
+	if err != nil || closed != 1 {
+		t.Errorf("CloseUnseenCompanyJobs = %d, %v; want 1", closed, err)
+	}
+}
+
+func TestCompanyFilter(t *testing.T) {
+	d := openTestDB(t)
+
+	for _, c := range []struct {
+		slug, batch, industry string
+		team                  int
+	}{
+		{"old", "Winter 2019", "Fintech", 400},
+		{"new", "S24", "Fintech", 30},
+		{"newer", "W25", "Healthcare", 0},
+	} {
+		stored, err := d.UpsertCompany(Company{Platform: "greenhouse", Slug: c.slug}, c.slug)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.SetCompanyDirectory(stored.ID, Company{YCBatch: c.batch, Industry: c.industry, TeamSize: c.team}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.RecordJobSeen(Job{Title: "Engineer", Company: c.slug, URL: "https://x/" + c.slug, CompanyID: stored.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		f    CompanyFilter
+		want int
+	}{
+		{CompanyFilter{}, 3},
+		{CompanyFilter{Batch: "S24+"}, 2},
+		{CompanyFilter{Batch: "W19"}, 1},
+		{CompanyFilter{Industry: "fintech"}, 2},
+		{CompanyFilter{MaxTeamSize: 100}, 1},
+		{CompanyFilter{Batch: "W19+", Industry: "Fintech", MaxTeamSize: 500}, 2},
+	}
+	for _, tt := range tests {
+		jobs, err := d.ListJobs(JobFilter{Companies: tt.f}, 1, 10)
+		if err != nil || len(jobs) != tt.want {
+			t.Errorf("ListJobs(%+v) = %d jobs, %v; want %d", tt.f, len(jobs), err, tt.want)
+		}
+	}
+
+	batches, industries, err := d.CompanyFacets()
+	if err != nil || !reflect.DeepEqual(batches, []string{"W25", "S24", "W19"}) || len(industries) != 2 {
+		t.Errorf("CompanyFacets = %v, %v, %v", batches, industries, err)
+	}
+}
+
+func TestRecordJobSeenReplay(t *testing.T) {
+	d := openTestDB(t)
+	day1 := time.Date(2026, 10, 1, 3, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+
+	job := Job{Title: "Engineer", Company: "Acme", URL: "https://x/1", Source: "greenhouse", SeenAt: day2}
+	if _, err := d.RecordJobSeen(job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.CloseUnseenJobs("greenhouse", "Acme", day2.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replaying day 1, with the old title, neither reopens nor reverts
+	job.Title, job.SeenAt = "Engineer (old)", day1
+	res, err := d.RecordJobSeen(job)
+	if err != nil || res != JobUnchanged {
+		t.Fatalf("replay = %v, %v; want JobUnchanged", res, err)
+	}
+	got, _ := d.GetJob(1)
+	if got.Title != "Engineer" || !got.IsClosed() || !got.LastSeenAt.Equal(day2) || !got.FirstSeenAt.Equal(day2) {
+		t.Errorf("after replay: %+v", got)
+	}
+
+	// A later sighting reopens
+	job.Title, job.SeenAt = "Engineer", time.Now()
+	if _, err := d.RecordJobSeen(job); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := d.GetJob(1); got.IsClosed() {
+		t.Error("job still closed after a new sighting")
+	}
+}
+
+func TestUpsertJobs(t *testing.T) {
+	d := openTestDB(t)
+	// Make one job fail, to check it does not take the batch down with it
+	if _, err := d.Conn.Exec(`CREATE TRIGGER reject_bad BEFORE INSERT ON job_applications
+		WHEN NEW.title = 'bad' BEGIN SELECT RAISE(ABORT, 'rejected'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := []Job{
+		{Title: "Engineer", Company: "Acme", URL: "https://x/1", Profiles: []string{"swe"}},
+		{Title: "Analyst", Company: "Acme", URL: "https://x/2"},
+		{Title: "Engineer", Company: "Acme", URL: "https://x/1?utm_source=feed"}, // same posting twice
+		{Title: "bad", Company: "Acme", URL: "https://x/3"},
+	}
+	counts, err := d.UpsertJobs(context.Background(), batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts.Inserted != 2 || counts.Unchanged != 1 || counts.Updated != 0 || counts.Failed != 1 {
+		t.Errorf("first batch = %+v", counts)
+	}
+	if counts.Errors[3] == nil || counts.Results[2] != JobUnchanged {
+		t.Errorf("per-job results = %v, %v", counts.Results, counts.Errors)
+	}
+	if profiles, _ := d.JobProfiles(1); !reflect.DeepEqual(profiles, []string{"swe"}) {
+		t.Errorf("profiles = %v", profiles)
+	}
+
+	batch[1].Title = "Data Analyst"
+	counts, err = d.UpsertJobs(context.Background(), batch[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts.Inserted != 0 || counts.Updated != 1 || counts.Unchanged != 2 || counts.Failed != 0 {
+		t.Errorf("second batch = %+v", counts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.UpsertJobs(ctx, []Job{{Title: "Designer", URL: "https://x/4"}}); err == nil {
+		t.Error("cancelled batch succeeded")
+	}
+	var n int
+	d.Conn.QueryRow(`SELECT COUNT(*) FROM job_applications`).Scan(&n)
+	if n != 2 {
+		t.Errorf("%d jobs stored, want 2", n)
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	t.Setenv("DB_PATH", path)
+
+	// A database from before lifecycle tracking and versioned migrations
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Exec(`
+	CREATE TABLE job_applications (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT, company TEXT, location TEXT,
+		salary TEXT, type TEXT, url TEXT UNIQUE, date_added DATETIME DEFAULT CURRENT_TIMESTAMP, status TEXT DEFAULT 'Not Applied');
+	INSERT INTO job_applications(title, company, location, type, url, date_added)
+		VALUES('Engineer', 'Acme', 'Remote', 'Eng', 'https://x/1?utm_source=feed', '2025-01-02 03:04:05');
+	`)
+	conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Connect()
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer d.Close()
+	all, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.SchemaVersion(); err != nil || v != len(all) {
+		t.Errorf("SchemaVersion = %d, %v; want %d", v, err, len(all))
+	}
+	got, err := d.GetJob(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.URL != "https://x/1" || got.FirstSeenAt.Format("2006-01-02") != "2025-01-02" {
+		t.Errorf("upgraded job = %+v", got)
+	}
+	if _, err := d.UpsertCompany(Company{Platform: "lever", Slug: "acme", Industry: "Robotics"}, "Acme"); err != nil {
+		t.Errorf("companies table: %v", err)
+	}
+	if n, err := d.CountJobs(JobFilter{Search: "engineer"}); err != nil || n != 1 {
+		t.Errorf("search over upgraded jobs = %d, %v; want 1", n, err)
+	}
+}
+
+func TestMigrateDownAndTooNew(t *testing.T) {
+	d := openTestDB(t)
+	all, _ := Migrations()
+
+	undone, err := d.MigrateDown(len(all))
+	if err != nil || len(undone) != len(all) {
+		t.Fatalf("MigrateDown = %d, %v", len(undone), err)
+	}
+	var n int
+	d.Conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'job_applications'`).Scan(&n)
+	if n != 0 {
+		t.Error("job_applications survived rolling back every migration")
+	}
+	if applied, err := d.MigrateUp(); err != nil || len(applied) != len(all) {
+		t.Fatalf("MigrateUp = %d, %v", len(applied), err)
+	}
+
+	if _, err := d.Conn.Exec(`INSERT INTO schema_migrations(version, name) VALUES(999, 'from_the_future')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Connect(); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Connect on a newer schema = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestJobFilter(t *testing.T) {
+	d := openTestDB(t)
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, j := range []Job{
+		{Title: "Software Engineer Intern", Company: "Acme", Location: "Remote", URL: "https://x/1"},
+		{Title: "New Grad Data Analyst", Company: "Beta", Location: "NYC", URL: "https://x/2"},
+		{Title: "Staff Engineer", Company: "Acme", Location: "Remote", URL: "https://x/3"},
+		{Title: "Junior Designer", Company: "Gamma", Location: "NYC", URL: "https://x/4"},
+	} {
+		j.SeenAt = day.AddDate(0, 0, i)
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	alice := testUser(t, d, "alice")
+	if err := d.SetJobStatus(DefaultPipeline, alice, 2, "Applied"); err != nil {
+		t.Fatal(err)
+	}
+	d.Conn.Exec(`UPDATE job_applications SET closed_at = '2026-03-10 00:00:00' WHERE id = 3`)
+	d.Conn.Exec(`UPDATE job_applications SET merged_into = 1 WHERE id = 4`)
+
+	tests := []struct {
+		name string
+		f    JobFilter
+		want []int
+	}{
+		{"default", JobFilter{}, []int{2, 1}},
+		{"closed and merged", JobFilter{IncludeClosed: true, IncludeMerged: true}, []int{4, 3, 2, 1}},
+		{"status", JobFilter{UserID: alice, Status: "Not Applied"}, []int{1}},
+		{"another user's status", JobFilter{UserID: alice + 1, Status: "Not Applied"}, []int{2, 1}},
+		{"search", JobFilter{Search: "ANALYST"}, []int{2}},
+		{"company and location", JobFilter{Company: "Acme", Location: "Remote", IncludeClosed: true}, []int{3, 1}},
+		{"levels", JobFilter{Levels: []string{"Intern", "New Grad"}}, []int{2, 1}},
+		{"added range", JobFilter{AddedAfter: day.AddDate(0, 0, 1), AddedBefore: day.AddDate(0, 0, 3), IncludeClosed: true}, []int{3, 2}},
+		{"oldest first", JobFilter{Sort: SortOldest, IncludeClosed: true}, []int{1, 2, 3}},
+		{"by title", JobFilter{Sort: SortTitle, IncludeClosed: true}, []int{2, 1, 3}},
+	}
+	for _, tt := range tests {
+		jobs, err := d.ListJobs(tt.f, 1, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []int
+		for _, j := range jobs {
+			got = append(got, j.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ListJobs = %v, want %v", tt.name, got, tt.want)
+		}
+		if n, err := d.CountJobs(tt.f); err != nil || n != len(tt.want) {
+			t.Errorf("%s: CountJobs = %d, %v; want %d", tt.name, n, err, len(tt.want))
+		}
+	}
+
+	page, err := d.ListJobs(JobFilter{IncludeClosed: true}, 2, 2)
+	if err != nil || len(page) != 1 || page[0].ID != 1 {
+		t.Errorf("page 2 = %+v, %v", page, err)
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := map[string]string{
+		`golang`:                `"golang"`,
+		`Go  "new grad" eng*`:   `"Go" "new grad" "eng"*`,
+		`node.js`:               `"node js"`,
+		`title:x OR (y) NEAR "`: `"title x" "OR" "y" "NEAR"`,
+		`"unterminated phrase`:  `"unterminated phrase"`,
+		`*** --`:                ``,
+		`São Paulo`:             `"São" "Paulo"`,
+	}
+	for in, want := range tests {
+		if got := ftsQuery(in); got != want {
+			t.Errorf("ftsQuery(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSearchJobs(t *testing.T) {
+	d := openTestDB(t)
+	for _, j := range []Job{
+		{Title: "Backend Engineer", Company: "Acme", Location: "Remote", URL: "https://x/1", Description: "We write Golang services."},
+		{Title: "Golang Developer", Company: "Beta", Location: "New York, NY", URL: "https://x/2"},
+		{Title: "Data Analyst", Company: "Golden Gate Labs", Location: "San Francisco", URL: "https://x/3", Description: "New grad friendly."},
+	} {
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := func(f JobFilter) []int {
+		t.Helper()
+		jobs, err := d.ListJobs(f, 1, 0)
+		if err != nil {
+			t.Fatalf("ListJobs(%+v): %v", f, err)
+		}
+		var out []int
+		for _, j := range jobs {
+			out = append(out, j.ID)
+		}
+		return out
+	}
+
+	// The title match ranks above the description match
+	if got := ids(JobFilter{Search: "golang", Sort: SortRelevance}); !reflect.DeepEqual(got, []int{2, 1}) {
+		t.Errorf("golang by relevance = %v, want [2 1]", got)
+	}
+	if got := ids(JobFilter{Search: "gol*"}); len(got) != 3 {
+		t.Errorf("prefix gol* = %v, want all three", got)
+	}
+	if got := ids(JobFilter{Search: `"new grad"`}); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf(`"new grad" = %v, want [3]`, got)
+	}
+	if got := ids(JobFilter{Search: `"grad new"`}); got != nil {
+		t.Errorf(`"grad new" = %v, want none`, got)
+	}
+	if got := ids(JobFilter{Search: "new york"}); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("new york = %v, want [2]", got)
+	}
+	if n, err := d.CountJobs(JobFilter{Search: "golang", Company: "Acme"}); err != nil || n != 1 {
+		t.Errorf("CountJobs(golang, Acme) = %d, %v", n, err)
+	}
+
+	jobs, _ := d.ListJobs(JobFilter{Search: "golang", Company: "Acme"}, 1, 10)
+	if len(jobs) != 1 || jobs[0].Snippet != "We write "+SnippetOpen+"Golang"+SnippetClose+" services." {
+		t.Errorf("snippet = %+v", jobs)
+	}
+
+	// Edits reach the index through the triggers
+	if _, err := d.RecordJobSeen(Job{Title: "Rust Developer", Company: "Beta", URL: "https://x/2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(JobFilter{Search: "rust"}); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("rust after edit = %v, want [2]", got)
+	}
+	if got := ids(JobFilter{Search: "golang"}); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("golang after edit = %v, want [1]", got)
+	}
+}
+
+func TestOpenPragmas(t *testing.T) {
+	d := openTestDB(t)
+
+	var mode string
+	if err := d.Conn.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("journal_mode = %q, %v; want wal", mode, err)
+	}
+	for _, c := range []struct {
+		name string
+		conn *sql.DB
+	}{{"writer", d.Conn}, {"reader", d.Read}} {
+		var fk, timeout int
+		if err := c.conn.QueryRow(`PRAGMA foreign_keys`).Scan(&fk); err != nil || fk != 1 {
+			t.Errorf("%s foreign_keys = %d, %v; want 1", c.name, fk, err)
+		}
+		if err := c.conn.QueryRow(`PRAGMA busy_timeout`).Scan(&timeout); err != nil || timeout != int(busyTimeout.Milliseconds()) {
+			t.Errorf("%s busy_timeout = %d, %v; want %d", c.name, timeout, err, busyTimeout.Milliseconds())
+		}
+	}
+	if _, err := d.Read.Exec(`INSERT INTO users(username, password_hash) VALUES('x', 'y')`); err == nil {
+		t.Error("write through the read pool succeeded; want it rejected")
+	}
+
+	// Readers see committed writes and are not blocked by an open write
+	// transaction.
+	if err := d.InsertJobTyped("SWE", "Acme", "Remote", "Eng", "https://x/1"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`UPDATE job_applications SET title = 'Changed'`); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := d.ListJobs(JobFilter{}, 0, 0)
+	if err != nil || len(jobs) != 1 || jobs[0].Title != "SWE" {
+		t.Fatalf("ListJobs during write = %+v, %v; want the committed job", jobs, err)
+	}
+}
+
+func TestListJobsPage(t *testing.T) {
+	d := openTestDB(t)
+	run := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	record := func(i int, title string, at time.Time) {
+		t.Helper()
+		j := Job{Title: title, Company: "Acme", Location: "Remote", URL: fmt.Sprintf("https://x/%d", i), SeenAt: at}
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// One scrape run: every job shares date_added, so only id orders them
+	for i := 1; i <= 7; i++ {
+		record(i, fmt.Sprintf("Engineer %c", 'H'-i), run)
+	}
+	ids := func(jobs []Job) []int {
+		var out []int
+		for _, j := range jobs {
+			out = append(out, j.ID)
+		}
+		return out
+	}
+
+	for _, f := range []JobFilter{{}, {Sort: SortOldest}, {Sort: SortTitle}, {Search: "engineer", Sort: SortRelevance}} {
+		all, err := d.ListJobs(f, 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var walked []int
+		var pages []JobPage
+		p, err := d.ListJobsPage(f, PageRequest{Size: 3})
+		for ; err == nil; p, err = d.ListJobsPage(f, PageRequest{Cursor: p.Next, Size: 3}) {
+			walked = append(walked, ids(p.Jobs)...)
+			pages = append(pages, p)
+			if p.Next == "" {
+				break
+			}
+		}
+		if err != nil {
+			t.Fatalf("sort %q: %v", f.Sort, err)
+		}
+		if !reflect.DeepEqual(walked, ids(all)) || len(pages) != 3 {
+			t.Errorf("sort %q: walked %v in %d pages, want %v in 3", f.Sort, walked, len(pages), ids(all))
+		}
+		if pages[0].Prev != "" {
+			t.Errorf("sort %q: first page has a Prev cursor", f.Sort)
+		}
+		// Walking back returns the same pages
+		for i := len(pages) - 1; i > 0; i-- {
+			back, err := d.ListJobsPage(f, PageRequest{Cursor: pages[i].Prev, Size: 3})
+			if err != nil || !reflect.DeepEqual(ids(back.Jobs), ids(pages[i-1].Jobs)) {
+				t.Errorf("sort %q: Prev of page %d = %v, %v; want %v", f.Sort, i+1, ids(back.Jobs), err, ids(pages[i-1].Jobs))
+			}
+			if (back.Prev == "") != (i == 1) || back.Next == "" {
+				t.Errorf("sort %q: Prev of page %d has cursors %q, %q", f.Sort, i+1, back.Prev, back.Next)
+			}
+		}
+	}
+
+	// A job inserted mid-browse lands on page 1 without shifting later pages
+	first, _ := d.ListJobsPage(JobFilter{}, PageRequest{Size: 3})
+	record(8, "Engineer New", run.Add(time.Hour))
+	second, err := d.ListJobsPage(JobFilter{}, PageRequest{Cursor: first.Next, Size: 3})
+	if want := []int{4, 3, 2}; err != nil || !reflect.DeepEqual(ids(second.Jobs), want) {
+		t.Errorf("page 2 after insert = %v, %v; want %v", ids(second.Jobs), err, want)
+	}
+	offset, err := d.ListJobsPage(JobFilter{}, PageRequest{Page: 2, Size: 3})
+	if want := []int{5, 4, 3}; err != nil || !reflect.DeepEqual(ids(offset.Jobs), want) || offset.Prev == "" || offset.Next == "" {
+		t.Errorf("offset page 2 = %v, %v; want %v with both cursors", ids(offset.Jobs), err, want)
+	}
+
+	for _, bad := range []string{"!!", "e30", first.Next} {
+		if _, err := d.ListJobsPage(JobFilter{Sort: SortTitle}, PageRequest{Cursor: bad, Size: 3}); !errors.Is(err, ErrBadCursor) {
+			t.Errorf("cursor %q with another sort: err = %v, want ErrBadCursor", bad, err)
+		}
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	d := openTestDB(t)
+	dir := filepath.Join(t.TempDir(), "backups")
+	if err := d.InsertJobTyped("SWE", "Acme", "Remote", "Eng", "https://x/1"); err != nil {
+		t.Fatal(err)
+	}
+	alice := testUser(t, d, "alice")
+	d.SetJobStatus(DefaultPipeline, alice, 1, "Applied")
+
+	path, err := d.Backup(dir)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if v, err := CheckBackup(path); err != nil || v != len(mustMigrations(t)) {
+		t.Fatalf("CheckBackup = %d, %v", v, err)
+	}
+
+	// Changes after the backup are undone by restoring it
+	d.SetJobStatus(DefaultPipeline, alice, 1, "Withdrawn")
+	d.InsertJobTyped("PM", "Acme", "Remote", "Eng", "https://x/2")
+	if err := d.Restore(path); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	jobs, err := d.ListJobs(JobFilter{UserID: alice}, 1, 0)
+	if err != nil || len(jobs) != 1 || jobs[0].Status != "Applied" {
+		t.Fatalf("after restore: %+v, %v", jobs, err)
+	}
+
+	// Damaged, foreign and newer backups are refused
+	junk := filepath.Join(dir, "junk.db")
+	os.WriteFile(junk, []byte("not a database"), 0644)
+	if err := d.Restore(junk); err == nil {
+		t.Error("Restore(junk) succeeded")
+	}
+	if err := d.Restore(filepath.Join(dir, "missing.db")); err == nil {
+		t.Error("Restore(missing) succeeded")
+	}
+	newer, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer.Exec(`INSERT INTO schema_migrations(version, name) VALUES(999, 'future')`)
+	newer.Close()
+	if err := d.Restore(path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Restore(newer) = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func mustMigrations(t *testing.T) []Migration {
+	t.Helper()
+	all, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return all
+}
+
+func TestPruneBackups(t *testing.T) {
+	d := openTestDB(t)
+	dir := t.TempDir()
+	// Two backups a day for three weeks, newest 2026-03-22 18:00
+	end := time.Date(2026, 3, 22, 18, 0, 0, 0, time.UTC)
+	for i := 0; i < 42; i++ {
+		at := end.Add(-time.Duration(i) * 12 * time.Hour)
+		os.WriteFile(filepath.Join(dir, "jobs-"+at.Format(backupTimeFormat)+".db"), nil, 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644)
+
+	removed, err := d.PruneBackups(dir, Retention{Last: 3, Daily: 4, Weekly: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _ := d.ListBackups(dir)
+	var got []string
+	for _, b := range kept {
+		got = append(got, b.TakenAt.Format("01-02 15"))
+	}
+	// The 3 newest; the newest of 03-22..03-19; and of ISO weeks 12, 11, 10
+	want := []string{"03-22 18", "03-22 06", "03-21 18", "03-20 18", "03-19 18", "03-15 18", "03-08 18"}
+	if !reflect.DeepEqual(got, want) || len(removed) != 42-len(want) {
+		t.Errorf("kept %v (removed %d), want %v", got, len(removed), want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error("PruneBackups removed a file that is not a backup")
+	}
+}
+
+func TestArchiveJobs(t *testing.T) {
+	d := openTestDB(t)
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i, title := range []string{"Old Closed", "Old Applied", "Recent Closed", "Ancient Open", "Old Duplicate", "Old With Applied Dup", "Applied Dup", "Old Hidden", "Old Unsaved"} {
+		j := Job{Title: title, Company: "Acme", Location: "Remote", URL: fmt.Sprintf("https://x/%d", i+1), SeenAt: now.AddDate(0, -2, 0)}
+		if i == 0 {
+			j.Profiles = []string{"new-grad"}
+		}
+		if _, err := d.RecordJobSeen(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, q := range []string{
+		`UPDATE job_applications SET closed_at = '2026-02-01 00:00:00' WHERE id IN (1, 2, 6, 8, 9)`,
+		`UPDATE job_applications SET closed_at = '2026-05-25 00:00:00' WHERE id = 3`,
+		`UPDATE job_applications SET first_seen_at = '2025-01-01 00:00:00' WHERE id = 4`,
+		`UPDATE job_applications SET merged_into = 1 WHERE id = 5`,
+		`UPDATE job_applications SET merged_into = 6 WHERE id = 7`,
+	} {
+		if _, err := d.Conn.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	alice := testUser(t, d, "alice")
+	for _, id := range []int{2, 7} {
+		if err := d.SetJobStatus(DefaultPipeline, alice, id, "Applied"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Hiding a job, or saving it and moving it back, also counts as acting on it
+	if err := d.SetJobHidden(alice, 8, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []string{"Saved", StatusNotApplied} {
+		if err := d.SetJobStatus(DefaultPipeline, alice, 9, status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules := []ArchiveRule{
+		{Name: "closed-90d", ClosedFor: 90 * 24 * time.Hour},
+		{Name: "old", Age: 365 * 24 * time.Hour},
+		{Name: "closed-30d", ClosedFor: 30 * 24 * time.Hour},
+	}
+	archived := func(jobs []ArchivedJob) map[int]string {
+		out := map[int]string{}
+		for _, j := range jobs {
+			out[j.ID] = j.Rule
+		}
+		return out
+	}
+	want := map[int]string{1: "closed-90d", 5: "closed-90d", 4: "old"}
+
+	preview, err := d.ArchiveJobs(rules, now, true)
+	if err != nil || !reflect.DeepEqual(archived(preview), want) {
+		t.Fatalf("dry run = %v, %v; want %v", archived(preview), err, want)
+	}
+	if n, _ := d.CountJobs(JobFilter{IncludeClosed: true, IncludeMerged: true}); n != 9 {
+		t.Fatalf("dry run left %d jobs, want 9", n)
+	}
+
+	moved, err := d.ArchiveJobs(rules, now, false)
+	if err != nil || !reflect.DeepEqual(archived(moved), want) {
+		t.Fatalf("ArchiveJobs = %v, %v; want %v", archived(moved), err, want)
+	}
+	left, _ := d.ListJobs(JobFilter{IncludeClosed: true, IncludeMerged: true, Sort: SortOldest}, 1, 0)
+	var ids []int
+	for _, j := range left {
+		ids = append(ids, j.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{2, 3, 6, 7, 8, 9}) {
+		t.Errorf("jobs left = %v, want [2 3 6 7 8 9]", ids)
+	}
+	if n, err := d.CountArchivedJobs(); err != nil || n != 3 {
+		t.Errorf("CountArchivedJobs = %d, %v; want 3", n, err)
+	}
+	var profiles string
+	d.Conn.QueryRow(`SELECT profiles FROM job_archive WHERE id = 1`).Scan(&profiles)
+	if profiles != "new-grad" {
+		t.Errorf("archived profiles = %q, want new-grad", profiles)
+	}
+	if hits, _ := d.ListJobs(JobFilter{Search: "ancient", IncludeClosed: true}, 1, 0); len(hits) != 0 {
+		t.Errorf("search still finds archived job: %+v", hits)
+	}
+	if again, err := d.ArchiveJobs(rules, now, false); err != nil || len(again) != 0 {
+		t.Errorf("second run = %v, %v; want nothing", archived(again), err)
+	}
+
+	// Rolling the archive migration (4) back restores the archived jobs
+	if _, err := d.MigrateDown(len(mustMigrations(t)) - 3); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := d.CountJobs(JobFilter{IncludeClosed: true, IncludeMerged: true}); n != 9 {
+		t.Errorf("after down migration: %d jobs, want 9", n)
+	}
+}
+
+func TestUserJobs(t *testing.T) {
+	d := openTestDB(t)
+	for i := 1; i <= 3; i++ {
+		if err := d.InsertJobTyped(fmt.Sprintf("SWE %d", i), "Acme", "Remote", "Eng", fmt.Sprintf("https://x/%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	alice, bob := testUser(t, d, "alice"), testUser(t, d, "bob")
+
+	if uj, err := d.GetUserJob(alice, 1); err != nil || uj.Status != StatusNotApplied || uj.AppliedAt != nil {
+		t.Fatalf("untouched job = %+v, %v", uj, err)
+	}
+	if err := d.SetJobStatus(DefaultPipeline, alice, 1, "Applied"); err != nil {
+		t.Fatal(err)
+	}
+	uj, err := d.GetUserJob(alice, 1)
+	if err != nil || uj.Status != "Applied" || uj.AppliedAt == nil {
+		t.Fatalf("after SetJobStatus = %+v, %v", uj, err)
+	}
+	if err := d.SetJobStatus(DefaultPipeline, alice, 99, "Applied"); err != sql.ErrNoRows {
+		t.Errorf("SetJobStatus on a missing job = %v, want sql.ErrNoRows", err)
+	}
+	d.SetJobStarred(alice, 2, true)
+	d.SetJobHidden(alice, 3, true)
+
+	ids := func(f JobFilter) []int {
+		jobs, err := d.ListJobs(f, 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []int
+		for _, j := range jobs {
+			out = append(out, j.ID)
+		}
+		return out
+	}
+	if got := ids(JobFilter{UserID: alice, Sort: SortOldest}); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("alice's jobs = %v, want the hidden one left out", got)
+	}
+	if got := ids(JobFilter{UserID: alice, IncludeHidden: true, Sort: SortOldest}); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("with hidden = %v", got)
+	}
+	if got := ids(JobFilter{UserID: alice, Starred: true}); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("starred = %v", got)
+	}
+	if got := ids(JobFilter{UserID: bob, Sort: SortOldest}); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("bob's jobs = %v, alice's flags should not apply", got)
+	}
+
+	counts, err := d.CountJobsByStatus(JobFilter{UserID: alice})
+	if want := map[string]int{"Applied": 1, StatusNotApplied: 1}; err != nil || !reflect.DeepEqual(counts, want) {
+		t.Errorf("alice's counts = %v, %v; want %v", counts, err, want)
+	}
+	counts, err = d.CountJobsByStatus(JobFilter{UserID: bob})
+	if want := map[string]int{StatusNotApplied: 3}; err != nil || !reflect.DeepEqual(counts, want) {
+		t.Errorf("bob's counts = %v, %v; want %v", counts, err, want)
+	}
+}
+
+func TestMigrateUserJobs(t *testing.T) {
+	d := openTestDB(t)
+	d.InsertJobTyped("SWE", "Acme", "Remote", "Eng", "https://x/1")
+	d.InsertJobTyped("PM", "Acme", "Remote", "Eng", "https://x/2")
+	alice, admin := testUser(t, d, "alice"), testUser(t, d, "admin")
+	d.SetJobStatus(DefaultPipeline, admin, 2, "Saved")
+
+	// Back to one global status, which is the admin's: undo migration 5
+	// and everything after it
+	if _, err := d.MigrateDown(len(mustMigrations(t)) - 4); err != nil {
+		t.Fatal(err)
+	}
+	var status string
+	d.Conn.QueryRow(`SELECT status FROM job_applications WHERE id = 2`).Scan(&status)
+	if status != "Saved" {
+		t.Fatalf("global status after down = %q, want the admin's", status)
+	}
+	d.Conn.Exec(`UPDATE job_applications SET status = 'Applied' WHERE id = 1`)
+
+	if _, err := d.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	counts, err := d.CountJobsByStatus(JobFilter{UserID: admin})
+	if want := map[string]int{"Applied": 1, "Saved": 1}; err != nil || !reflect.DeepEqual(counts, want) {
+		t.Errorf("admin's counts = %v, %v; want %v", counts, err, want)
+	}
+	if counts, _ := d.CountJobsByStatus(JobFilter{UserID: alice}); counts[StatusNotApplied] != 2 {
+		t.Errorf("alice's counts = %v, want every job Not Applied", counts)
+	}
+	// Statuses from before the history count as one change each
+	if h, err := d.ListStatusHistory(admin, 1); err != nil || len(h) != 1 || h[0].From != StatusNotApplied || h[0].To != "Applied" {
+		t.Errorf("migrated history = %+v, %v", h, err)
+	}
+}
+
+func TestMigrateUserJobsWithoutUsers(t *testing.T) {
+	d := openTestDB(t)
+	d.InsertJobTyped("SWE", "Acme", "Remote", "Eng", "https://x/1")
+	if _, err := d.MigrateDown(len(mustMigrations(t)) - 4); err != nil {
+		t.Fatal(err)
+	}
+	// Statuses set before there were any accounts
+	d.Conn.Exec(`UPDATE job_applications SET status = 'Applied' WHERE id = 1`)
+
+	if _, err := d.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	admin, _, _, err := d.GetUserByUsername("admin")
+	if err != nil {
+		t.Fatalf("no admin created to own the statuses: %v", err)
+	}
+	if ok, err := d.AuthenticateUser("admin", "password123"); err != nil || !ok {
+		t.Errorf("default admin login = %v, %v; want true", ok, err)
+	}
+	if uj, err := d.GetUserJob(admin, 1); err != nil || uj.Status != "Applied" {
+		t.Errorf("admin's status = %+v, %v; want Applied", uj, err)
+	}
+}
+
+func TestSetJobStatusPipeline(t *testing.T) {
+	d := openTestDB(t)
+	d.InsertJobTyped("SWE", "Acme", "Remote", "Eng", "https://x/1")
+	alice := testUser(t, d, "alice")
+
+	for _, to := range []string{"Saved", "Applied", "OA", "Onsite", "Offer"} {
+		if err := d.SetJobStatus(DefaultPipeline, alice, 1, to); err != nil {
+			t.Fatalf("move to %s: %v", to, err)
+		}
+	}
+	err := d.SetJobStatus(DefaultPipeline, alice, 1, StatusNotApplied)
+	var terr *TransitionError
+	if !errors.As(err, &terr) || terr.From != "Offer" || !reflect.DeepEqual(terr.Allowed, []string{"Rejected", "Withdrawn", "Onsite"}) {
+		t.Fatalf("Offer → Not Applied = %v, want a TransitionError", err)
+	}
+	if uj, _ := d.GetUserJob(alice, 1); uj.Status != "Offer" {
+		t.Errorf("status after a refused move = %q, want Offer", uj.Status)
+	}
+	d.SetJobStatus(DefaultPipeline, alice, 1, "Withdrawn")
+	if err := d.SetJobStatus(DefaultPipeline, alice, 1, "Applied"); err == nil {
+		t.Error("moved out of the final Withdrawn stage")
+	}
+
+	h, err := d.ListStatusHistory(alice, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range h {
+		got = append(got, c.From+" → "+c.To)
+	}
+	want := []string{"Not Applied → Saved", "Saved → Applied", "Applied → OA", "OA → Onsite", "Onsite → Offer", "Offer → Withdrawn"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+
+	// Statuses outside the pipeline, e.g. from an older config, can move anywhere
+	free := Pipeline{Stages: []string{"Applied", "Hired"}}
+	if err := free.Check("Interviewing", "Hired"); err != nil {
+		t.Errorf("move from an unknown status: %v", err)
+	}
+	if err := free.Check(StatusNotApplied, "Hired"); err != nil {
+		t.Errorf("pipeline without transitions refused a move: %v", err)
+	}
+}
+
+func TestUndoStatusChange(t *testing.T) {
+	d := openTestDB(t)
+	d.InsertJobTyped("SWE", "Acme", "Remote", "Eng", "https://x/1")
+	d.InsertJobTyped("PM", "Acme", "Remote", "Eng", "https://x/2")
+	alice := testUser(t, d, "alice")
+
+	// A mistaken Rejected goes back to where it came from, and only there
+	for _, to := range []string{"Applied", "OA", "Rejected"} {
+		if err := d.SetJobStatus(DefaultPipeline, alice, 1, to); err != nil {
+			t.Fatalf("move to %s: %v", to, err)
+		}
+	}
+	err := d.SetJobStatus(DefaultPipeline, alice, 1, "Applied")
+	var terr *TransitionError
+	if !errors.As(err, &terr) || !reflect.DeepEqual(terr.Allowed, []string{"OA"}) {
+		t.Errorf("Rejected → Applied = %v, want a TransitionError allowing only OA", err)
+	}
+	if err := d.SetJobStatus(DefaultPipeline, alice, 1, "OA"); err != nil {
+		t.Fatalf("undo Rejected: %v", err)
+	}
+	if uj, _ := d.GetUserJob(alice, 1); uj.Status != "OA" {
+		t.Errorf("status after undo = %q, want OA", uj.Status)
+	}
+	if h, _ := d.ListStatusHistory(alice, 1); len(h) != 4 || h[3].From != "Rejected" || h[3].To != "OA" {
+		t.Errorf("history = %+v, want the undo recorded", h)
+	}
+
+	d.SetJobStatus(DefaultPipeline, alice, 2, "Saved")
+	last, err := d.LastStatusChanges(alice, []int{1, 2})
+	if err != nil || len(last) != 2 || last[1].To != "OA" || last[2].To != "Saved" {
+		t.Fatalf("LastStatusChanges = %v, %v", last, err)
+	}
+	// Undoing is offered where the pipeline would not allow the move
+	if next := DefaultPipeline.NextAfter("Rejected", &StatusChange{From: "Onsite", To: "Rejected"}); !reflect.DeepEqual(next, []string{"Onsite"}) {
+		t.Errorf("NextAfter(Rejected) = %v, want [Onsite]", next)
+	}
+	if next := DefaultPipeline.NextAfter("Saved", last[2]); !reflect.DeepEqual(next, DefaultPipeline.Next("Saved")) {
+		t.Errorf("NextAfter(Saved) = %v, want the pipeline's moves", next)
+	}
+}
+
+func TestJobNotesTagsAttachments(t *testing.T) {
+	d := openTestDB(t)
+	for i := 1; i <= 3; i++ {
+		d.InsertJobTyped(fmt.Sprintf("SWE %d", i), "Acme", "Remote", "Eng", fmt.Sprintf("https://x/%d", i))
+	}
+	alice, bob := testUser(t, d, "alice"), testUser(t, d, "bob")
+
+	id, err := d.AddJobNote(alice, 1, "Recruiter: **Sam**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddJobNote(alice, 99, "x"); err != sql.ErrNoRows {
+		t.Errorf("note on a missing job = %v, want sql.ErrNoRows", err)
+	}
+	if err := d.DeleteJobNote(bob, id); err != sql.ErrNoRows {
+		t.Errorf("bob deleting alice's note = %v, want sql.ErrNoRows", err)
+	}
+	if notes, err := d.ListJobNotes(alice, 1); err != nil || len(notes) != 1 || notes[0].Body != "Recruiter: **Sam**" {
+		t.Fatalf("ListJobNotes = %+v, %v", notes, err)
+	}
+	if err := d.DeleteJobNote(alice, id); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tag := range []string{"Referral ", "referral", "big  tech"} {
+		if err := d.AddJobTag(alice, 1, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.AddJobTag(alice, 2, "big tech")
+	d.AddJobTag(bob, 3, "referral")
+	if err := d.AddJobTag(alice, 1, "a,b"); err != ErrBadTag {
+		t.Errorf("tag with a comma = %v, want ErrBadTag", err)
+	}
+	if tags, _ := d.ListJobTags(alice, 1); !reflect.DeepEqual(tags, []string{"big tech", "referral"}) {
+		t.Errorf("ListJobTags = %q", tags)
+	}
+	jobs, err := d.ListJobs(JobFilter{UserID: alice, Tags: []string{"Referral"}}, 1, 0)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != 1 || !reflect.DeepEqual(jobs[0].Tags, []string{"big tech", "referral"}) {
+		t.Errorf("jobs tagged referral = %+v, %v", jobs, err)
+	}
+	if n, _ := d.CountJobs(JobFilter{UserID: alice, Tags: []string{"big tech", "referral"}}); n != 2 {
+		t.Errorf("jobs with either tag = %d, want 2", n)
+	}
+	if err := d.DeleteJobTag(alice, 1, "referral"); err != nil {
+		t.Fatal(err)
+	}
+	if tags, _ := d.ListUserTags(alice); !reflect.DeepEqual(tags, []string{"big tech"}) {
+		t.Errorf("ListUserTags = %q", tags)
+	}
+
+	aid, err := d.AddJobAttachment(alice, 2, "resume.pdf", "application/pdf", []byte("%PDF-1.4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := d.ListJobAttachments(alice, 2); len(list) != 1 || list[0].Size != 8 || list[0].Data != nil {
+		t.Errorf("ListJobAttachments = %+v", list)
+	}
+	if _, err := d.GetJobAttachment(bob, aid); err != sql.ErrNoRows {
+		t.Errorf("bob reading alice's attachment = %v, want sql.ErrNoRows", err)
+	}
+	if a, err := d.GetJobAttachment(alice, aid); err != nil || string(a.Data) != "%PDF-1.4" {
+		t.Errorf("GetJobAttachment = %+v, %v", a, err)
+	}
+
+	// Tagged jobs and jobs with files are kept by retention rules
+	d.Conn.Exec(`UPDATE job_applications SET first_seen_at = '2020-01-01 00:00:00'`)
+	moved, err := d.ArchiveJobs([]ArchiveRule{{Name: "old", Age: 24 * time.Hour}}, time.Now(), false)
+	if err != nil || len(moved) != 0 {
+		t.Errorf("ArchiveJobs = %+v, %v; want every job kept", moved, err)
+	}
+	if err := d.DeleteJobAttachment(alice, aid); err != nil {
+		t.Fatal(err)
+	}
+}
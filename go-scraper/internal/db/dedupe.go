@@ -0,0 +1,217 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/dedupe"
+)
+
+// canonicalizeURLs rewrites stored URLs into canonical form. Rows whose
+// canonical URL is already taken by another row are merged into it instead.
+func canonicalizeURLs(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, url FROM job_applications ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	type row struct {
+		id  int
+		url string
+	}
+	var all []row
+	for rows.Next() {
+		var r row
+		var url sql.NullString
+		if err := rows.Scan(&r.id, &url); err != nil {
+			rows.Close()
+			return err
+		}
+		r.url = url.String
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	owner := make(map[string]int, len(all))
+	for _, r := range all {
+		owner[r.url] = r.id
+	}
+	for _, r := range all {
+		canon := dedupe.CanonicalURL(r.url)
+		if canon == r.url {
+			continue
+		}
+		if id, ok := owner[canon]; ok && id != r.id {
+			if _, err := tx.Exec(`UPDATE job_applications SET merged_into = $1 WHERE id = $2`, id, r.id); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE job_applications SET url = $1 WHERE id = $2`, canon, r.id); err != nil {
+			return err
+		}
+		delete(owner, r.url)
+		owner[canon] = r.id
+	}
+	return nil
+}
+
+// MergeDuplicates merges open jobs that look like the same role listed more
+// than once: same company and location with near-identical titles. The
+// oldest row, or one a user has already acted on, becomes the canonical
+// job and the others point at it through merged_into, along with any
+// listings already merged into them. Postings from the same board with
+// different board IDs are separate roles and are never merged, and rows a
+// user has split apart with UnmergeJob are never merged again. It returns
+// the number of rows merged.
+func (d *DB) MergeDuplicates() (int, error) {
+	// Reading the rows in the same transaction as the merges keeps a
+	// scrape that writes in between from being merged on stale data
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Board IDs of the listings already merged into each job
+	children := map[int][]listing{}
+	rows, err := tx.Query(`
+	SELECT merged_into, COALESCE(source, ''), COALESCE(source_job_id, '')
+	FROM job_applications WHERE merged_into IS NOT NULL`)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var parent int
+		var l listing
+		if err := rows.Scan(&parent, &l.source, &l.sourceJobID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		children[parent] = append(children[parent], l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	rows, err = tx.Query(`
+	SELECT id, title, company, location, merge_locked, COALESCE(source, ''), COALESCE(source_job_id, '')
+	FROM job_applications j
+	WHERE merged_into IS NULL AND closed_at IS NULL
+	ORDER BY ` + hasUserState("j") + ` DESC, id`)
+	if err != nil {
+		return 0, err
+	}
+	type candidate struct {
+		id       int
+		title    string
+		locked   bool
+		listings []listing // the row and the listings merged into it
+	}
+	groups := map[string][]*candidate{}
+	for rows.Next() {
+		var (
+			c                   candidate
+			l                   listing
+			title, company, loc sql.NullString
+		)
+		if err := rows.Scan(&c.id, &title, &company, &loc, &c.locked, &l.source, &l.sourceJobID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		c.title = title.String
+		c.listings = append([]listing{l}, children[c.id]...)
+		key := dedupe.GroupKey(company.String, loc.String)
+		groups[key] = append(groups[key], &c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	merged := 0
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		// Each row is either a canonical job or merged into the first
+		// earlier canonical with a similar title. Locked rows stay apart
+		// and are not merge targets either.
+		var canon []*candidate
+		for _, c := range group {
+			if c.locked {
+				continue
+			}
+			var target *candidate
+			for _, k := range canon {
+				if dedupe.SimilarTitles(k.title, c.title) && !distinctPostings(k.listings, c.listings) {
+					target = k
+					break
+				}
+			}
+			if target == nil {
+				canon = append(canon, c)
+				continue
+			}
+			// Listings merged into c move to the new canonical job too, so
+			// merged_into never points at a merged row
+			if _, err := tx.Exec(`UPDATE job_applications SET merged_into = $1 WHERE id = $2 OR merged_into = $2`, target.id, c.id); err != nil {
+				return 0, err
+			}
+			target.listings = append(target.listings, c.listings...)
+			merged++
+		}
+	}
+	return merged, tx.Commit()
+}
+
+// listing is the board a row was scraped from and its ID there.
+type listing struct {
+	source, sourceJobID string
+}
+
+// distinctPostings reports whether two sets of listings hold different
+// postings from the same board, which are separate roles however similar
+// their titles.
+func distinctPostings(a, b []listing) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.source != "" && x.source == y.source &&
+				x.sourceJobID != "" && y.sourceJobID != "" && x.sourceJobID != y.sourceJobID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UnmergeJob splits a merged row back out into its own job and stops it
+// from being merged automatically again. It returns sql.ErrNoRows when
+// there is no such job.
+func (d *DB) UnmergeJob(id int) error {
+	return affectedOne(d.Conn.Exec(`UPDATE job_applications SET merged_into = NULL, merge_locked = 1 WHERE id = $1`, id))
+}
+
+// ListJobSources returns the duplicate listings merged into a job.
+func (d *DB) ListJobSources(id int) ([]Job, error) {
+	rows, err := d.Read.Query(`
+	SELECT id, title, company, location, url, COALESCE(source, ''), closed_at
+	FROM job_applications WHERE merged_into = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var job Job
+		if err := rows.Scan(&job.ID, &job.Title, &job.Company, &job.Location, &job.URL, &job.Source, &job.ClosedAt); err != nil {
+			return nil, err
+		}
+		job.MergedInto = &id
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
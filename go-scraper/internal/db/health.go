@@ -0,0 +1,158 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CompanyHealth is the scrape history of one company board.
+type CompanyHealth struct {
+	Platform            string     `json:"platform"`
+	Slug                string     `json:"slug"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	NextAttemptAt       *time.Time `json:"next_attempt_at,omitempty"` // failing boards are skipped until then
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`     // set once the board has failed too often
+}
+
+// Backoff decides how long a failing company is skipped: Base after the
+// first failure, doubling with each further one up to Max. After
+// DisableAfter consecutive failures (0 = never) the company is disabled
+// until re-enabled by hand.
+type Backoff struct {
+	Base         time.Duration
+	Max          time.Duration
+	DisableAfter int
+}
+
+// Delay returns how long to wait after the given number of consecutive
+// failures.
+func (b Backoff) Delay(failures int) time.Duration {
+	if failures <= 0 || b.Base <= 0 {
+		return 0
+	}
+	delay := b.Base
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if b.Max > 0 && delay >= b.Max {
+			return b.Max
+		}
+	}
+	if b.Max > 0 && delay > b.Max {
+		return b.Max
+	}
+	return delay
+}
+
+const healthColumns = `platform, slug, consecutive_failures, COALESCE(last_error, ''),
+	last_failure_at, last_success_at, next_attempt_at, disabled_at`
+
+func scanHealth(row interface{ Scan(...interface{}) error }) (CompanyHealth, error) {
+	var h CompanyHealth
+	err := row.Scan(&h.Platform, &h.Slug, &h.ConsecutiveFailures, &h.LastError,
+		&h.LastFailureAt, &h.LastSuccessAt, &h.NextAttemptAt, &h.DisabledAt)
+	return h, err
+}
+
+// GetCompanyHealth returns the scrape history of a company. Companies that
+// have never been recorded are returned healthy.
+func (d *DB) GetCompanyHealth(platform, slug string) (CompanyHealth, error) {
+	h, err := scanHealth(d.Read.QueryRow(`SELECT `+healthColumns+` FROM company_health
+		WHERE platform = $1 AND slug = $2`, platform, slug))
+	if err == sql.ErrNoRows {
+		return CompanyHealth{Platform: platform, Slug: slug}, nil
+	}
+	return h, err
+}
+
+// RecordScrapeSuccess resets a company's failure count.
+func (d *DB) RecordScrapeSuccess(platform, slug string) error {
+	_, err := d.Conn.Exec(`INSERT INTO company_health(platform, slug, last_success_at) VALUES($1,$2,$3)
+		ON CONFLICT (platform, slug) DO UPDATE SET
+			consecutive_failures = 0,
+			last_success_at = excluded.last_success_at,
+			next_attempt_at = NULL`,
+		platform, slug, sqliteTime(time.Now()))
+	return err
+}
+
+// RecordScrapeFailure counts a failed scrape, schedules the next attempt
+// according to b and disables the company once it has failed
+// b.DisableAfter times in a row. It returns the updated health.
+func (d *DB) RecordScrapeFailure(platform, slug, msg string, b Backoff) (CompanyHealth, error) {
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return CompanyHealth{}, err
+	}
+	defer tx.Rollback()
+
+	var failures int
+	err = tx.QueryRow(`SELECT consecutive_failures FROM company_health WHERE platform = $1 AND slug = $2`,
+		platform, slug).Scan(&failures)
+	if err != nil && err != sql.ErrNoRows {
+		return CompanyHealth{}, err
+	}
+	failures++
+
+	now := time.Now()
+	var disabled interface{}
+	if b.DisableAfter > 0 && failures >= b.DisableAfter {
+		disabled = sqliteTime(now)
+	}
+	_, err = tx.Exec(`INSERT INTO company_health(platform, slug, consecutive_failures, last_error,
+			last_failure_at, next_attempt_at, disabled_at)
+		VALUES($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (platform, slug) DO UPDATE SET
+			consecutive_failures = excluded.consecutive_failures,
+			last_error = excluded.last_error,
+			last_failure_at = excluded.last_failure_at,
+			next_attempt_at = excluded.next_attempt_at,
+			disabled_at = COALESCE(company_health.disabled_at, excluded.disabled_at)`,
+		platform, slug, failures, msg, sqliteTime(now), sqliteTime(now.Add(b.Delay(failures))), disabled)
+	if err != nil {
+		return CompanyHealth{}, err
+	}
+	h, err := scanHealth(tx.QueryRow(`SELECT `+healthColumns+` FROM company_health
+		WHERE platform = $1 AND slug = $2`, platform, slug))
+	if err != nil {
+		return h, err
+	}
+	return h, tx.Commit()
+}
+
+// ListCompanyHealth returns every company that has failed at least once
+// since its last success, disabled ones first.
+func (d *DB) ListCompanyHealth() ([]CompanyHealth, error) {
+	rows, err := d.Read.Query(`SELECT ` + healthColumns + ` FROM company_health
+		WHERE consecutive_failures > 0 OR disabled_at IS NOT NULL
+		ORDER BY disabled_at IS NULL, platform, slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CompanyHealth
+	for rows.Next() {
+		h, err := scanHealth(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// EnableCompany re-enables a disabled company and clears its failures. It
+// returns false if the company had no failures recorded.
+func (d *DB) EnableCompany(platform, slug string) (bool, error) {
+	res, err := d.Conn.Exec(`UPDATE company_health
+		SET consecutive_failures = 0, next_attempt_at = NULL, disabled_at = NULL
+		WHERE platform = $1 AND slug = $2`, platform, slug)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
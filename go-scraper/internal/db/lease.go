@@ -0,0 +1,26 @@
+package db
+
+import "time"
+
+// AcquireLease takes or renews the named lease for holder until ttl from
+// now. It returns false if another holder has an unexpired lease.
+func (d *DB) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	q := `INSERT INTO leases(name, holder, expires_at) VALUES($1,$2,$3)
+			 ON CONFLICT (name) DO UPDATE SET
+				holder = excluded.holder,
+				expires_at = excluded.expires_at
+			 WHERE leases.holder = excluded.holder OR leases.expires_at < $4`
+	res, err := d.Conn.Exec(q, name, holder, sqliteTime(now.Add(ttl)), sqliteTime(now))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReleaseLease gives up the named lease if holder still owns it.
+func (d *DB) ReleaseLease(name, holder string) error {
+	_, err := d.Conn.Exec(`DELETE FROM leases WHERE name = $1 AND holder = $2`, name, holder)
+	return err
+}
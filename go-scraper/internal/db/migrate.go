@@ -0,0 +1,396 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Schema changes are numbered migrations, applied in order and recorded in
+// schema_migrations. Most are SQL files embedded from
+// migrations/NNNN_name.up.sql (and an optional .down.sql); those that need
+// Go code are listed in goMigrations. Each runs in its own transaction.
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is one numbered schema change.
+type Migration struct {
+	Version int
+	Name    string
+
+	upSQL, downSQL string
+	up, down       func(tx *sql.Tx) error // used instead of the SQL when set
+}
+
+// Reversible reports whether the migration can be rolled back.
+func (m Migration) Reversible() bool {
+	return m.down != nil || m.downSQL != ""
+}
+
+// goMigrations are the migrations written in Go, merged with the SQL files
+// by version.
+var goMigrations = []Migration{
+	{Version: 2, Name: "legacy_columns", up: upgradeLegacyColumns},
+}
+
+// ErrSchemaTooNew is returned when the database has migrations this binary
+// does not know, i.e. it was migrated by a newer version.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// ErrSchemaPending is returned by ConnectCurrent when the database has
+// migrations this binary would apply.
+var ErrSchemaPending = errors.New("database schema has pending migrations")
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migrations returns every known migration, oldest first.
+func Migrations() ([]Migration, error) {
+	byVersion := map[int]*Migration{}
+	get := func(version int, name string) (*Migration, error) {
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d is named both %q and %q", version, m.Name, name)
+		}
+		return m, nil
+	}
+
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		match := migrationFileRe.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrations/%s: name must be NNNN_name.up.sql or NNNN_name.down.sql", e.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		m, err := get(version, match[2])
+		if err != nil {
+			return nil, err
+		}
+		body, err := migrationFiles.ReadFile("migrations/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			m.upSQL = string(body)
+		} else {
+			m.downSQL = string(body)
+		}
+	}
+	for _, g := range goMigrations {
+		m, err := get(g.Version, g.Name)
+		if err != nil {
+			return nil, err
+		}
+		if g.up != nil {
+			m.up = g.up
+		}
+		if g.down != nil {
+			m.down = g.down
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == nil && m.upSQL == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up step", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	for i, m := range out {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d_%s: versions must run 1, 2, 3... without gaps", m.Version, m.Name)
+		}
+	}
+	return out, nil
+}
+
+// MigrationStatus is a known migration and when it was applied, if it was.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+func (d *DB) createMigrationTable() error {
+	_, err := d.Conn.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// SchemaVersion returns the highest applied migration, 0 for a new database.
+func (d *DB) SchemaVersion() (int, error) {
+	if err := d.createMigrationTable(); err != nil {
+		return 0, err
+	}
+	var v int
+	err := d.Conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v)
+	return v, err
+}
+
+// MigrationStatus lists every known migration with when it was applied.
+func (d *DB) MigrationStatus() ([]MigrationStatus, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.createMigrationTable(); err != nil {
+		return nil, err
+	}
+	rows, err := d.Conn.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		applied[v] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]MigrationStatus, len(all))
+	for i, m := range all {
+		out[i].Migration = m
+		if at, ok := applied[m.Version]; ok {
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// PendingMigrations returns the migrations MigrateUp would apply, without
+// writing anything: a new database has no schema_migrations table yet. It
+// returns ErrSchemaTooNew when the database is ahead of this binary.
+func (d *DB) PendingMigrations() ([]Migration, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	var tables int
+	if err := d.Conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&tables); err != nil {
+		return nil, err
+	}
+	current := 0
+	if tables > 0 {
+		if err := d.Conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+			return nil, err
+		}
+	}
+	if current > len(all) {
+		return nil, fmt.Errorf("%w: database is at version %d, this binary knows up to %d", ErrSchemaTooNew, current, len(all))
+	}
+	return all[current:], nil
+}
+
+// MigrateUp applies every pending migration and returns those it applied.
+// It returns ErrSchemaTooNew, changing nothing, when the database is ahead
+// of this binary.
+func (d *DB) MigrateUp() ([]Migration, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	current, err := d.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > len(all) {
+		return nil, fmt.Errorf("%w: database is at version %d, this binary knows up to %d", ErrSchemaTooNew, current, len(all))
+	}
+	var applied []Migration
+	for _, m := range all[current:] {
+		ok, err := d.applyMigration(m, true)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// MigrateDown rolls back the latest steps migrations and returns those it
+// rolled back, newest first.
+func (d *DB) MigrateDown(steps int) ([]Migration, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	current, err := d.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > len(all) {
+		return nil, fmt.Errorf("%w: database is at version %d, this binary knows up to %d", ErrSchemaTooNew, current, len(all))
+	}
+	var undone []Migration
+	for v := current; v > 0 && len(undone) < steps; v-- {
+		m := all[v-1]
+		if !m.Reversible() {
+			return undone, fmt.Errorf("migration %d_%s cannot be rolled back", m.Version, m.Name)
+		}
+		if _, err := d.applyMigration(m, false); err != nil {
+			return undone, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		undone = append(undone, m)
+	}
+	return undone, nil
+}
+
+// applyMigration runs one migration up or down in a transaction. Claiming
+// the version first takes SQLite's write lock, so when two processes start
+// at once only one applies it; the other reports false.
+func (d *DB) applyMigration(m Migration, up bool) (bool, error) {
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if up {
+		res, err := tx.Exec(`INSERT OR IGNORE INTO schema_migrations(version, name) VALUES($1,$2)`, m.Version, m.Name)
+		if err != nil {
+			return false, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return false, err
+		}
+		if m.up != nil {
+			err = m.up(tx)
+		} else {
+			_, err = tx.Exec(m.upSQL)
+		}
+		if err != nil {
+			return false, err
+		}
+	} else {
+		if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+			return false, err
+		}
+		if m.down != nil {
+			err = m.down(tx)
+		} else {
+			_, err = tx.Exec(m.downSQL)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// upgradeLegacyColumns brings job_applications and companies tables created
+// before versioned migrations up to the migration 1 schema, then adds the
+// indexes on those columns. It changes nothing else on newer databases.
+func upgradeLegacyColumns(tx *sql.Tx) error {
+	// SQLite cannot add a column with a CURRENT_TIMESTAMP default, so
+	// lifecycle timestamps are backfilled from date_added instead.
+	for _, col := range []string{"first_seen_at", "last_seen_at", "closed_at"} {
+		if _, err := ensureColumn(tx, "job_applications", col, "DATETIME"); err != nil {
+			return err
+		}
+	}
+	if _, err := ensureColumn(tx, "job_applications", "description", "TEXT"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+	UPDATE job_applications
+	SET first_seen_at = COALESCE(first_seen_at, date_added),
+	    last_seen_at = COALESCE(last_seen_at, date_added)
+	WHERE first_seen_at IS NULL OR last_seen_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Source identity and merge columns arrived with cross-source dedupe;
+	// URLs stored before then were not canonicalized.
+	added, err := ensureColumn(tx, "job_applications", "source", "TEXT")
+	if err != nil {
+		return err
+	}
+	for _, col := range []struct{ table, name, decl string }{
+		{"job_applications", "source_job_id", "TEXT"},
+		{"job_applications", "merged_into", "INTEGER REFERENCES job_applications(id) ON DELETE SET NULL"},
+		{"job_applications", "merge_locked", "INTEGER NOT NULL DEFAULT 0"},
+		{"job_applications", "company_id", "INTEGER REFERENCES companies(id)"},
+		{"companies", "industry", "TEXT"},
+		{"companies", "team_size", "INTEGER"},
+		{"companies", "yc_status", "TEXT"},
+		{"companies", "yc_batch_key", "INTEGER"},
+	} {
+		if _, err := ensureColumn(tx, col.table, col.name, col.decl); err != nil {
+			return err
+		}
+	}
+	if added {
+		if err := canonicalizeURLs(tx); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`
+	CREATE INDEX IF NOT EXISTS idx_job_company_id ON job_applications(company_id);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_job_source
+		ON job_applications(source, source_job_id) WHERE source_job_id IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_job_merged_into ON job_applications(merged_into);
+	`)
+	return err
+}
+
+// ensureColumn adds a column to table when an older database lacks it and
+// reports whether it did.
+func ensureColumn(tx *sql.Tx, table, column, decl string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid     int
+			name    string
+			typ     string
+			notNull int
+			dflt    sql.NullString
+			pk      int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl))
+	return err == nil, err
+}
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS company_health;
+DROP TABLE IF EXISTS leases;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS job_profiles;
+DROP TABLE IF EXISTS job_versions;
+DROP TABLE IF EXISTS job_applications;
+DROP TABLE IF EXISTS companies;
@@ -0,0 +1,86 @@
+-- The schema as of the first versioned release. Tables use IF NOT EXISTS
+-- so databases created before migrations existed adopt it; columns they
+-- lack are added by migration 2.
+
+CREATE TABLE IF NOT EXISTS job_applications (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT,
+	company TEXT,
+	location TEXT,
+	salary TEXT,
+	type TEXT,
+	url TEXT UNIQUE,
+	date_added DATETIME DEFAULT CURRENT_TIMESTAMP,
+	status TEXT DEFAULT 'Not Applied',
+	first_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	closed_at DATETIME,
+	description TEXT,
+	source TEXT,
+	source_job_id TEXT,
+	merged_into INTEGER REFERENCES job_applications(id) ON DELETE SET NULL,
+	merge_locked INTEGER NOT NULL DEFAULT 0,
+	company_id INTEGER REFERENCES companies(id)
+);
+
+CREATE TABLE IF NOT EXISTS job_versions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	job_id INTEGER NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+	changed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	changes TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_job_versions_job ON job_versions(job_id, changed_at);
+
+CREATE TABLE IF NOT EXISTS job_profiles (
+	job_id INTEGER NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+	profile TEXT NOT NULL,
+	PRIMARY KEY (job_id, profile)
+);
+CREATE INDEX IF NOT EXISTS idx_job_profiles_profile ON job_profiles(profile);
+
+-- yc_batch_key orders batches chronologically for "W24 or later" filters.
+CREATE TABLE IF NOT EXISTS companies (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	platform TEXT NOT NULL,
+	slug TEXT NOT NULL,
+	name TEXT NOT NULL,
+	website TEXT,
+	logo_url TEXT,
+	tags TEXT,
+	yc_batch TEXT,
+	notes TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	industry TEXT,
+	team_size INTEGER,
+	yc_status TEXT,
+	yc_batch_key INTEGER,
+	UNIQUE (platform, slug)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Keeps scraper instances from overlapping.
+CREATE TABLE IF NOT EXISTS leases (
+	name TEXT PRIMARY KEY,
+	holder TEXT NOT NULL,
+	expires_at DATETIME NOT NULL
+);
+
+-- Scrape failures per company board.
+CREATE TABLE IF NOT EXISTS company_health (
+	platform TEXT NOT NULL,
+	slug TEXT NOT NULL,
+	consecutive_failures INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	last_failure_at DATETIME,
+	last_success_at DATETIME,
+	next_attempt_at DATETIME,
+	disabled_at DATETIME,
+	PRIMARY KEY (platform, slug)
+);
@@ -0,0 +1,5 @@
+-- The columns added by the up migration are part of migration 1's tables,
+-- so only the indexes are dropped.
+DROP INDEX IF EXISTS idx_job_merged_into;
+DROP INDEX IF EXISTS idx_job_source;
+DROP INDEX IF EXISTS idx_job_company_id;
@@ -0,0 +1,26 @@
+-- Full-text index over job titles, companies, locations and descriptions,
+-- kept in sync with job_applications by triggers.
+CREATE VIRTUAL TABLE job_search USING fts5(
+	title, company, location, description,
+	content='job_applications', content_rowid='id',
+	tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER job_search_insert AFTER INSERT ON job_applications BEGIN
+	INSERT INTO job_search(rowid, title, company, location, description)
+	VALUES (new.id, new.title, new.company, new.location, new.description);
+END;
+
+CREATE TRIGGER job_search_delete AFTER DELETE ON job_applications BEGIN
+	INSERT INTO job_search(job_search, rowid, title, company, location, description)
+	VALUES ('delete', old.id, old.title, old.company, old.location, old.description);
+END;
+
+CREATE TRIGGER job_search_update AFTER UPDATE OF title, company, location, description ON job_applications BEGIN
+	INSERT INTO job_search(job_search, rowid, title, company, location, description)
+	VALUES ('delete', old.id, old.title, old.company, old.location, old.description);
+	INSERT INTO job_search(rowid, title, company, location, description)
+	VALUES (new.id, new.title, new.company, new.location, new.description);
+END;
+
+INSERT INTO job_search(job_search) VALUES ('rebuild');
@@ -0,0 +1,9 @@
+-- Archived jobs go back to job_applications unless a job with the same id
+-- or URL has been scraped since.
+INSERT OR IGNORE INTO job_applications(id, title, company, location, salary, type, url, date_added, status,
+	first_seen_at, last_seen_at, closed_at, description, source, source_job_id, merged_into, company_id)
+SELECT id, title, company, location, salary, type, url, date_added, status,
+	first_seen_at, last_seen_at, closed_at, description, source, source_job_id, merged_into, company_id
+FROM job_archive;
+DROP INDEX IF EXISTS idx_job_closed_at;
+DROP TABLE IF EXISTS job_archive;
@@ -0,0 +1,27 @@
+-- Jobs moved out of job_applications by retention rules, keeping their id.
+-- Their change history is dropped; search profiles are kept as a comma
+-- separated list.
+CREATE TABLE job_archive (
+	id INTEGER PRIMARY KEY,
+	title TEXT,
+	company TEXT,
+	location TEXT,
+	salary TEXT,
+	type TEXT,
+	url TEXT,
+	date_added DATETIME,
+	status TEXT,
+	first_seen_at DATETIME,
+	last_seen_at DATETIME,
+	closed_at DATETIME,
+	description TEXT,
+	source TEXT,
+	source_job_id TEXT,
+	merged_into INTEGER,
+	company_id INTEGER,
+	profiles TEXT,
+	rule TEXT NOT NULL,
+	archived_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_job_archive_archived_at ON job_archive(archived_at);
+CREATE INDEX idx_job_closed_at ON job_applications(closed_at);
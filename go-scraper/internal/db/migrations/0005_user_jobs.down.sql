@@ -0,0 +1,8 @@
+-- The admin's statuses (or the first user's) become the global ones again.
+ALTER TABLE job_applications ADD COLUMN status TEXT DEFAULT 'Not Applied';
+UPDATE job_applications SET status = (
+	SELECT u.status FROM user_jobs u JOIN users ON users.id = u.user_id
+	WHERE u.job_id = job_applications.id
+	ORDER BY users.username <> 'admin', users.id LIMIT 1)
+WHERE id IN (SELECT job_id FROM user_jobs);
+DROP TABLE user_jobs;
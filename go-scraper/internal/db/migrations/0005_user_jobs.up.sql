@@ -0,0 +1,30 @@
+-- Application state per user. A job without a row is "Not Applied" and
+-- unflagged for that user.
+CREATE TABLE user_jobs (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	job_id INTEGER NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+	status TEXT NOT NULL DEFAULT 'Not Applied',
+	applied_at DATETIME,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	starred INTEGER NOT NULL DEFAULT 0,
+	hidden INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, job_id)
+);
+CREATE INDEX idx_user_jobs_job ON user_jobs(job_id);
+
+-- The global statuses were set by whoever used the dashboard; they go to
+-- the admin user, or the first user when there is no admin. With statuses
+-- but no users at all, the dashboard's default admin (admin/password123,
+-- as autoInitialize would create it) is created to own them.
+INSERT INTO users(username, password_hash)
+SELECT 'admin', '$2a$10$0MrQQlsG0zsYkVP3vNGySurk5TovmpPD6WPFvOyNStPJLiXg6rYAC'
+WHERE NOT EXISTS (SELECT 1 FROM users)
+  AND EXISTS (SELECT 1 FROM job_applications WHERE COALESCE(status, 'Not Applied') <> 'Not Applied');
+
+-- When they were set is unknown.
+INSERT INTO user_jobs(user_id, job_id, status)
+SELECT (SELECT id FROM users ORDER BY username <> 'admin', id LIMIT 1), id, status
+FROM job_applications
+WHERE COALESCE(status, 'Not Applied') <> 'Not Applied';
+
+ALTER TABLE job_applications DROP COLUMN status;
@@ -0,0 +1,15 @@
+-- Every status change a user makes, oldest first by id.
+CREATE TABLE status_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	job_id INTEGER NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+	from_status TEXT NOT NULL,
+	to_status TEXT NOT NULL,
+	changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_status_history_job ON status_history(user_id, job_id);
+
+-- Statuses set before history was kept count as one change each
+INSERT INTO status_history(user_id, job_id, from_status, to_status, changed_at)
+SELECT user_id, job_id, 'Not Applied', status, COALESCE(applied_at, updated_at)
+FROM user_jobs WHERE status <> 'Not Applied';
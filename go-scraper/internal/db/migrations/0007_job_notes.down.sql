@@ -0,0 +1,3 @@
+DROP TABLE job_attachments;
+DROP TABLE job_tags;
+DROP TABLE job_notes;
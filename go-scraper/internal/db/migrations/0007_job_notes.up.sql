@@ -0,0 +1,29 @@
+-- A user's own notes, tags and files for a job.
+CREATE TABLE job_notes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	job_id INTEGER NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+	body TEXT NOT NULL, -- markdown
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_job_notes_job ON job_notes(user_id, job_id);
+
+CREATE TABLE job_tags (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	job_id INTEGER NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (user_id, job_id, tag)
+);
+CREATE INDEX idx_job_tags_tag ON job_tags(user_id, tag);
+
+CREATE TABLE job_attachments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	job_id INTEGER NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	data BLOB NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_job_attachments_job ON job_attachments(user_id, job_id);
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrBadTag is returned for a tag that is empty or contains a comma.
+var ErrBadTag = errors.New("tags must be non-empty and must not contain commas")
+
+// Note is a user's markdown note on a job.
+type Note struct {
+	ID        int       `json:"id"`
+	JobID     int       `json:"job_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Attachment is a file a user keeps with a job, such as the resume sent.
+// Data is only loaded by GetJobAttachment.
+type Attachment struct {
+	ID          int       `json:"id"`
+	JobID       int       `json:"job_id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	Data        []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AddJobNote adds a note to a job for a user and returns its ID. It fails
+// with sql.ErrNoRows if the job does not exist.
+func (d *DB) AddJobNote(userID, jobID int, body string) (int, error) {
+	if err := jobExists(d.Conn, jobID); err != nil {
+		return 0, err
+	}
+	res, err := d.Conn.Exec(`INSERT INTO job_notes(user_id, job_id, body) VALUES($1, $2, $3)`, userID, jobID, body)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// ListJobNotes returns a user's notes on a job, oldest first.
+func (d *DB) ListJobNotes(userID, jobID int) ([]Note, error) {
+	rows, err := d.Read.Query(`SELECT id, job_id, body, created_at FROM job_notes
+		WHERE user_id = $1 AND job_id = $2 ORDER BY id`, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Note
+	for rows.Next() {
+		var n Note
+		if err := rows.Scan(&n.ID, &n.JobID, &n.Body, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// DeleteJobNote deletes one of a user's notes. It fails with sql.ErrNoRows
+// if the user has no such note.
+func (d *DB) DeleteJobNote(userID, noteID int) error {
+	return deleteOwned(d, "job_notes", userID, noteID)
+}
+
+// NormalizeTag trims a tag, collapses its spaces and lower-cases it, so
+// "Referral " and "referral" are one tag.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	if tag == "" || strings.Contains(tag, ",") {
+		return "", ErrBadTag
+	}
+	return tag, nil
+}
+
+// AddJobTag tags a job for a user. Adding a tag twice is not an error.
+func (d *DB) AddJobTag(userID, jobID int, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	if err := jobExists(d.Conn, jobID); err != nil {
+		return err
+	}
+	_, err = d.Conn.Exec(`INSERT OR IGNORE INTO job_tags(user_id, job_id, tag) VALUES($1, $2, $3)`, userID, jobID, tag)
+	return err
+}
+
+// DeleteJobTag removes a tag from a job for a user. It fails with
+// sql.ErrNoRows if the job did not have the tag.
+func (d *DB) DeleteJobTag(userID, jobID int, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	res, err := d.Conn.Exec(`DELETE FROM job_tags WHERE user_id = $1 AND job_id = $2 AND tag = $3`, userID, jobID, tag)
+	return affectedOne(res, err)
+}
+
+// ListJobTags returns a user's tags on a job, sorted.
+func (d *DB) ListJobTags(userID, jobID int) ([]string, error) {
+	return d.listTags(`SELECT tag FROM job_tags WHERE user_id = $1 AND job_id = $2 ORDER BY tag`, userID, jobID)
+}
+
+// ListUserTags returns every tag a user has used, sorted.
+func (d *DB) ListUserTags(userID int) ([]string, error) {
+	return d.listTags(`SELECT DISTINCT tag FROM job_tags WHERE user_id = $1 ORDER BY tag`, userID)
+}
+
+func (d *DB) listTags(q string, args ...interface{}) ([]string, error) {
+	rows, err := d.Read.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// userTags is the comma-separated tags of the current job_applications
+// row for the user in placeholder arg, "" without any.
+func userTags(arg string) string {
+	return `COALESCE((SELECT group_concat(tag, ',') FROM (SELECT tag FROM job_tags jt
+		WHERE jt.job_id = job_applications.id AND jt.user_id = ` + arg + ` ORDER BY tag)), '')`
+}
+
+// splitTags undoes userTags. group_concat does not promise to keep the
+// subquery's order, so the tags are sorted again.
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	tags := strings.Split(s, ",")
+	sort.Strings(tags)
+	return tags
+}
+
+// AddJobAttachment stores a file with a job for a user and returns its ID.
+// It fails with sql.ErrNoRows if the job does not exist.
+func (d *DB) AddJobAttachment(userID, jobID int, name, contentType string, data []byte) (int, error) {
+	if err := jobExists(d.Conn, jobID); err != nil {
+		return 0, err
+	}
+	res, err := d.Conn.Exec(`INSERT INTO job_attachments(user_id, job_id, name, content_type, size, data)
+		VALUES($1, $2, $3, $4, $5, $6)`, userID, jobID, name, contentType, len(data), data)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// ListJobAttachments returns a user's attachments on a job without their
+// data, oldest first.
+func (d *DB) ListJobAttachments(userID, jobID int) ([]Attachment, error) {
+	rows, err := d.Read.Query(`SELECT id, job_id, name, content_type, size, created_at FROM job_attachments
+		WHERE user_id = $1 AND job_id = $2 ORDER BY id`, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.JobID, &a.Name, &a.ContentType, &a.Size, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// GetJobAttachment returns one of a user's attachments with its data. It
+// fails with sql.ErrNoRows if the user has no such attachment.
+func (d *DB) GetJobAttachment(userID, id int) (Attachment, error) {
+	var a Attachment
+	err := d.Read.QueryRow(`SELECT id, job_id, name, content_type, size, data, created_at FROM job_attachments
+		WHERE user_id = $1 AND id = $2`, userID, id).Scan(&a.ID, &a.JobID, &a.Name, &a.ContentType, &a.Size, &a.Data, &a.CreatedAt)
+	return a, err
+}
+
+// DeleteJobAttachment deletes one of a user's attachments. It fails with
+// sql.ErrNoRows if the user has no such attachment.
+func (d *DB) DeleteJobAttachment(userID, id int) error {
+	return deleteOwned(d, "job_attachments", userID, id)
+}
+
+// deleteOwned deletes the row of table with the ID if the user owns it.
+func deleteOwned(d *DB, table string, userID, id int) error {
+	res, err := d.Conn.Exec(fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1 AND id = $2`, table), userID, id)
+	return affectedOne(res, err)
+}
+
+// affectedOne turns a statement that changed nothing into sql.ErrNoRows.
+func affectedOne(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Job listings page by keyset: a cursor holds the sort key values of the
+// row at a page boundary, and the next page is the rows ordered after it.
+// Unlike an offset, that position does not move when the scraper inserts
+// jobs mid-browse, and SQLite seeks to it instead of counting past every
+// earlier row. Offsets remain for jumping to a page number.
+
+// ErrBadCursor is returned for a page cursor that is malformed or was made
+// for a different sort order.
+var ErrBadCursor = errors.New("invalid page cursor")
+
+// PageRequest selects a page of jobs: the one a Cursor from an earlier
+// JobPage points to, or else page number Page (from 1). A Size of 0 or
+// less returns every match.
+type PageRequest struct {
+	Cursor string
+	Page   int
+	Size   int
+}
+
+// JobPage is a page of jobs with the cursors of the pages before and
+// after it, each "" at that end of the listing.
+type JobPage struct {
+	Jobs []Job
+	Prev string
+	Next string
+}
+
+// sortKey is one column of a job ordering.
+type sortKey struct {
+	expr  string // compared and ordered on
+	value string // selected into cursors; expr when empty
+	desc  bool
+}
+
+var (
+	keyNewest = []sortKey{{expr: "date_added", value: "CAST(date_added AS TEXT)", desc: true}, {expr: "id", desc: true}}
+	keyOldest = []sortKey{{expr: "date_added", value: "CAST(date_added AS TEXT)"}, {expr: "id"}}
+)
+
+// sortKeys returns the filter's ordering. Every ordering ends with id, so
+// no two rows tie and pages neither skip nor repeat rows.
+func (f JobFilter) sortKeys() []sortKey {
+	switch f.Sort {
+	case SortRelevance:
+		if ftsQuery(f.Search) != "" {
+			return append([]sortKey{{expr: "score"}}, keyNewest...)
+		}
+	case SortOldest:
+		return keyOldest
+	case SortTitle:
+		return []sortKey{{expr: "COALESCE(title, '') COLLATE NOCASE", value: "COALESCE(title, '')"}, {expr: "id"}}
+	case SortCompany:
+		return append([]sortKey{{expr: "COALESCE(company, '') COLLATE NOCASE", value: "COALESCE(company, '')"}}, keyNewest...)
+	}
+	return keyNewest
+}
+
+// orderBy returns the ORDER BY clause for keys, reversed when walking
+// backwards from a cursor.
+func orderBy(keys []sortKey, reverse bool) string {
+	cols := make([]string, len(keys))
+	for i, k := range keys {
+		dir := "ASC"
+		if k.desc != reverse {
+			dir = "DESC"
+		}
+		cols[i] = k.expr + " " + dir
+	}
+	return "ORDER BY " + strings.Join(cols, ", ")
+}
+
+// keysetClause returns the condition for rows ordered after the cursor
+// values (before them when reverse), numbering placeholders from $first:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for descending keys.
+func keysetClause(keys []sortKey, values []interface{}, reverse bool, first int) string {
+	var ors []string
+	for i, k := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = $%d", keys[j].expr, first+j))
+		}
+		op := ">"
+		if k.desc != reverse {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s $%d", k.expr, op, first+i))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")"
+}
+
+// pageCursor is the decoded form of a cursor token.
+type pageCursor struct {
+	Sort   JobSort       `json:"s,omitempty"`
+	Values []interface{} `json:"k"`
+	Before bool          `json:"b,omitempty"` // the page ending before the row, not starting after it
+}
+
+func (c pageCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses a token made for sort order s with n keys.
+func decodeCursor(token string, s JobSort, n int) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrBadCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil || c.Sort != s || len(c.Values) != n {
+		return c, ErrBadCursor
+	}
+	for i, v := range c.Values {
+		switch v := v.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				c.Values[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				c.Values[i] = f
+			} else {
+				return c, ErrBadCursor
+			}
+		case string:
+		default:
+			return c, ErrBadCursor
+		}
+	}
+	return c, nil
+}
+
+// ListJobsPage returns the page of jobs matching the filter that req
+// selects, with cursors for the neighbouring pages. It fails with
+// ErrBadCursor when req.Cursor was not made by this filter's sort order.
+func (d *DB) ListJobsPage(filter JobFilter, req PageRequest) (JobPage, error) {
+	var page JobPage
+	keys := filter.sortKeys()
+	var cur pageCursor
+	if req.Cursor != "" {
+		var err error
+		if cur, err = decodeCursor(req.Cursor, filter.Sort, len(keys)); err != nil {
+			return page, err
+		}
+	}
+
+	// A search joins its hits, for ranking and snippets
+	from, snippet := "job_applications", "''"
+	var args []interface{}
+	if q := ftsQuery(filter.Search); q != "" {
+		from = `(SELECT rowid AS id, ` + searchRank + ` AS score, ` + searchSnippet + ` AS snippet
+		FROM job_search WHERE job_search MATCH $1) hits JOIN job_applications USING (id)`
+		snippet = "hits.snippet"
+		args = append(args, q)
+	}
+	where, wargs := filter.where(len(args)+1, len(args) > 0)
+	args = append(args, wargs...)
+	if req.Cursor != "" {
+		clause := keysetClause(keys, cur.Values, cur.Before, len(args)+1)
+		args = append(args, cur.Values...)
+		if where == "" {
+			where = "WHERE " + clause
+		} else {
+			where += " AND " + clause
+		}
+	}
+	user := fmt.Sprintf("$%d", len(args)+1)
+	args = append(args, filter.UserID)
+	limit := ""
+	if req.Size > 0 {
+		// One extra row tells whether there is a further page
+		limit = fmt.Sprintf("LIMIT $%d", len(args)+1)
+		args = append(args, req.Size+1)
+		if req.Cursor == "" && req.Page > 1 {
+			limit += fmt.Sprintf(" OFFSET $%d", len(args)+1)
+			args = append(args, (req.Page-1)*req.Size)
+		}
+	}
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = k.value
+		if values[i] == "" {
+			values[i] = k.expr
+		}
+	}
+	q := `
+	SELECT id, COALESCE(title, ''), COALESCE(company, ''), COALESCE(company_id, 0), COALESCE(location, ''),
+		COALESCE(type, ''), COALESCE(url, ''), date_added, ` + userStatus(user) + `,
+		` + userFlag("starred", user) + `, ` + userFlag("hidden", user) + `, ` + userTags(user) + `,
+		first_seen_at, last_seen_at, closed_at, ` + snippet + `, ` + strings.Join(values, ", ") + `
+	FROM ` + from + `
+	` + where + `
+	` + orderBy(keys, cur.Before) + `
+	` + limit
+	rows, err := d.Read.Query(q, args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	var bounds [][]interface{}
+	for rows.Next() {
+		var job Job
+		var tags string
+		key := make([]interface{}, len(keys))
+		// Note: The Scan order MUST match the SELECT column order
+		dest := []interface{}{
+			&job.ID,
+			&job.Title,
+			&job.Company,
+			&job.CompanyID,
+			&job.Location,
+			&job.Type,
+			&job.URL,
+			&job.DateAdded,
+			&job.Status,
+			&job.Starred,
+			&job.Hidden,
+			&tags,
+			&job.FirstSeenAt,
+			&job.LastSeenAt,
+			&job.ClosedAt,
+			&job.Snippet,
+		}
+		for i := range key {
+			dest = append(dest, &key[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return page, err
+		}
+		job.Tags = splitTags(tags)
+		page.Jobs = append(page.Jobs, job)
+		bounds = append(bounds, key)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+	if req.Size <= 0 || len(page.Jobs) == 0 {
+		return page, nil
+	}
+
+	more := len(page.Jobs) > req.Size
+	if more {
+		page.Jobs, bounds = page.Jobs[:req.Size], bounds[:req.Size]
+	}
+	if cur.Before {
+		for i, j := 0, len(page.Jobs)-1; i < j; i, j = i+1, j-1 {
+			page.Jobs[i], page.Jobs[j] = page.Jobs[j], page.Jobs[i]
+			bounds[i], bounds[j] = bounds[j], bounds[i]
+		}
+	}
+	// Walking forward, rows exist behind us if we came from a cursor or
+	// an offset; walking back, the cursor row itself lies ahead.
+	hasPrev, hasNext := more, true
+	if !cur.Before {
+		hasPrev, hasNext = req.Cursor != "" || req.Page > 1, more
+	}
+	if hasPrev {
+		page.Prev = pageCursor{Sort: filter.Sort, Values: bounds[0], Before: true}.encode()
+	}
+	if hasNext {
+		page.Next = pageCursor{Sort: filter.Sort, Values: bounds[len(bounds)-1]}.encode()
+	}
+	return page, nil
+}
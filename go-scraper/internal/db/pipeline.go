@@ -0,0 +1,115 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pipeline is the set of application stages a job moves through for a
+// user, and the moves allowed between them. Every job starts at
+// StatusNotApplied, which is not one of the Stages.
+type Pipeline struct {
+	Stages []string
+
+	// Transitions lists the stages each status may move to, including
+	// StatusNotApplied. A nil map allows any move.
+	Transitions map[string][]string
+}
+
+// DefaultPipeline is the pipeline used unless the config defines one.
+var DefaultPipeline = Pipeline{
+	Stages: []string{"Saved", "Applied", "OA", "Recruiter Screen", "Onsite", "Offer", "Rejected", "Withdrawn", "Ghosted"},
+	Transitions: map[string][]string{
+		StatusNotApplied:   {"Saved", "Applied"},
+		"Saved":            {"Applied", "Withdrawn", StatusNotApplied},
+		"Applied":          {"OA", "Recruiter Screen", "Onsite", "Rejected", "Withdrawn", "Ghosted"},
+		"OA":               {"Recruiter Screen", "Onsite", "Rejected", "Withdrawn", "Ghosted"},
+		"Recruiter Screen": {"OA", "Onsite", "Rejected", "Withdrawn", "Ghosted"},
+		"Onsite":           {"Offer", "Rejected", "Withdrawn", "Ghosted"},
+		"Offer":            {"Rejected", "Withdrawn"},
+		// A company that went quiet may still get back in touch
+		"Ghosted": {"OA", "Recruiter Screen", "Onsite", "Offer", "Rejected", "Withdrawn"},
+	},
+}
+
+// TransitionError is returned for a status change the pipeline does not
+// allow.
+type TransitionError struct {
+	From, To string
+	Allowed  []string
+}
+
+func (e *TransitionError) Error() string {
+	if len(e.Allowed) == 0 {
+		return fmt.Sprintf("cannot move from %q to %q: %q is final", e.From, e.To, e.From)
+	}
+	return fmt.Sprintf("cannot move from %q to %q (allowed: %s)", e.From, e.To, strings.Join(e.Allowed, ", "))
+}
+
+// Has reports whether status is StatusNotApplied or one of the stages.
+func (p Pipeline) Has(status string) bool {
+	if status == StatusNotApplied {
+		return true
+	}
+	for _, s := range p.Stages {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the statuses a job at status may move to. A status the
+// pipeline does not know, e.g. one set under an older config, may move to
+// any stage.
+func (p Pipeline) Next(status string) []string {
+	if p.Transitions == nil || !p.Has(status) {
+		var out []string
+		for _, s := range append([]string{StatusNotApplied}, p.Stages...) {
+			if s != status {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return p.Transitions[status]
+}
+
+// NextAfter is Next for a job whose last status change was last, nil if it
+// has none. Undoing that change is always allowed, so a job moved to a
+// final status by mistake can go back.
+func (p Pipeline) NextAfter(status string, last *StatusChange) []string {
+	next := p.Next(status)
+	if last == nil || last.To != status || last.From == status || contains(next, last.From) {
+		return next
+	}
+	return append(next[:len(next):len(next)], last.From)
+}
+
+// Check returns a *TransitionError unless a job may move from one status
+// to the other. Staying at the same status is always allowed.
+func (p Pipeline) Check(from, to string) error {
+	return p.CheckAfter(from, to, nil)
+}
+
+// CheckAfter is Check for a job whose last status change was last, which
+// may always be undone.
+func (p Pipeline) CheckAfter(from, to string, last *StatusChange) error {
+	if from == to {
+		return nil
+	}
+	next := p.NextAfter(from, last)
+	if contains(next, to) {
+		return nil
+	}
+	return &TransitionError{From: from, To: to, Allowed: next}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package db
+
+// setJobProfiles replaces the search profiles a job is tagged with.
+func setJobProfiles(tx execer, jobID int, profiles []string) error {
+	if _, err := tx.Exec(`DELETE FROM job_profiles WHERE job_id = $1`, jobID); err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO job_profiles(job_id, profile) VALUES($1,$2)`, jobID, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListProfiles returns the names of all profiles that have tagged jobs.
+func (d *DB) ListProfiles() ([]string, error) {
+	rows, err := d.Read.Query(`SELECT DISTINCT profile FROM job_profiles ORDER BY profile`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// JobProfiles returns the profiles a job is tagged with.
+func (d *DB) JobProfiles(jobID int) ([]string, error) {
+	rows, err := d.Read.Query(`SELECT profile FROM job_profiles WHERE job_id = $1 ORDER BY profile`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
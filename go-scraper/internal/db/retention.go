@@ -0,0 +1,124 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ArchiveRule selects jobs for ArchiveJobs. Every condition set must hold,
+// and a rule with neither set matches nothing.
+type ArchiveRule struct {
+	Name      string
+	ClosedFor time.Duration // closed at least this long ago
+	Age       time.Duration // first seen at least this long ago, open or closed
+}
+
+// ArchivedJob is a job ArchiveJobs moved, or would move, to the archive.
+type ArchivedJob struct {
+	Job
+	Rule string
+}
+
+// archiveColumns are copied from job_applications to job_archive.
+const archiveColumns = `id, title, company, location, salary, type, url, date_added,
+	first_seen_at, last_seen_at, closed_at, description, source, source_job_id, merged_into, company_id`
+
+// keepUserState excludes jobs any user has acted on: changed the status
+// of, starred, hidden, annotated or split from a duplicate. It applies to
+// a job and to every duplicate merged into it, since they are archived
+// together.
+var keepUserState = `NOT ` + hasUserState("j") + ` AND j.merge_locked = 0
+	AND NOT EXISTS (SELECT 1 FROM job_applications m WHERE m.merged_into = j.id
+		AND (` + hasUserState("m") + ` OR m.merge_locked = 1))`
+
+// ArchiveJobs moves the jobs matching any rule, as of now, from
+// job_applications to job_archive, and returns them with the first rule
+// each matched. Duplicates merged into an archived job go with it. Jobs
+// with user state are always kept. With dryRun it only returns what it
+// would move.
+func (d *DB) ArchiveJobs(rules []ArchiveRule, now time.Time, dryRun bool) ([]ArchivedJob, error) {
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var out []ArchivedJob
+	for _, r := range rules {
+		var conds []string
+		var args []interface{}
+		if r.ClosedFor > 0 {
+			args = append(args, sqliteTime(now.Add(-r.ClosedFor)))
+			conds = append(conds, fmt.Sprintf("j.closed_at <= $%d", len(args)))
+		}
+		if r.Age > 0 {
+			args = append(args, sqliteTime(now.Add(-r.Age)))
+			conds = append(conds, fmt.Sprintf("COALESCE(j.first_seen_at, j.date_added) <= $%d", len(args)))
+		}
+		if len(conds) == 0 {
+			continue
+		}
+		matched := `SELECT j.id FROM job_applications j
+			WHERE j.merged_into IS NULL AND ` + strings.Join(conds, " AND ") + ` AND ` + keepUserState
+		rows, err := tx.Query(`
+		SELECT id, COALESCE(title, ''), COALESCE(company, ''), COALESCE(location, ''), COALESCE(url, ''),
+			date_added, closed_at, merged_into
+		FROM job_applications
+		WHERE id IN (`+matched+`) OR merged_into IN (`+matched+`)
+		ORDER BY id`, args...)
+		if err != nil {
+			return nil, err
+		}
+		var ids []interface{}
+		for rows.Next() {
+			j := ArchivedJob{Rule: r.Name}
+			if err := rows.Scan(&j.ID, &j.Title, &j.Company, &j.Location, &j.URL, &j.DateAdded, &j.ClosedAt, &j.MergedInto); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			out = append(out, j)
+			ids = append(ids, j.ID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		// Moved even in a dry run, so later rules do not match these jobs
+		// again; the rollback undoes it
+		_, err = tx.Exec(`INSERT INTO job_archive(`+archiveColumns+`, profiles, rule)
+			SELECT `+archiveColumns+`,
+				(SELECT group_concat(profile, ',') FROM job_profiles p WHERE p.job_id = job_applications.id), $1
+			FROM job_applications WHERE id IN `+placeholders(2, len(ids)), append([]interface{}{r.Name}, ids...)...)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`DELETE FROM job_applications WHERE id IN `+placeholders(1, len(ids)), ids...); err != nil {
+			return nil, err
+		}
+	}
+	if dryRun {
+		return out, nil
+	}
+	return out, tx.Commit()
+}
+
+// CountArchivedJobs returns the number of jobs in the archive.
+func (d *DB) CountArchivedJobs() (int, error) {
+	var n int
+	err := d.Read.QueryRow(`SELECT COUNT(*) FROM job_archive`).Scan(&n)
+	return n, err
+}
+
+// placeholders returns "($first, ..., $first+n-1)".
+func placeholders(first, n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = fmt.Sprintf("$%d", first+i)
+	}
+	return "(" + strings.Join(ps, ", ") + ")"
+}
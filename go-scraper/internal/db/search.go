@@ -0,0 +1,57 @@
+package db
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Snippet markers: Job.Snippet holds the matched terms between SnippetOpen
+// and SnippetClose, for the caller to escape and highlight.
+const (
+	SnippetOpen  = "\x02"
+	SnippetClose = "\x03"
+)
+
+// searchRank scores job_search matches with bm25, weighting the title over
+// the company, location and description columns. Lower is better.
+const searchRank = `bm25(job_search, 10.0, 5.0, 2.0, 1.0)`
+
+// searchSnippet picks up to 16 tokens around the best match in any column.
+const searchSnippet = `snippet(job_search, -1, '` + SnippetOpen + `', '` + SnippetClose + `', '…', 16)`
+
+// ftsQuery turns search box input into an FTS5 query. Every word must
+// match somewhere; "quoted words" match as a phrase and a trailing * as a
+// prefix, e.g. golang "new grad" eng*. Other FTS5 syntax in the input is
+// treated as plain text. It returns "" when the input has no words.
+func ftsQuery(s string) string {
+	var terms []string
+	for i, part := range strings.Split(s, `"`) {
+		if i%2 == 1 {
+			// Inside quotes: one phrase
+			if words := ftsWords(part); len(words) > 0 {
+				terms = append(terms, `"`+strings.Join(words, " ")+`"`)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			words := ftsWords(field)
+			if len(words) == 0 {
+				continue
+			}
+			// "node.js" is the phrase "node js", as the tokenizer sees it
+			term := `"` + strings.Join(words, " ") + `"`
+			if strings.HasSuffix(field, "*") {
+				term += "*"
+			}
+			terms = append(terms, term)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// ftsWords splits s into the letter and digit runs the tokenizer indexes.
+func ftsWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// UpsertCounts summarizes what UpsertJobs did with a batch of jobs.
+type UpsertCounts struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+	Failed    int
+
+	// Results and Errors are indexed like the input jobs. Results[i] is
+	// only meaningful when Errors[i] is nil.
+	Results []UpsertResult
+	Errors  []error
+}
+
+// execer is satisfied by *sql.Tx and stmtCache.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// stmtCache prepares each statement once per transaction, so a batch of
+// jobs reuses the same prepared statements.
+type stmtCache struct {
+	tx    *sql.Tx
+	stmts map[string]*sql.Stmt
+}
+
+func (c *stmtCache) stmt(query string) (*sql.Stmt, error) {
+	if st, ok := c.stmts[query]; ok {
+		return st, nil
+	}
+	st, err := c.tx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = st
+	return st, nil
+}
+
+func (c *stmtCache) QueryRow(query string, args ...interface{}) *sql.Row {
+	st, err := c.stmt(query)
+	if err != nil {
+		// The unprepared query reports the same error from Scan
+		return c.tx.QueryRow(query, args...)
+	}
+	return st.QueryRow(args...)
+}
+
+func (c *stmtCache) Exec(query string, args ...interface{}) (sql.Result, error) {
+	st, err := c.stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	return st.Exec(args...)
+}
+
+// UpsertJobs records a batch of scraped jobs, typically one company's board,
+// in a single transaction with prepared statements. Each job is handled as
+// RecordJobSeen would; a job that fails is rolled back on its own and
+// reported in Errors without affecting the rest of the batch. The returned
+// error is set, and nothing is stored, only when the transaction itself
+// fails or ctx is cancelled.
+func (d *DB) UpsertJobs(ctx context.Context, jobs []Job) (UpsertCounts, error) {
+	counts := UpsertCounts{Results: make([]UpsertResult, len(jobs)), Errors: make([]error, len(jobs))}
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return UpsertCounts{}, err
+	}
+	defer tx.Rollback()
+
+	c := &stmtCache{tx: tx, stmts: map[string]*sql.Stmt{}}
+	for i, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return UpsertCounts{}, err
+		}
+		if _, err := tx.Exec(`SAVEPOINT upsert_job`); err != nil {
+			return UpsertCounts{}, err
+		}
+		res, err := recordJob(c, job)
+		if err != nil {
+			if _, err := tx.Exec(`ROLLBACK TO upsert_job`); err != nil {
+				return UpsertCounts{}, err
+			}
+			counts.Errors[i] = err
+			counts.Failed++
+		} else {
+			counts.Results[i] = res
+			switch res {
+			case JobInserted:
+				counts.Inserted++
+			case JobUpdated:
+				counts.Updated++
+			case JobUnchanged:
+				counts.Unchanged++
+			}
+		}
+		if _, err := tx.Exec(`RELEASE upsert_job`); err != nil {
+			return UpsertCounts{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return UpsertCounts{}, err
+	}
+	return counts, nil
+}
+
+// RecordJobSeen inserts a scraped job or, if it is already known, refreshes
+// last_seen_at and reopens it when it had been marked closed. Existing jobs
+// are matched on (Source, SourceJobID) when set, then on canonical URL.
+// Edits to the title, company, location, type, description or URL are
+// applied to the stored record and written to job_versions. Profile tags
+// are replaced when job.Profiles is set.
+func (d *DB) RecordJobSeen(job Job) (UpsertResult, error) {
+	counts, err := d.UpsertJobs(context.Background(), []Job{job})
+	if err != nil {
+		return 0, err
+	}
+	return counts.Results[0], counts.Errors[0]
+}
+
+// MarkJobsListed refreshes last_seen_at for the stored jobs among a board's
+// postings without recording anything else, for postings the filters left
+// out. A job still listed on the board is not closed just because it no
+// longer matches the filters. It returns how many stored jobs it found.
+func (d *DB) MarkJobsListed(jobs []Job) (int, error) {
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	found := 0
+	for _, job := range jobs {
+		plan, err := planJob(tx, job)
+		if err != nil {
+			return 0, err
+		}
+		if plan.ID == 0 {
+			continue
+		}
+		seen := sqliteTime(time.Now())
+		if !job.SeenAt.IsZero() {
+			seen = sqliteTime(job.SeenAt)
+		}
+		if _, err := tx.Exec(`UPDATE job_applications SET last_seen_at = MAX(COALESCE(last_seen_at, ''), $1) WHERE id = $2`, seen, plan.ID); err != nil {
+			return 0, err
+		}
+		found++
+	}
+	return found, tx.Commit()
+}
+
+// recordJob writes one scraped job within an UpsertJobs transaction.
+func recordJob(c *stmtCache, job Job) (UpsertResult, error) {
+	plan, err := planJob(c, job)
+	if err != nil {
+		return 0, err
+	}
+	seen := sqliteTime(time.Now())
+	if !job.SeenAt.IsZero() {
+		seen = sqliteTime(job.SeenAt)
+	}
+	if plan.Result == JobInserted {
+		var id int
+		err := c.QueryRow(`INSERT INTO job_applications(title, company, location, type, url, description, source, source_job_id, company_id,
+				date_added, first_seen_at, last_seen_at)
+			 VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$10,$10)
+			 RETURNING id`,
+			job.Title, job.Company, job.Location, job.Type, plan.URL,
+			nullIfEmpty(job.Description), nullIfEmpty(job.Source), nullIfEmpty(job.SourceJobID), nullIfZero(job.CompanyID), seen).Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+		if job.Profiles != nil {
+			if err := setJobProfiles(c, id, job.Profiles); err != nil {
+				return 0, err
+			}
+		}
+		return JobInserted, nil
+	}
+
+	if plan.Result == JobUpdated {
+		raw, err := json.Marshal(plan.Changes)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := c.Exec(`INSERT INTO job_versions(job_id, changes) VALUES($1,$2)`, plan.ID, string(raw)); err != nil {
+			return 0, err
+		}
+		_, err = c.Exec(`UPDATE job_applications
+			 SET title = COALESCE(NULLIF($1,''), title),
+				 company = COALESCE(NULLIF($2,''), company),
+				 location = COALESCE(NULLIF($3,''), location),
+				 type = COALESCE(NULLIF($4,''), type),
+				 description = COALESCE(NULLIF($5,''), description),
+				 url = $6
+			 WHERE id = $7`, job.Title, job.Company, job.Location, job.Type, job.Description, plan.URL, plan.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Rows matched by URL before identity tracking existed adopt it now.
+	// Jobs closed after this sighting stay closed.
+	_, err = c.Exec(`UPDATE job_applications
+		 SET last_seen_at = MAX(COALESCE(last_seen_at, ''), $5),
+			 closed_at = CASE WHEN closed_at > $5 THEN closed_at END,
+			 source = COALESCE(source, $1),
+			 source_job_id = COALESCE(source_job_id, $2),
+			 company_id = COALESCE($3, company_id)
+		 WHERE id = $4`, nullIfEmpty(job.Source), nullIfEmpty(job.SourceJobID), nullIfZero(job.CompanyID), plan.ID, seen)
+	if err != nil {
+		return 0, err
+	}
+	if job.Profiles != nil {
+		if err := setJobProfiles(c, plan.ID, job.Profiles); err != nil {
+			return 0, err
+		}
+	}
+	return plan.Result, nil
+}
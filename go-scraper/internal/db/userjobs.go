@@ -0,0 +1,236 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StatusNotApplied is the status of a job a user has not acted on.
+const StatusNotApplied = "Not Applied"
+
+// UserJob is one user's state for a job. Jobs a user has never touched
+// have the zero state with StatusNotApplied.
+type UserJob struct {
+	UserID    int
+	JobID     int
+	Status    string
+	AppliedAt *time.Time // first time the status became Applied
+	UpdatedAt time.Time
+	Starred   bool
+	Hidden    bool // hidden from the user's job lists
+}
+
+// userStatus is the status of the current job_applications row for the
+// user in placeholder arg.
+func userStatus(arg string) string {
+	return `COALESCE((SELECT status FROM user_jobs uj
+		WHERE uj.job_id = job_applications.id AND uj.user_id = ` + arg + `), '` + StatusNotApplied + `')`
+}
+
+// userFlag is a user_jobs flag of the current job_applications row for the
+// user in placeholder arg, 0 without a row.
+func userFlag(flag, arg string) string {
+	return `COALESCE((SELECT ` + flag + ` FROM user_jobs uj
+		WHERE uj.job_id = job_applications.id AND uj.user_id = ` + arg + `), 0)`
+}
+
+// hasUserState matches the job in alias's row when any user has applied,
+// changed its status (even back to Not Applied), starred or hidden it, or
+// added notes, tags or attachments.
+func hasUserState(alias string) string {
+	return `(EXISTS (SELECT 1 FROM user_jobs uj WHERE uj.job_id = ` + alias + `.id
+		AND (uj.status <> '` + StatusNotApplied + `' OR uj.starred = 1 OR uj.hidden = 1))
+		OR EXISTS (SELECT 1 FROM status_history WHERE job_id = ` + alias + `.id)
+		OR EXISTS (SELECT 1 FROM job_notes WHERE job_id = ` + alias + `.id)
+		OR EXISTS (SELECT 1 FROM job_tags WHERE job_id = ` + alias + `.id)
+		OR EXISTS (SELECT 1 FROM job_attachments WHERE job_id = ` + alias + `.id))`
+}
+
+// GetUserJob returns a user's state for a job.
+func (d *DB) GetUserJob(userID, jobID int) (UserJob, error) {
+	uj := UserJob{UserID: userID, JobID: jobID, Status: StatusNotApplied}
+	err := d.Read.QueryRow(`SELECT status, applied_at, updated_at, starred, hidden
+		FROM user_jobs WHERE user_id = $1 AND job_id = $2`, userID, jobID).Scan(
+		&uj.Status, &uj.AppliedAt, &uj.UpdatedAt, &uj.Starred, &uj.Hidden)
+	if err == sql.ErrNoRows {
+		return uj, nil
+	}
+	return uj, err
+}
+
+// SetJobStatus moves a user's status for a job to status, if the pipeline
+// allows it or it undoes the last change, and records the change in the
+// job's status history. It fails with sql.ErrNoRows if the job does not
+// exist and a *TransitionError if the move is not allowed.
+func (d *DB) SetJobStatus(p Pipeline, userID, jobID int, status string) error {
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := jobExists(tx, jobID); err != nil {
+		return err
+	}
+	from := StatusNotApplied
+	err = tx.QueryRow(`SELECT status FROM user_jobs WHERE user_id = $1 AND job_id = $2`, userID, jobID).Scan(&from)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if from == status {
+		return nil
+	}
+	last, err := lastStatusChange(tx, userID, jobID)
+	if err != nil {
+		return err
+	}
+	if err := p.CheckAfter(from, status, last); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO user_jobs(user_id, job_id, status) VALUES($1, $2, $3)
+		ON CONFLICT (user_id, job_id) DO UPDATE SET status = excluded.status, updated_at = CURRENT_TIMESTAMP`,
+		userID, jobID, status); err != nil {
+		return err
+	}
+	if status == "Applied" {
+		if _, err := tx.Exec(`UPDATE user_jobs SET applied_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1 AND job_id = $2 AND applied_at IS NULL`, userID, jobID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO status_history(user_id, job_id, from_status, to_status) VALUES($1, $2, $3, $4)`,
+		userID, jobID, from, status); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// StatusChange is one entry in a job's status history.
+type StatusChange struct {
+	From, To  string
+	ChangedAt time.Time
+}
+
+// ListStatusHistory returns a user's status changes for a job, oldest
+// first.
+func (d *DB) ListStatusHistory(userID, jobID int) ([]StatusChange, error) {
+	rows, err := d.Read.Query(`SELECT from_status, to_status, changed_at FROM status_history
+		WHERE user_id = $1 AND job_id = $2 ORDER BY id`, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []StatusChange
+	for rows.Next() {
+		var c StatusChange
+		if err := rows.Scan(&c.From, &c.To, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// LastStatusChanges returns a user's latest status change for each of the
+// jobs that has one, keyed by job ID.
+func (d *DB) LastStatusChanges(userID int, jobIDs []int) (map[int]*StatusChange, error) {
+	out := map[int]*StatusChange{}
+	if len(jobIDs) == 0 {
+		return out, nil
+	}
+	args := []interface{}{userID}
+	marks := make([]string, len(jobIDs))
+	for i, id := range jobIDs {
+		args = append(args, id)
+		marks[i] = fmt.Sprintf("$%d", i+2)
+	}
+	rows, err := d.Read.Query(`SELECT job_id, from_status, to_status, changed_at FROM status_history
+		WHERE id IN (SELECT MAX(id) FROM status_history
+			WHERE user_id = $1 AND job_id IN (`+strings.Join(marks, ", ")+`) GROUP BY job_id)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var c StatusChange
+		if err := rows.Scan(&id, &c.From, &c.To, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		out[id] = &c
+	}
+	return out, rows.Err()
+}
+
+// lastStatusChange returns a user's latest status change for a job, nil if
+// there is none.
+func lastStatusChange(q queryer, userID, jobID int) (*StatusChange, error) {
+	var c StatusChange
+	err := q.QueryRow(`SELECT from_status, to_status, changed_at FROM status_history
+		WHERE user_id = $1 AND job_id = $2 ORDER BY id DESC LIMIT 1`, userID, jobID).Scan(&c.From, &c.To, &c.ChangedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// SetJobStarred stars or unstars a job for a user.
+func (d *DB) SetJobStarred(userID, jobID int, starred bool) error {
+	return d.setUserFlag(userID, jobID, "starred", starred)
+}
+
+// SetJobHidden hides a job from a user's lists, or shows it again.
+func (d *DB) SetJobHidden(userID, jobID int, hidden bool) error {
+	return d.setUserFlag(userID, jobID, "hidden", hidden)
+}
+
+// setUserFlag sets one user_jobs flag column, creating the row if needed.
+func (d *DB) setUserFlag(userID, jobID int, column string, value bool) error {
+	if err := jobExists(d.Conn, jobID); err != nil {
+		return err
+	}
+	_, err := d.Conn.Exec(fmt.Sprintf(`INSERT INTO user_jobs(user_id, job_id, %[1]s) VALUES($1, $2, $3)
+		ON CONFLICT (user_id, job_id) DO UPDATE SET %[1]s = excluded.%[1]s, updated_at = CURRENT_TIMESTAMP`, column),
+		userID, jobID, value)
+	return err
+}
+
+// jobExists returns sql.ErrNoRows if there is no job with the ID.
+func jobExists(q queryer, jobID int) error {
+	var exists bool
+	if err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM job_applications WHERE id = $1)`, jobID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CountJobsByStatus returns how many jobs matching the filter have each
+// status for the filter's user.
+func (d *DB) CountJobsByStatus(filter JobFilter) (map[string]int, error) {
+	where, args := filter.Where(1)
+	rows, err := d.Read.Query(`SELECT `+userStatus(fmt.Sprintf("$%d", len(args)+1))+` AS s, COUNT(*)
+		FROM job_applications `+where+` GROUP BY s`, append(args, filter.UserID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		out[status] = n
+	}
+	return out, rows.Err()
+}
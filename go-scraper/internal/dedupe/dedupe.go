@@ -0,0 +1,133 @@
+package dedupe
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// trackingParams are query parameters that identify how a visitor reached a
+// posting rather than the posting itself.
+var trackingParams = map[string]bool{
+	"gh_src":       true,
+	"ref":          true,
+	"lever-source": true,
+	"lever-origin": true,
+	"lever-via":    true,
+}
+
+// CanonicalURL normalizes a posting URL so that links differing only by
+// tracking parameters, fragments, scheme, host case or a trailing slash
+// compare equal. Unparseable input is returned trimmed but otherwise as-is.
+func CanonicalURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	u.Scheme = "https"
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Fragment = ""
+	u.RawFragment = ""
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+	}
+	u.RawPath = ""
+
+	q := u.Query()
+	for k := range q {
+		lk := strings.ToLower(k)
+		if trackingParams[lk] || strings.HasPrefix(lk, "utm_") {
+			q.Del(k)
+		}
+	}
+	u.RawQuery = q.Encode() // Encode sorts keys
+	return u.String()
+}
+
+var (
+	nonAlnumRe = regexp.MustCompile(`[^a-z0-9]+`)
+	// noise that varies between listings of the same role
+	titleNoiseRe = regexp.MustCompile(`\b(20\d\d|remote|hybrid|onsite|on site|us|usa|f t|full time|req \d+)\b`)
+)
+
+var companySuffixes = []string{"inc", "llc", "ltd", "corp", "corporation", "co", "hq", "technologies", "labs"}
+
+// NormalizeCompany reduces a company name or slug to a comparable form, e.g.
+// "Snap Inc." and "snapinc" both become "snap".
+func NormalizeCompany(s string) string {
+	s = strings.ToLower(s)
+	words := strings.Fields(nonAlnumRe.ReplaceAllString(s, " "))
+	for len(words) > 1 && isSuffix(words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	joined := strings.Join(words, "")
+	for _, suf := range companySuffixes {
+		if len(joined) > len(suf)+2 && strings.HasSuffix(joined, suf) {
+			return strings.TrimSuffix(joined, suf)
+		}
+	}
+	return joined
+}
+
+func isSuffix(w string) bool {
+	for _, s := range companySuffixes {
+		if w == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeTitle lowercases a title and strips punctuation, years and
+// location/work-mode words that aggregators tend to append.
+func NormalizeTitle(s string) string {
+	s = nonAlnumRe.ReplaceAllString(strings.ToLower(s), " ")
+	s = titleNoiseRe.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// NormalizeLocation returns the sorted set of location words, so "Remote - US"
+// and "US, Remote" match.
+func NormalizeLocation(s string) string {
+	words := strings.Fields(nonAlnumRe.ReplaceAllString(strings.ToLower(s), " "))
+	sort.Strings(words)
+	out := words[:0]
+	for i, w := range words {
+		if i == 0 || w != words[i-1] {
+			out = append(out, w)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// GroupKey buckets jobs that may be duplicates: same company and location.
+// Titles within a bucket are then compared with SimilarTitles.
+func GroupKey(company, location string) string {
+	return NormalizeCompany(company) + "|" + NormalizeLocation(location)
+}
+
+// SimilarTitles reports whether two titles likely describe the same role,
+// using the Jaccard similarity of their normalized words.
+func SimilarTitles(a, b string) bool {
+	ta := strings.Fields(NormalizeTitle(a))
+	tb := strings.Fields(NormalizeTitle(b))
+	if len(ta) == 0 || len(tb) == 0 {
+		return false
+	}
+	set := make(map[string]int, len(ta))
+	for _, w := range ta {
+		set[w] |= 1
+	}
+	for _, w := range tb {
+		set[w] |= 2
+	}
+	both := 0
+	for _, v := range set {
+		if v == 3 {
+			both++
+		}
+	}
+	return float64(both)/float64(len(set)) >= 0.8
+}
@@ -0,0 +1,37 @@
+package dedupe
+
+import "testing"
+
+func TestCanonicalURL(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"https://boards.greenhouse.io/stripe/jobs/123?gh_src=abc", "https://boards.greenhouse.io/stripe/jobs/123"},
+		{"http://WWW.Example.com/jobs/1/?utm_source=x&utm_medium=y#apply", "https://example.com/jobs/1"},
+		{"https://example.com/careers?gh_jid=42&utm_campaign=z", "https://example.com/careers?gh_jid=42"},
+		{"not a url", "not a url"},
+	}
+	for _, c := range cases {
+		if got := CanonicalURL(c.in); got != c.want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNormalizeCompany(t *testing.T) {
+	for _, in := range []string{"Snap Inc.", "snapinc", "Snap"} {
+		if got := NormalizeCompany(in); got != "snap" {
+			t.Errorf("NormalizeCompany(%q) = %q, want snap", in, got)
+		}
+	}
+}
+
+func TestGroupKeyAndSimilarTitles(t *testing.T) {
+	if GroupKey("Stripe", "Remote - US") != GroupKey("stripe", "US, Remote") {
+		t.Error("GroupKey should ignore location word order and punctuation")
+	}
+	if !SimilarTitles("Software Engineer, New Grad (2025)", "Software Engineer - New Grad") {
+		t.Error("SimilarTitles should match titles differing only by year and punctuation")
+	}
+	if SimilarTitles("Software Engineer Intern", "Data Analyst Intern") {
+		t.Error("SimilarTitles matched different roles")
+	}
+}
@@ -0,0 +1,293 @@
+// Package discover finds which applicant tracking system (ATS) a company
+// uses and its board slug, from a careers page or just the company name.
+package discover
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/dedupe"
+)
+
+// Candidate is a possible ATS board for a company.
+type Candidate struct {
+	Platform  string `json:"platform"` // greenhouse, lever, ashby or workday
+	Slug      string `json:"slug"`
+	Host      string `json:"host,omitempty"` // API host override, e.g. EU Lever boards
+	Name      string `json:"name,omitempty"` // company name reported by the board
+	Found     string `json:"found"`          // "page" if linked from the careers page, else "guess"
+	Verified  bool   `json:"verified"`       // the board answered
+	Jobs      int    `json:"jobs"`           // open postings, when verified
+	Supported bool   `json:"supported"`      // the scraper can fetch this platform
+	Error     string `json:"error,omitempty"`
+}
+
+// Result is everything discovered for one input.
+type Result struct {
+	Input      string      `json:"input"`
+	Page       string      `json:"page,omitempty"` // careers page fetched, if the input was a URL
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Best returns the first verified candidate the scraper supports; after Run
+// sorts them, that is the one with the most jobs.
+func (r *Result) Best() (Candidate, bool) {
+	for _, c := range r.Candidates {
+		if c.Verified && c.Supported {
+			return c, true
+		}
+	}
+	return Candidate{}, false
+}
+
+// Supported lists the platforms the scraper can fetch.
+var Supported = map[string]bool{"greenhouse": true, "lever": true}
+
+// API URLs probed for each platform, exposed for testing. Each takes the slug.
+var (
+	greenhouseBoardURL = "https://api.greenhouse.io/v1/boards/%s"
+	greenhouseJobsURL  = "https://api.greenhouse.io/v1/boards/%s/jobs"
+	leverPostingsURL   = "https://api.lever.co/v0/postings/%s?mode=json"
+	ashbyBoardURL      = "https://api.ashbyhq.com/posting-api/job-board/%s"
+)
+
+var client = &http.Client{Timeout: 15 * time.Second}
+
+var widgetPatterns = []struct {
+	platform string
+	re       *regexp.Regexp
+}{
+	{"greenhouse", regexp.MustCompile(`(?i)(?:boards|job-boards)(?:\.eu)?\.greenhouse\.io/(?:embed/job_board(?:/js)?\?for=)?([a-z0-9_-]+)`)},
+	{"lever", regexp.MustCompile(`(?i)jobs(\.eu)?\.lever\.co/([a-z0-9_.-]+)`)},
+	{"ashby", regexp.MustCompile(`(?i)jobs\.ashbyhq\.com/([a-z0-9_.%-]+)`)},
+	{"workday", regexp.MustCompile(`(?i)([a-z0-9-]+)\.wd\d+\.myworkdayjobs\.com/(?:[a-z]{2}-[a-z]{2}/)?([a-z0-9_-]+)`)},
+}
+
+// Detect finds ATS boards linked or embedded in a careers page.
+func Detect(page string) []Candidate {
+	var out []Candidate
+	seen := map[string]bool{}
+	add := func(c Candidate) {
+		key := c.Platform + "/" + strings.ToLower(c.Slug)
+		if c.Slug == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		c.Found = "page"
+		c.Supported = Supported[c.Platform]
+		out = append(out, c)
+	}
+	for _, p := range widgetPatterns {
+		for _, m := range p.re.FindAllStringSubmatch(page, -1) {
+			switch p.platform {
+			case "greenhouse":
+				if slug := m[1]; slug != "embed" {
+					add(Candidate{Platform: p.platform, Slug: slug})
+				}
+			case "lever":
+				c := Candidate{Platform: p.platform, Slug: m[2]}
+				if m[1] != "" {
+					c.Host = "api.eu.lever.co"
+				}
+				add(c)
+			case "ashby":
+				slug, _ := url.PathUnescape(m[1])
+				add(Candidate{Platform: p.platform, Slug: slug})
+			case "workday":
+				add(Candidate{Platform: p.platform, Slug: m[1] + "/" + m[2]})
+			}
+		}
+	}
+	return out
+}
+
+var (
+	nonAlnum      = regexp.MustCompile(`[^a-z0-9]+`)
+	legalSuffixes = map[string]bool{"inc": true, "llc": true, "ltd": true, "corp": true, "corporation": true, "co": true, "gmbh": true, "plc": true}
+)
+
+// SlugGuesses returns likely board slugs for a company name, most likely
+// first: "Acme Robotics, Inc." gives acmerobotics, acme-robotics and acme.
+func SlugGuesses(name string) []string {
+	words := strings.Fields(nonAlnum.ReplaceAllString(strings.ToLower(name), " "))
+	for len(words) > 1 && legalSuffixes[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	var out []string
+	seen := map[string]bool{}
+	add := func(s string) {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	add(strings.Join(words, ""))
+	add(strings.Join(words, "-"))
+	add(dedupe.NormalizeCompany(name)) // also drops words like "labs"
+	add(words[0])
+	return out
+}
+
+// Run discovers boards for a company name or careers page URL. Boards
+// linked from the page are probed first; if none answer, slugs guessed
+// from the name (or the page's domain) are tried on every platform.
+func Run(input string) (*Result, error) {
+	res := &Result{Input: input}
+	name := input
+	if page, ok := asURL(input); ok {
+		res.Page = page.String()
+		body, final, err := fetchPage(page.String())
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s: %w", page, err)
+		}
+		// Redirects often land on the ATS itself
+		res.Candidates = Detect(final + "\n" + body)
+		name = domainName(page.Hostname())
+	}
+
+	for i := range res.Candidates {
+		probe(&res.Candidates[i])
+	}
+	if _, ok := res.Best(); !ok {
+		for _, slug := range SlugGuesses(name) {
+			for _, p := range []string{"greenhouse", "lever", "ashby"} {
+				c := Candidate{Platform: p, Slug: slug, Found: "guess", Supported: Supported[p]}
+				probe(&c)
+				if c.Verified {
+					res.Candidates = append(res.Candidates, c)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(res.Candidates, func(i, j int) bool {
+		a, b := res.Candidates[i], res.Candidates[j]
+		if a.Verified != b.Verified {
+			return a.Verified
+		}
+		if a.Supported != b.Supported {
+			return a.Supported
+		}
+		return a.Jobs > b.Jobs
+	})
+	return res, nil
+}
+
+// probe fills in Verified, Jobs, Name and Error for a candidate.
+func probe(c *Candidate) {
+	var err error
+	switch c.Platform {
+	case "greenhouse":
+		var board struct {
+			Name string `json:"name"`
+		}
+		if err = getJSON(fmt.Sprintf(greenhouseBoardURL, url.PathEscape(c.Slug)), &board); err == nil {
+			c.Name = board.Name
+			var jobs struct {
+				Jobs []json.RawMessage `json:"jobs"`
+			}
+			err = getJSON(fmt.Sprintf(greenhouseJobsURL, url.PathEscape(c.Slug)), &jobs)
+			c.Jobs = len(jobs.Jobs)
+		}
+	case "lever":
+		u := fmt.Sprintf(leverPostingsURL, url.PathEscape(c.Slug))
+		if c.Host != "" {
+			u = strings.Replace(u, "api.lever.co", c.Host, 1)
+		}
+		var jobs []json.RawMessage
+		err = getJSON(u, &jobs)
+		c.Jobs = len(jobs)
+	case "ashby":
+		var board struct {
+			Jobs []json.RawMessage `json:"jobs"`
+		}
+		err = getJSON(fmt.Sprintf(ashbyBoardURL, url.PathEscape(c.Slug)), &board)
+		c.Jobs = len(board.Jobs)
+	default:
+		err = fmt.Errorf("%s boards cannot be probed", c.Platform)
+	}
+	if err != nil {
+		c.Error = err.Error()
+		return
+	}
+	c.Verified = true
+}
+
+func getJSON(u string, v interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "yc-go-scraper/1.0 (+https://github.com/ajiteshreddy7/yc-go-scraper)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("no such board")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// fetchPage returns a page's body and the URL it finally redirected to.
+func fetchPage(u string) (string, string, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; yc-go-scraper/1.0)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+	return string(body), resp.Request.URL.String(), err
+}
+
+// asURL reports whether input looks like a careers page rather than a name.
+func asURL(input string) (*url.URL, bool) {
+	s := strings.TrimSpace(input)
+	if strings.ContainsAny(s, " \t") || !strings.Contains(s, ".") {
+		return nil, false
+	}
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// domainName guesses the company name from a host: careers.acme.co.uk
+// gives acme.
+func domainName(host string) string {
+	parts := strings.Split(strings.TrimPrefix(strings.ToLower(host), "www."), ".")
+	// Drop the TLD, plus a second-level one such as "co" in .co.uk
+	if len(parts) > 1 {
+		parts = parts[:len(parts)-1]
+	}
+	if n := len(parts); n > 1 && (parts[n-1] == "co" || parts[n-1] == "com") {
+		parts = parts[:n-1]
+	}
+	return parts[len(parts)-1]
+}
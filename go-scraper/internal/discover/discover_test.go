@@ -0,0 +1,64 @@
+package discover
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	page := `
+	<script src="https://boards.greenhouse.io/embed/job_board/js?for=acme"></script>
+	<a href="https://jobs.eu.lever.co/acme-eu/123">EU roles</a>
+	<a href="https://jobs.ashbyhq.com/Acme%20Labs">Labs</a>
+	<a href="https://acme.wd5.myworkdayjobs.com/en-US/External">Workday</a>
+	<a href="https://boards.greenhouse.io/acme/jobs/42">dup</a>`
+
+	got := Detect(page)
+	want := []Candidate{
+		{Platform: "greenhouse", Slug: "acme", Found: "page", Supported: true},
+		{Platform: "lever", Slug: "acme-eu", Host: "api.eu.lever.co", Found: "page", Supported: true},
+		{Platform: "ashby", Slug: "Acme Labs", Found: "page"},
+		{Platform: "workday", Slug: "acme/External", Found: "page"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Detect =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSlugGuesses(t *testing.T) {
+	got := SlugGuesses("Acme Robotics, Inc.")
+	want := []string{"acmerobotics", "acme-robotics", "acme"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SlugGuesses = %v, want %v", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/careers", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<iframe src="https://boards.greenhouse.io/embed/job_board?for=acme"></iframe>`))
+	})
+	mux.HandleFunc("/v1/boards/acme", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "Acme Corp"}`))
+	})
+	mux.HandleFunc("/v1/boards/acme/jobs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jobs": [{"id": 1}, {"id": 2}]}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	origBoard, origJobs := greenhouseBoardURL, greenhouseJobsURL
+	greenhouseBoardURL, greenhouseJobsURL = ts.URL+"/v1/boards/%s", ts.URL+"/v1/boards/%s/jobs"
+	defer func() { greenhouseBoardURL, greenhouseJobsURL = origBoard, origJobs }()
+
+	res, err := Run(ts.URL + "/careers")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	best, ok := res.Best()
+	if !ok || best.Slug != "acme" || best.Name != "Acme Corp" || best.Jobs != 2 {
+		t.Errorf("Best = %+v, %v", best, ok)
+	}
+}
@@ -4,17 +4,38 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
 )
 
-// ExportCSV exports all rows from job_applications to the given CSV file
-func ExportCSV(d *db.DB, path string) error {
-	rows, err := d.Conn.Query(`SELECT title, company, location, type, url, date_added, status FROM job_applications ORDER BY date_added DESC`)
+// ExportCSV exports every open job to the given CSV file. With a userID
+// the CSV gets Status and Tags columns holding that dashboard user's
+// statuses and sorted, semicolon-separated tags; with 0 it has neither.
+func ExportCSV(d *db.DB, path string, userID int) error {
+	return exportCSV(d, path, UserFilter(userID))
+}
+
+// ExportProfileCSV exports the open jobs tagged with a search profile,
+// with statuses and tags like ExportCSV.
+func ExportProfileCSV(d *db.DB, profile, path string, userID int) error {
+	filter := UserFilter(userID)
+	filter.Profile = profile
+	return exportCSV(d, path, filter)
+}
+
+// UserFilter selects the jobs an export lists for a dashboard user, or for
+// no one with 0: every open job, including the ones the user hid, with the
+// user's statuses and tags.
+func UserFilter(userID int) db.JobFilter {
+	return db.JobFilter{UserID: userID, IncludeHidden: true}
+}
+
+func exportCSV(d *db.DB, path string, filter db.JobFilter) error {
+	jobs, err := d.ListJobs(filter, 0, 0)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
 	f, err := os.Create(path)
 	if err != nil {
@@ -23,23 +44,27 @@ func ExportCSV(d *db.DB, path string) error {
 	defer f.Close()
 
 	w := csv.NewWriter(f)
-	defer w.Flush()
 
 	// header
-	if err := w.Write([]string{"Title", "Company", "Location", "Type", "URL", "Date Added", "Status"}); err != nil {
+	header := []string{"Title", "Company", "Location", "Type", "URL", "Date Added"}
+	if filter.UserID > 0 {
+		header = append(header, "Status", "Tags")
+	}
+	if err := w.Write(header); err != nil {
 		return err
 	}
 
-	for rows.Next() {
-		var title, company, location, typ, url, dateAdded, status string
-		if err := rows.Scan(&title, &company, &location, &typ, &url, &dateAdded, &status); err != nil {
-			return err
+	for _, j := range jobs {
+		row := []string{j.Title, j.Company, j.Location, j.Type, j.URL, j.DateAdded.UTC().Format("2006-01-02 15:04:05")}
+		if filter.UserID > 0 {
+			row = append(row, j.Status, strings.Join(j.Tags, ";"))
 		}
-		if err := w.Write([]string{title, company, location, typ, url, dateAdded, status}); err != nil {
+		if err := w.Write(row); err != nil {
 			return err
 		}
 	}
-	if err := rows.Err(); err != nil {
+	w.Flush()
+	if err := w.Error(); err != nil {
 		return err
 	}
 	fmt.Printf("Wrote CSV to %s\n", path)
@@ -0,0 +1,101 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/db"
+)
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestExportCSV(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DB_PATH", filepath.Join(dir, "jobs.db"))
+	d, err := db.Connect()
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer d.Close()
+
+	for _, url := range []string{"https://x/1", "https://x/2", "https://x/3"} {
+		if _, err := d.RecordJobSeen(db.Job{Title: "Engineer", Company: "Acme", Location: "Remote", URL: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Conn.Exec(`UPDATE job_applications SET closed_at = CURRENT_TIMESTAMP WHERE id = 3`); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.CreateUser("alice", "password"); err != nil {
+		t.Fatal(err)
+	}
+	alice, _, _, err := d.GetUserByUsername("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []string{"referral", "dream", "remote"} {
+		if err := d.AddJobTag(alice, 1, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.SetJobStatus(db.DefaultPipeline, alice, 1, "Applied"); err != nil {
+		t.Fatal(err)
+	}
+	// Hidden jobs stay in the user's export
+	if err := d.SetJobHidden(alice, 2, true); err != nil {
+		t.Fatal(err)
+	}
+
+	plain := filepath.Join(dir, "plain.csv")
+	if err := ExportCSV(d, plain, 0); err != nil {
+		t.Fatalf("ExportCSV: %v", err)
+	}
+	rows := readCSV(t, plain)
+	if want := []string{"Title", "Company", "Location", "Type", "URL", "Date Added"}; !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("header = %v, want %v", rows[0], want)
+	}
+	if len(rows) != 3 {
+		t.Errorf("%d rows, want 2 open jobs and a header", len(rows))
+	}
+
+	withUser := filepath.Join(dir, "alice.csv")
+	if err := ExportCSV(d, withUser, alice); err != nil {
+		t.Fatalf("ExportCSV(alice): %v", err)
+	}
+	rows = readCSV(t, withUser)
+	if want := []string{"Title", "Company", "Location", "Type", "URL", "Date Added", "Status", "Tags"}; !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("header = %v, want %v", rows[0], want)
+	}
+	got := map[string][]string{}
+	for _, row := range rows[1:] {
+		got[row[4]] = row[6:]
+	}
+	want := map[string][]string{
+		"https://x/1": {"Applied", "dream;referral;remote"},
+		"https://x/2": {db.StatusNotApplied, ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("status and tags = %v, want %v", got, want)
+	}
+
+	// The JSON and HTML exports list the same jobs for the user
+	jobs, err := d.ListJobs(UserFilter(alice), 0, 0)
+	if err != nil || len(jobs) != 2 {
+		t.Errorf("ListJobs(UserFilter) = %d jobs, %v; want both open jobs, hidden included", len(jobs), err)
+	}
+}
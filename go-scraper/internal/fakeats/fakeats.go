@@ -0,0 +1,230 @@
+// Package fakeats serves job board API responses from fixture files, so the
+// whole pipeline can run without network access. It emulates:
+//
+//	Greenhouse  GET /v1/boards/{slug}/jobs
+//	Lever       GET /v0/postings/{slug}?skip=N&limit=N
+//
+// Responses are read from <dir>/<platform>/<slug>.json, or .json.gz, so an
+// archived scrape run directory can be served as fixtures. Latency, rate
+// limiting, 5xx bursts and malformed bodies can be injected for every board
+// or per board, see Faults.
+package fakeats
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Faults are the failures injected into a board's responses.
+type Faults struct {
+	Latency        time.Duration `yaml:"latency"`          // delay before every response
+	RateLimitEvery int           `yaml:"rate_limit_every"` // every Nth request gets a 429
+	RetryAfter     int           `yaml:"retry_after"`      // Retry-After seconds sent with a 429; 0 omits it
+	ErrorBurst     int           `yaml:"error_burst"`      // consecutive 503s at the start of each cycle
+	ErrorEvery     int           `yaml:"error_every"`      // cycle length in requests; 0 means only the first burst
+	Malformed      bool          `yaml:"malformed"`        // truncate bodies so they are not valid JSON
+	Status         int           `yaml:"status"`           // always answer with this status, e.g. 404
+}
+
+// faultsFile is the optional <dir>/faults.yaml, keyed by "platform/slug":
+//
+//	boards:
+//	  greenhouse/acme: {error_burst: 2}
+//	  lever/beta: {rate_limit_every: 2, retry_after: 1}
+type faultsFile struct {
+	Boards map[string]Faults `yaml:"boards"`
+}
+
+// Server is an http.Handler serving fixtures from Dir.
+type Server struct {
+	Dir      string
+	Defaults Faults            // applied to boards without an entry in Boards
+	Boards   map[string]Faults // per-board faults, keyed by "platform/slug"
+
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+// New returns a server for the fixtures in dir, reading per-board faults
+// from dir/faults.yaml when it exists.
+func New(dir string, defaults Faults) (*Server, error) {
+	s := &Server{Dir: dir, Defaults: defaults, Boards: map[string]Faults{}, hits: map[string]int{}}
+	data, err := os.ReadFile(filepath.Join(dir, "faults.yaml"))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ff faultsFile
+	if err := yaml.Unmarshal(data, &ff); err != nil {
+		return nil, fmt.Errorf("faults.yaml: %w", err)
+	}
+	for k, f := range ff.Boards {
+		s.Boards[k] = f
+	}
+	return s, nil
+}
+
+// Hits returns how many requests a board ("platform/slug") has received.
+func (s *Server) Hits(board string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[board]
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" {
+		fmt.Fprintln(w, "ok")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	platform, slug, ok := route(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	board := platform + "/" + slug
+
+	s.mu.Lock()
+	s.hits[board]++
+	n := s.hits[board]
+	s.mu.Unlock()
+
+	f, ok := s.Boards[board]
+	if !ok {
+		f = s.Defaults
+	}
+	if f.Latency > 0 {
+		select {
+		case <-time.After(f.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	switch {
+	case f.Status != 0:
+		http.Error(w, http.StatusText(f.Status), f.Status)
+		return
+	case inBurst(n, f.ErrorBurst, f.ErrorEvery):
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	case f.RateLimitEvery > 0 && n%f.RateLimitEvery == 0:
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+		}
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+		return
+	}
+
+	body, err := s.fixture(platform, slug)
+	if os.IsNotExist(err) {
+		http.Error(w, `{"error":"board not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if platform == "lever" {
+		if body, err = page(body, r.URL.Query()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if f.Malformed {
+		body = body[:len(body)/2]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// route maps an API path to its board.
+func route(path string) (platform, slug string, ok bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) == 4 && parts[0] == "v1" && parts[1] == "boards" && parts[3] == "jobs":
+		return "greenhouse", parts[2], parts[2] != ""
+	case len(parts) == 3 && parts[0] == "v0" && parts[1] == "postings":
+		return "lever", parts[2], parts[2] != ""
+	}
+	return "", "", false
+}
+
+// inBurst reports whether the nth request (from 1) falls in an error burst.
+func inBurst(n, burst, every int) bool {
+	if burst <= 0 {
+		return false
+	}
+	if every <= 0 {
+		return n <= burst
+	}
+	return (n-1)%every < burst
+}
+
+// fixture reads a board's response, plain or gzip-compressed.
+func (s *Server) fixture(platform, slug string) ([]byte, error) {
+	if strings.ContainsAny(slug, `/\`) || strings.HasPrefix(slug, ".") {
+		return nil, os.ErrNotExist
+	}
+	path := filepath.Join(s.Dir, platform, slug+".json")
+	if body, err := os.ReadFile(path); !os.IsNotExist(err) {
+		return body, err
+	}
+	f, err := os.Open(path + ".gz")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s.gz: %w", path, err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// page applies Lever's skip and limit parameters to a postings array.
+func page(body []byte, q map[string][]string) ([]byte, error) {
+	get := func(k string) int {
+		if v := q[k]; len(v) > 0 {
+			if n, err := strconv.Atoi(v[0]); err == nil && n >= 0 {
+				return n
+			}
+		}
+		return -1
+	}
+	skip, limit := get("skip"), get("limit")
+	if skip < 0 && limit < 0 {
+		return body, nil
+	}
+	var postings []json.RawMessage
+	if err := json.Unmarshal(body, &postings); err != nil {
+		return nil, fmt.Errorf("lever fixture: %w", err)
+	}
+	if skip < 0 {
+		skip = 0
+	}
+	if skip > len(postings) {
+		skip = len(postings)
+	}
+	postings = postings[skip:]
+	if limit >= 0 && limit < len(postings) {
+		postings = postings[:limit]
+	}
+	return json.Marshal(postings)
+}
@@ -0,0 +1,110 @@
+package fakeats
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func get(t *testing.T, url string) (*http.Response, []byte) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestServeFixtures(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "greenhouse"), 0755)
+	os.MkdirAll(filepath.Join(dir, "lever"), 0755)
+	os.WriteFile(filepath.Join(dir, "greenhouse", "acme.json"), []byte(`{"jobs":[{"id":1,"title":"Engineer"}]}`), 0644)
+	f, _ := os.Create(filepath.Join(dir, "lever", "beta.json.gz"))
+	zw := gzip.NewWriter(f)
+	zw.Write([]byte(`[{"id":"a"},{"id":"b"},{"id":"c"}]`))
+	zw.Close()
+	f.Close()
+
+	s, err := New(dir, Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, body := get(t, ts.URL+"/v1/boards/acme/jobs?content=true")
+	if resp.StatusCode != 200 || string(body) != `{"jobs":[{"id":1,"title":"Engineer"}]}` {
+		t.Errorf("greenhouse = %d %s", resp.StatusCode, body)
+	}
+
+	var page []map[string]string
+	_, body = get(t, ts.URL+"/v0/postings/beta?mode=json&skip=1&limit=1")
+	if err := json.Unmarshal(body, &page); err != nil || len(page) != 1 || page[0]["id"] != "b" {
+		t.Errorf("lever page = %s, %v", body, err)
+	}
+	_, body = get(t, ts.URL+"/v0/postings/beta?mode=json&skip=5&limit=2")
+	if string(body) != "[]" {
+		t.Errorf("lever past end = %s", body)
+	}
+
+	if resp, _ := get(t, ts.URL+"/v1/boards/missing/jobs"); resp.StatusCode != 404 {
+		t.Errorf("missing board status = %d", resp.StatusCode)
+	}
+	if got := s.Hits("lever/beta"); got != 2 {
+		t.Errorf("Hits = %d, want 2", got)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "greenhouse"), 0755)
+	for _, slug := range []string{"flaky", "limited", "broken"} {
+		os.WriteFile(filepath.Join(dir, "greenhouse", slug+".json"), []byte(`{"jobs":[]}`), 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "faults.yaml"), []byte(`boards:
+  greenhouse/flaky: {error_burst: 2, error_every: 4}
+  greenhouse/limited: {rate_limit_every: 2, retry_after: 7}
+`), 0644)
+
+	s, err := New(dir, Faults{Malformed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var codes []int
+	for i := 0; i < 6; i++ {
+		resp, _ := get(t, ts.URL+"/v1/boards/flaky/jobs")
+		codes = append(codes, resp.StatusCode)
+	}
+	want := []int{503, 503, 200, 200, 503, 503}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("flaky statuses = %v, want %v", codes, want)
+		}
+	}
+
+	get(t, ts.URL+"/v1/boards/limited/jobs")
+	resp, _ := get(t, ts.URL+"/v1/boards/limited/jobs")
+	if resp.StatusCode != 429 || resp.Header.Get("Retry-After") != "7" {
+		t.Errorf("limited = %d Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	// broken has no entry in faults.yaml, so the defaults apply
+	_, body := get(t, ts.URL+"/v1/boards/broken/jobs")
+	if json.Valid(body) {
+		t.Errorf("malformed body %s is valid JSON", body)
+	}
+}
@@ -0,0 +1,152 @@
+// Package markdown renders the Markdown used in job notes as HTML that is
+// safe to put on a page. The source is HTML-escaped before any markup is
+// added, so raw HTML in a note always shows as text, and links keep only
+// http, https and mailto targets.
+//
+// It covers headings, paragraphs, bullet and numbered lists, quotes, fenced
+// code blocks, inline code, bold, italics and links.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletRe  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	numberRe  = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	quoteRe   = regexp.MustCompile(`^>\s?(.*)$`)
+	linkRe    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongRe  = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	emRe      = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+)
+
+// ToHTML renders src as HTML. Headings start at <h3>, below the page's own.
+func ToHTML(src string) string {
+	var (
+		b     strings.Builder
+		para  []string // lines of the open paragraph or quote
+		block string   // "p", "blockquote", "ul" or "ol" while one is open
+		code  []string // lines of the open fenced code block
+		fence bool
+	)
+	closeBlock := func() {
+		switch block {
+		case "p", "blockquote":
+			b.WriteString("<" + block + ">" + strings.Join(para, "<br>") + "</" + block + ">\n")
+		case "ul", "ol":
+			b.WriteString("</" + block + ">\n")
+		}
+		para, block = nil, ""
+	}
+	open := func(kind string) {
+		if block != kind {
+			closeBlock()
+			block = kind
+			if kind == "ul" || kind == "ol" {
+				b.WriteString("<" + kind + ">\n")
+			}
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if fence {
+				b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+				code, fence = nil, false
+			} else {
+				closeBlock()
+				fence = true
+			}
+			continue
+		}
+		if fence {
+			code = append(code, line)
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			closeBlock()
+		case headingRe.MatchString(line):
+			closeBlock()
+			m := headingRe.FindStringSubmatch(line)
+			level := len(m[1]) + 2
+			if level > 6 {
+				level = 6
+			}
+			tag := "h" + string(rune('0'+level))
+			b.WriteString("<" + tag + ">" + inline(m[2]) + "</" + tag + ">\n")
+		case bulletRe.MatchString(line):
+			open("ul")
+			b.WriteString("<li>" + inline(bulletRe.FindStringSubmatch(line)[1]) + "</li>\n")
+		case numberRe.MatchString(line):
+			open("ol")
+			b.WriteString("<li>" + inline(numberRe.FindStringSubmatch(line)[1]) + "</li>\n")
+		case quoteRe.MatchString(line):
+			open("blockquote")
+			para = append(para, inline(quoteRe.FindStringSubmatch(line)[1]))
+		default:
+			open("p")
+			para = append(para, inline(line))
+		}
+	}
+	// An unclosed fence runs to the end of the note
+	if fence {
+		b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+	}
+	closeBlock()
+	return b.String()
+}
+
+// inline escapes one line and renders its code spans, links and emphasis.
+func inline(s string) string {
+	var b strings.Builder
+	// Odd parts between backticks are code spans
+	parts := strings.Split(s, "`")
+	for i, part := range parts {
+		switch {
+		case i%2 == 1 && i < len(parts)-1:
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+		case i%2 == 1:
+			b.WriteString("`" + links(part))
+		default:
+			b.WriteString(links(part))
+		}
+	}
+	return b.String()
+}
+
+// links renders the links in s, emphasising the text around and inside
+// them but never their targets.
+func links(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range linkRe.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(emphasis(s[last:m[0]]))
+		text, target := s[m[2]:m[3]], s[m[4]:m[5]]
+		if safeTarget(target) {
+			b.WriteString(`<a href="` + html.EscapeString(target) + `" target="_blank" rel="noopener noreferrer">` + emphasis(text) + "</a>")
+		} else {
+			b.WriteString(emphasis(text))
+		}
+		last = m[1]
+	}
+	b.WriteString(emphasis(s[last:]))
+	return b.String()
+}
+
+// emphasis escapes s and renders **bold** and *italic* text.
+func emphasis(s string) string {
+	s = html.EscapeString(s)
+	s = strongRe.ReplaceAllString(s, "<strong>$1</strong>")
+	return emRe.ReplaceAllString(s, "<em>$1</em>")
+}
+
+// safeTarget reports whether a link target may become an href.
+func safeTarget(target string) bool {
+	t := strings.ToLower(target)
+	return strings.HasPrefix(t, "http://") || strings.HasPrefix(t, "https://") || strings.HasPrefix(t, "mailto:")
+}
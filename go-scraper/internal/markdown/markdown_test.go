@@ -0,0 +1,59 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Called **Dana** the *recruiter*", "<p>Called <strong>Dana</strong> the <em>recruiter</em></p>\n"},
+		{"line one\nline two\n\nnext", "<p>line one<br>line two</p>\n<p>next</p>\n"},
+		{"# Prep", "<h3>Prep</h3>\n"},
+		{"- SQL\n- Go", "<ul>\n<li>SQL</li>\n<li>Go</li>\n</ul>\n"},
+		{"1. Screen\n2. Onsite", "<ol>\n<li>Screen</li>\n<li>Onsite</li>\n</ol>\n"},
+		{"> quoted", "<blockquote>quoted</blockquote>\n"},
+		{"run `go test`", "<p>run <code>go test</code></p>\n"},
+		{"```\nif a < b {}\n```", "<pre><code>if a &lt; b {}</code></pre>\n"},
+		{"[offer](https://x.com/a?b=1&c=2)", `<p><a href="https://x.com/a?b=1&amp;c=2" target="_blank" rel="noopener noreferrer">offer</a></p>` + "\n"},
+		{"[*x*](https://x.com/*y*)", `<p><a href="https://x.com/*y*" target="_blank" rel="noopener noreferrer"><em>x</em></a></p>` + "\n"},
+	}
+	for _, tt := range tests {
+		if got := ToHTML(tt.in); got != tt.want {
+			t.Errorf("ToHTML(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToHTMLEscapes(t *testing.T) {
+	for _, in := range []string{
+		`<script>alert(1)</script>`,
+		"- <script>alert(1)</script>",
+		"# <script>alert(1)</script>",
+		"**<script>alert(1)</script>**",
+		"`<script>alert(1)</script>`",
+		"```\n<script>alert(1)</script>\n```",
+		`[<script>alert(1)</script>](https://x.com)`,
+	} {
+		got := ToHTML(in)
+		if strings.Contains(got, "<script") || !strings.Contains(got, "&lt;script&gt;") {
+			t.Errorf("ToHTML(%q) = %q, want the script tag escaped", in, got)
+		}
+	}
+
+	for _, in := range []string{
+		`[click](javascript:alert(1))`,
+		`[click](JavaScript:alert(1))`,
+		`[click](data:text/html,x)`,
+	} {
+		if got := ToHTML(in); strings.Contains(got, "href") {
+			t.Errorf("ToHTML(%q) = %q, want no link", in, got)
+		}
+	}
+	if got := ToHTML(`[x](https://a.com/"onmouseover="alert(1))`); strings.Contains(got, `"onmouseover`) {
+		t.Errorf("link target broke out of the href: %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// Board identifies one company's job board on a supported platform.
+type Board struct {
+	Platform string // "greenhouse" or "lever"
+	Slug     string // company identifier on the platform
+	Name     string // display name stored on jobs; defaults to CompanyName(Slug)
+	Host     string // optional API host override, e.g. "api.eu.lever.co"
+}
+
+// DisplayName returns the company name stored on the board's jobs.
+func (b Board) DisplayName() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return CompanyName(b.Slug)
+}
+
+// Platforms lists the supported board platforms in the order they are scraped.
+var Platforms = []string{"greenhouse", "lever"}
+
+// Fetch fetches every job on a board without filtering.
+func Fetch(b Board) ([]Job, error) {
+	body, err := FetchRaw(b)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b, body)
+}
+
+// FetchRaw returns a board's API response as received, for archiving and
+// later reprocessing with Parse.
+func FetchRaw(b Board) ([]byte, error) {
+	switch b.Platform {
+	case "greenhouse":
+		return fetchGreenhouseBody(b)
+	case "lever":
+		return fetchLeverBody(b)
+	default:
+		return nil, fmt.Errorf("unsupported platform %q", b.Platform)
+	}
+}
+
+// Parse converts a board's raw API response into jobs, without filtering.
+func Parse(b Board, body []byte) ([]Job, error) {
+	switch b.Platform {
+	case "greenhouse":
+		return ParseGreenhouse(b, body)
+	case "lever":
+		return ParseLever(b, body)
+	default:
+		return nil, fmt.Errorf("unsupported platform %q", b.Platform)
+	}
+}
+
+// APIBaseEnv names the environment variable that, when set to a base URL
+// such as http://localhost:8089, sends every board API request there
+// instead, e.g. to cmd/fake-ats.
+const APIBaseEnv = "SCRAPER_API_BASE"
+
+// apiURL applies a board's host override and then the APIBaseEnv override.
+func apiURL(raw, host string) string {
+	raw = withHost(raw, host)
+	base := os.Getenv(APIBaseEnv)
+	if base == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return raw
+	}
+	u.Scheme, u.Host = b.Scheme, b.Host
+	return u.String()
+}
+
+// withHost swaps the host of an API URL, leaving it unchanged when host is empty.
+func withHost(raw, host string) string {
+	if host == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.Host = host
+	return u.String()
+}
@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Filter decides which fetched jobs are kept.
+type Filter struct {
+	EarlyCareer   bool             // keep only early-career titles (see isEarlyCareer)
+	USOnly        bool             // keep only US or remote locations (see isInUSA)
+	IncludeTitles []*regexp.Regexp // if set, the title must match at least one
+	ExcludeTitles []*regexp.Regexp // drop titles matching any
+	Locations     []string         // if set, the location must contain one (case-insensitive)
+}
+
+// DefaultFilter is the early-career, US-only filter used when nothing else
+// is configured.
+func DefaultFilter() Filter {
+	return Filter{EarlyCareer: true, USOnly: true}
+}
+
+// Match reports whether a job passes the filter.
+func (f Filter) Match(j Job) bool {
+	if f.EarlyCareer && !isEarlyCareer(j.Title) {
+		return false
+	}
+	if f.USOnly && !isInUSA(j.Location) {
+		return false
+	}
+	if len(f.IncludeTitles) > 0 && !matchAny(f.IncludeTitles, j.Title) {
+		return false
+	}
+	if matchAny(f.ExcludeTitles, j.Title) {
+		return false
+	}
+	if len(f.Locations) > 0 {
+		loc := strings.ToLower(j.Location)
+		found := false
+		for _, l := range f.Locations {
+			if strings.Contains(loc, strings.ToLower(l)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the jobs that pass the filter.
+func (f Filter) Apply(jobs []Job) []Job {
+	var out []Job
+	for _, j := range jobs {
+		if f.Match(j) {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
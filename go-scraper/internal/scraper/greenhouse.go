@@ -12,6 +12,7 @@ import (
 )
 
 type greenhouseJob struct {
+	ID       int64  `json:"id"`
 	Title    string `json:"title"`
 	Absolute string `json:"absolute_url"`
 	Location struct {
@@ -20,6 +21,7 @@ type greenhouseJob struct {
 	Department struct {
 		Name string `json:"name"`
 	} `json:"department"`
+	Content string `json:"content"` // HTML-escaped description, present with ?content=true
 }
 
 type greenhouseResponse struct {
@@ -33,12 +35,25 @@ type Job struct {
 	Location string
 	URL      string
 	Type     string
+
+	// Description is the plain-text posting body, used to detect edits.
+	Description string
+
+	// Source names the board platform and SourceJobID is the posting's ID
+	// there, which stays stable when the URL changes.
+	Source      string
+	SourceJobID string
 }
 
 var seniorRe = regexp.MustCompile(`(?i)\b(senior|sr\.|lead|staff|principal|manager|director|architect|vp|head of|chief)\b`)
 var earlyCareerRe = regexp.MustCompile(`(?i)\b(intern|internship|new grad|new graduate|associate|junior|entry level|entry-level|rotational|co-op|fellow|apprentice)\b`)
 var usaLocs = []string{"united states", "usa", "us", "remote", "new york", "san francisco", "seattle", "austin", "boston", "chicago", "los angeles", "atlanta"}
 
+// CompanyName returns the display name stored on jobs scraped for a board slug.
+func CompanyName(slug string) string {
+	return strings.Title(slug)
+}
+
 func isEarlyCareer(title string) bool {
 	t := strings.ToLower(title)
 	if seniorRe.MatchString(t) {
@@ -71,7 +86,25 @@ var greenhouseAPIURL = "https://api.greenhouse.io/v1/boards/%s/jobs"
 
 // ScrapeGreenhouse fetches and filters jobs for a given company identifier
 func ScrapeGreenhouse(company string) ([]Job, error) {
-	url := fmt.Sprintf(greenhouseAPIURL+"?content=true", company)
+	jobs, err := FetchGreenhouse(Board{Platform: "greenhouse", Slug: company})
+	if err != nil {
+		return nil, err
+	}
+	return DefaultFilter().Apply(jobs), nil
+}
+
+// FetchGreenhouse fetches every job on a Greenhouse board, unfiltered.
+func FetchGreenhouse(b Board) ([]Job, error) {
+	body, err := fetchGreenhouseBody(b)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGreenhouse(b, body)
+}
+
+// fetchGreenhouseBody returns the raw jobs API response for a board.
+func fetchGreenhouseBody(b Board) ([]byte, error) {
+	url := apiURL(fmt.Sprintf(greenhouseAPIURL+"?content=true", b.Slug), b.Host)
 	client := &http.Client{Timeout: 20 * time.Second}
 
 	var resp *http.Response
@@ -130,7 +163,11 @@ func ScrapeGreenhouse(company string) ([]Job, error) {
 		return nil, fmt.Errorf("status %d", resp.StatusCode)
 	}
 
-	body, _ := ioutil.ReadAll(resp.Body)
+	return ioutil.ReadAll(resp.Body)
+}
+
+// ParseGreenhouse converts a raw Greenhouse jobs API response into jobs.
+func ParseGreenhouse(b Board, body []byte) ([]Job, error) {
 	var gr greenhouseResponse
 	if err := json.Unmarshal(body, &gr); err != nil {
 		return nil, err
@@ -138,17 +175,17 @@ func ScrapeGreenhouse(company string) ([]Job, error) {
 
 	var out []Job
 	for _, j := range gr.Jobs {
-		title := j.Title
-		loc := j.Location.Name
-		if isEarlyCareer(title) && isInUSA(loc) {
-			out = append(out, Job{
-				Title:    title,
-				Company:  strings.Title(company),
-				Location: loc,
-				URL:      j.Absolute,
-				Type:     j.Department.Name,
-			})
-		}
+		out = append(out, Job{
+			Title:    j.Title,
+			Company:  b.DisplayName(),
+			Location: j.Location.Name,
+			URL:      j.Absolute,
+			Type:     j.Department.Name,
+
+			Description: htmlToText(j.Content),
+			Source:      "greenhouse",
+			SourceJobID: strconv.FormatInt(j.ID, 10),
+		})
 	}
 	return out, nil
 }
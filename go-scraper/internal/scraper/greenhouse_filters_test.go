@@ -1,6 +1,9 @@
 package scraper
 
-import "testing"
+import (
+	"regexp"
+	"testing"
+)
 
 func TestIsEarlyCareer(t *testing.T) {
 	cases := []struct {
@@ -38,3 +41,34 @@ func TestIsInUSA(t *testing.T) {
 		}
 	}
 }
+
+func TestHTMLToText(t *testing.T) {
+	in := `&lt;p&gt;Build &amp;amp; ship&lt;/p&gt;&lt;ul&gt;&lt;li&gt;Go&lt;/li&gt;&lt;li&gt;SQL&lt;/li&gt;&lt;/ul&gt;`
+	want := "Build & ship\nGo\nSQL"
+	if got := htmlToText(in); got != want {
+		t.Errorf("htmlToText() = %q, want %q", got, want)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	f := Filter{
+		IncludeTitles: []*regexp.Regexp{regexp.MustCompile(`(?i)data|ml`)},
+		ExcludeTitles: []*regexp.Regexp{regexp.MustCompile(`(?i)senior`)},
+		Locations:     []string{"Toronto", "Remote"},
+	}
+	cases := []struct {
+		job  Job
+		want bool
+	}{
+		{Job{Title: "Data Analyst", Location: "Toronto, Canada"}, true},
+		{Job{Title: "ML Engineer", Location: "remote"}, true},
+		{Job{Title: "Senior Data Engineer", Location: "Toronto"}, false},
+		{Job{Title: "Backend Engineer", Location: "Toronto"}, false},
+		{Job{Title: "Data Analyst", Location: "London, UK"}, false},
+	}
+	for _, c := range cases {
+		if got := f.Match(c.job); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.job.Title, c.job.Location, got, c.want)
+		}
+	}
+}
@@ -10,8 +10,10 @@ import (
 )
 
 type leverJob struct {
+	ID       string `json:"id"`
 	Text     string `json:"text"`      // Job title
 	Hostedurl string `json:"hostedUrl"` // Job posting URL
+	DescriptionPlain string `json:"descriptionPlain"`
 	Categories struct {
 		Location     string `json:"location"`
 		Commitment   string `json:"commitment"`
@@ -25,7 +27,53 @@ var leverAPIURL = "https://api.lever.co/v0/postings/%s?mode=json"
 
 // ScrapeLever fetches and filters jobs from Lever's API
 func ScrapeLever(company string) ([]Job, error) {
-	url := fmt.Sprintf(leverAPIURL, company)
+	jobs, err := FetchLever(Board{Platform: "lever", Slug: company})
+	if err != nil {
+		return nil, err
+	}
+	return DefaultFilter().Apply(jobs), nil
+}
+
+// FetchLever fetches every posting on a Lever board, unfiltered.
+func FetchLever(b Board) ([]Job, error) {
+	body, err := fetchLeverBody(b)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLever(b, body)
+}
+
+// leverPageSize is how many postings are requested per page.
+var leverPageSize = 100
+
+// fetchLeverBody returns every posting on a board as one JSON array,
+// following skip/limit pages until a short page.
+func fetchLeverBody(b Board) ([]byte, error) {
+	base := apiURL(fmt.Sprintf(leverAPIURL, b.Slug), b.Host)
+	var all []json.RawMessage
+	for skip := 0; ; skip += leverPageSize {
+		body, err := leverGet(fmt.Sprintf("%s&skip=%d&limit=%d", base, skip, leverPageSize))
+		if err != nil {
+			return nil, err
+		}
+		var page []json.RawMessage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < leverPageSize {
+			break
+		}
+	}
+	if all == nil {
+		all = []json.RawMessage{}
+	}
+	return json.Marshal(all)
+}
+
+// leverGet fetches one page of postings, retrying network errors, 429s and
+// 5xx responses.
+func leverGet(url string) ([]byte, error) {
 	client := &http.Client{Timeout: 20 * time.Second}
 
 	var resp *http.Response
@@ -69,8 +117,11 @@ func ScrapeLever(company string) ([]Job, error) {
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("status %d", resp.StatusCode)
 	}
+	return ioutil.ReadAll(resp.Body)
+}
 
-	body, _ := ioutil.ReadAll(resp.Body)
+// ParseLever converts a raw Lever postings API response into jobs.
+func ParseLever(b Board, body []byte) ([]Job, error) {
 	var jobs []leverJob
 	if err := json.Unmarshal(body, &jobs); err != nil {
 		return nil, err
@@ -78,17 +129,17 @@ func ScrapeLever(company string) ([]Job, error) {
 
 	var out []Job
 	for _, j := range jobs {
-		title := j.Text
-		loc := j.Categories.Location
-		if isEarlyCareer(title) && isInUSA(loc) {
-			out = append(out, Job{
-				Title:    title,
-				Company:  strings.Title(company),
-				Location: loc,
-				URL:      j.Hostedurl,
-				Type:     j.Categories.Team,
-			})
-		}
+		out = append(out, Job{
+			Title:    j.Text,
+			Company:  b.DisplayName(),
+			Location: j.Categories.Location,
+			URL:      j.Hostedurl,
+			Type:     j.Categories.Team,
+
+			Description: strings.TrimSpace(j.DescriptionPlain),
+			Source:      "lever",
+			SourceJobID: j.ID,
+		})
 	}
 	return out, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,61 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/fakeats"
+)
+
+func TestFetchLeverPaginates(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "lever"), 0755)
+	var postings []map[string]interface{}
+	for i := 0; i < 5; i++ {
+		postings = append(postings, map[string]interface{}{
+			"id": fmt.Sprint(i), "text": "Software Engineer", "hostedUrl": fmt.Sprintf("https://jobs.lever.co/acme/%d", i),
+			"categories": map[string]string{"location": "Austin, TX"},
+		})
+	}
+	body, _ := json.Marshal(postings)
+	os.WriteFile(filepath.Join(dir, "lever", "acme.json"), body, 0644)
+
+	// The third page request is rate limited and must be retried
+	s, err := fakeats.New(dir, fakeats.Faults{RateLimitEvery: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	t.Setenv(APIBaseEnv, ts.URL)
+
+	oldSize := leverPageSize
+	leverPageSize = 2
+	defer func() { leverPageSize = oldSize }()
+
+	jobs, err := FetchLever(Board{Platform: "lever", Slug: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 5 {
+		t.Errorf("got %d jobs, want 5", len(jobs))
+	}
+	if got := s.Hits("lever/acme"); got != 4 {
+		t.Errorf("%d requests, want 4 (3 pages and a retry)", got)
+	}
+}
+
+func TestAPIURL(t *testing.T) {
+	t.Setenv(APIBaseEnv, "")
+	if got := apiURL("https://api.lever.co/v0/postings/x?mode=json", "api.eu.lever.co"); got != "https://api.eu.lever.co/v0/postings/x?mode=json" {
+		t.Errorf("host override = %s", got)
+	}
+	t.Setenv(APIBaseEnv, "http://localhost:8089")
+	if got := apiURL("https://api.greenhouse.io/v1/boards/x/jobs?content=true", ""); got != "http://localhost:8089/v1/boards/x/jobs?content=true" {
+		t.Errorf("base override = %s", got)
+	}
+}
@@ -0,0 +1,35 @@
+package scraper
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	blockTagRe = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/h[1-6]|/tr)\s*/?>`)
+	anyTagRe   = regexp.MustCompile(`<[^>]*>`)
+	spaceRe    = regexp.MustCompile(`[ \t\x{00a0}]+`)
+)
+
+// htmlToText converts a board's HTML description into plain text with one
+// paragraph or list item per line. Greenhouse escapes its HTML, so entities
+// are decoded before and after tags are stripped.
+func htmlToText(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = html.UnescapeString(s)
+	s = blockTagRe.ReplaceAllString(s, "\n")
+	s = anyTagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(spaceRe.ReplaceAllString(line, " "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,19 @@
+package textdiff
+
+import "testing"
+
+func TestLines(t *testing.T) {
+	old := "Intern\nGo\nSQL"
+	new := "New Grad\nGo\nSQL\nKubernetes"
+	want := "-Intern\n+New Grad\n Go\n SQL\n+Kubernetes\n"
+	if got := String(Lines(old, new)); got != want {
+		t.Errorf("Lines() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	diff := Changed(Lines("a\nb", "a\nc"))
+	if len(diff) != 2 || diff[0].Op != Delete || diff[1].Op != Insert {
+		t.Errorf("Changed() = %+v", diff)
+	}
+}
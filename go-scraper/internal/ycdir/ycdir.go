@@ -0,0 +1,310 @@
+// Package ycdir reads Y Combinator's public company directory, as published
+// in JSON (e.g. the yc-oss "all companies" dump) or exported to CSV.
+package ycdir
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ajiteshreddy7/yc-go-scraper/internal/dedupe"
+)
+
+// Company is one directory entry.
+type Company struct {
+	Name     string
+	Slug     string // the company's directory slug, not its ATS board
+	Batch    string // normalized, e.g. "W24"; the raw value if unrecognized
+	Industry string
+	TeamSize int
+	Status   string // Active, Acquired, Inactive, Public...
+	Website  string
+	LogoURL  string
+	ATSLinks []string // job board or careers URLs, when the dataset has them
+}
+
+// Field names accepted for each attribute, compared after lowercasing and
+// dropping everything but letters and digits, so "Team Size", "team_size"
+// and "teamSize" are the same column.
+var fields = map[string][]string{
+	"name":     {"name", "companyname"},
+	"slug":     {"slug"},
+	"batch":    {"batch", "ycbatch"},
+	"industry": {"industry", "industries", "vertical"},
+	"teamsize": {"teamsize", "employees", "headcount"},
+	"status":   {"status"},
+	"website":  {"website", "homepage"},
+	"logo":     {"smalllogothumburl", "logourl", "logo"},
+	"ats":      {"atsurl", "atslinks", "atslink", "jobsurl", "jobboard", "careersurl", "careers"},
+}
+
+var keyRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normKey(k string) string {
+	return keyRe.ReplaceAllString(strings.ToLower(k), "")
+}
+
+// Load reads a directory file, picking JSON or CSV by its extension.
+func Load(path string) ([]Company, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParseJSON(f)
+	case ".csv":
+		return ParseCSV(f)
+	}
+	return nil, fmt.Errorf("%s: unsupported directory format (want .json or .csv)", path)
+}
+
+// ParseJSON reads an array of company objects, or an object holding one
+// under "companies".
+func ParseJSON(r io.Reader) ([]Company, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(body, &records); err != nil {
+		var wrapped struct {
+			Companies []map[string]interface{} `json:"companies"`
+		}
+		if err2 := json.Unmarshal(body, &wrapped); err2 != nil || wrapped.Companies == nil {
+			return nil, err
+		}
+		records = wrapped.Companies
+	}
+
+	var out []Company
+	for _, rec := range records {
+		values := map[string][]string{}
+		for k, v := range rec {
+			values[normKey(k)] = jsonStrings(v)
+		}
+		if c, ok := fromValues(values); ok {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// jsonStrings flattens a JSON value to strings: arrays to their elements,
+// numbers without a trailing ".0".
+func jsonStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	case []interface{}:
+		var out []string
+		for _, e := range v {
+			out = append(out, jsonStrings(e)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// ParseCSV reads a CSV file with a header row. Multi-valued cells, such as
+// several ATS links, are separated by whitespace or ";".
+func ParseCSV(r io.Reader) ([]Company, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	for i := range header {
+		header[i] = normKey(header[i])
+	}
+
+	var out []Company
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		values := map[string][]string{}
+		for i, cell := range row {
+			if i < len(header) && strings.TrimSpace(cell) != "" {
+				values[header[i]] = []string{cell}
+			}
+		}
+		if c, ok := fromValues(values); ok {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func fromValues(values map[string][]string) (Company, bool) {
+	first := func(field string) string {
+		for _, k := range fields[field] {
+			for _, v := range values[k] {
+				if v = strings.TrimSpace(v); v != "" {
+					return v
+				}
+			}
+		}
+		return ""
+	}
+
+	c := Company{
+		Name:     first("name"),
+		Slug:     first("slug"),
+		Industry: first("industry"),
+		Status:   first("status"),
+		Website:  first("website"),
+		LogoURL:  first("logo"),
+	}
+	if c.Name == "" {
+		return c, false
+	}
+	c.Batch = first("batch")
+	if b, ok := ParseBatch(c.Batch); ok {
+		c.Batch = b.String()
+	}
+	if n, err := strconv.Atoi(strings.ReplaceAll(first("teamsize"), ",", "")); err == nil {
+		c.TeamSize = n
+	}
+	for _, k := range fields["ats"] {
+		for _, v := range values[k] {
+			for _, link := range strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == ' ' || r == '\n' || r == ',' }) {
+				c.ATSLinks = append(c.ATSLinks, link)
+			}
+		}
+	}
+	return c, true
+}
+
+// Batch is a YC batch such as Winter 2024.
+type Batch struct {
+	Season byte // W, X (spring), S or F
+	Year   int
+}
+
+// seasons in calendar order.
+const seasons = "WXSF"
+
+var (
+	shortBatchRe = regexp.MustCompile(`^([WXSF])(\d{2}|\d{4})$`)
+	longBatchRe  = regexp.MustCompile(`^(winter|spring|summer|fall)\s*(\d{2}|\d{4})$`)
+)
+
+// ParseBatch reads "W24", "S2023" or "Winter 2024".
+func ParseBatch(s string) (Batch, bool) {
+	s = strings.TrimSpace(s)
+	var season, year string
+	if m := shortBatchRe.FindStringSubmatch(strings.ToUpper(s)); m != nil {
+		season, year = m[1], m[2]
+	} else if m := longBatchRe.FindStringSubmatch(strings.ToLower(s)); m != nil {
+		season, year = map[string]string{"winter": "W", "spring": "X", "summer": "S", "fall": "F"}[m[1]], m[2]
+	} else {
+		return Batch{}, false
+	}
+	y, _ := strconv.Atoi(year)
+	if y < 100 {
+		y += 2000
+	}
+	return Batch{Season: season[0], Year: y}, true
+}
+
+// String returns the short form, e.g. "W24".
+func (b Batch) String() string {
+	return fmt.Sprintf("%c%02d", b.Season, b.Year%100)
+}
+
+// Key orders batches chronologically; it is 0 for the zero Batch.
+func (b Batch) Key() int {
+	if b.Year == 0 {
+		return 0
+	}
+	return b.Year*10 + strings.IndexByte(seasons, b.Season) + 1
+}
+
+// BatchKey returns the Key of a batch string, or 0 if it does not parse.
+func BatchKey(s string) int {
+	b, ok := ParseBatch(s)
+	if !ok {
+		return 0
+	}
+	return b.Key()
+}
+
+// Record is a company we already track, to match directory entries against.
+type Record struct {
+	ID      int
+	Name    string
+	Slug    string // ATS board slug
+	Website string
+}
+
+// Index matches directory entries to records by website domain, then by
+// normalized name or slug.
+type Index struct {
+	byDomain map[string][]int
+	byName   map[string][]int
+}
+
+// NewIndex indexes records for Match.
+func NewIndex(records []Record) *Index {
+	ix := &Index{byDomain: map[string][]int{}, byName: map[string][]int{}}
+	for _, r := range records {
+		if d := Domain(r.Website); d != "" {
+			ix.byDomain[d] = append(ix.byDomain[d], r.ID)
+		}
+		keys := map[string]bool{}
+		for _, k := range []string{dedupe.NormalizeCompany(r.Name), dedupe.NormalizeCompany(r.Slug)} {
+			if k != "" && !keys[k] {
+				keys[k] = true
+				ix.byName[k] = append(ix.byName[k], r.ID)
+			}
+		}
+	}
+	return ix
+}
+
+// Match returns the IDs of the records that are the directory company c.
+// A company may have several records, one per board.
+func (ix *Index) Match(c Company) []int {
+	if ids := ix.byDomain[Domain(c.Website)]; len(ids) > 0 {
+		return ids
+	}
+	if ids := ix.byName[dedupe.NormalizeCompany(c.Name)]; len(ids) > 0 {
+		return ids
+	}
+	return ix.byName[dedupe.NormalizeCompany(c.Slug)]
+}
+
+// Domain returns a website's host without "www.", or "" if it has none.
+func Domain(website string) string {
+	website = strings.TrimSpace(website)
+	if website == "" {
+		return ""
+	}
+	if !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+	u, err := url.Parse(website)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
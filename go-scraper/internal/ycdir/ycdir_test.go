@@ -0,0 +1,91 @@
+package ycdir
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBatch(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"W24", "W24", true},
+		{"s2023", "S23", true},
+		{"Winter 2024", "W24", true},
+		{"Spring 2025", "X25", true},
+		{"Fall 2024", "F24", true},
+		{"IK12", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		b, ok := ParseBatch(tt.in)
+		if ok != tt.ok || (ok && b.String() != tt.want) {
+			t.Errorf("ParseBatch(%q) = %v, %v; want %s, %v", tt.in, b, ok, tt.want, tt.ok)
+		}
+	}
+
+	order := []string{"S23", "F23", "W24", "X24", "S24", "F24"}
+	for i := 1; i < len(order); i++ {
+		if BatchKey(order[i-1]) >= BatchKey(order[i]) {
+			t.Errorf("BatchKey(%s) >= BatchKey(%s)", order[i-1], order[i])
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	in := `[
+		{"name": "Acme Robotics", "slug": "acme-robotics", "batch": "Winter 2024", "industry": "Industrials",
+		 "team_size": 12, "status": "Active", "website": "https://www.acme.dev", "ats_links": ["https://jobs.lever.co/acme"]},
+		{"name": "Beta", "industries": ["Fintech", "B2B"], "teamSize": "1,200", "batch": "IK12"},
+		{"slug": "nameless"}
+	]`
+	got, err := ParseJSON(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Company{
+		{Name: "Acme Robotics", Slug: "acme-robotics", Batch: "W24", Industry: "Industrials", TeamSize: 12, Status: "Active",
+			Website: "https://www.acme.dev", ATSLinks: []string{"https://jobs.lever.co/acme"}},
+		{Name: "Beta", Batch: "IK12", Industry: "Fintech", TeamSize: 1200},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseJSON =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	in := "Company Name,Batch,Team Size,Website,ATS URL\n" +
+		"Acme Robotics,S23,40,acme.dev,https://boards.greenhouse.io/acme; https://jobs.ashbyhq.com/acme\n"
+	got, err := ParseCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "Acme Robotics" || got[0].Batch != "S23" || got[0].TeamSize != 40 || len(got[0].ATSLinks) != 2 {
+		t.Errorf("ParseCSV = %+v", got)
+	}
+}
+
+func TestIndexMatch(t *testing.T) {
+	ix := NewIndex([]Record{
+		{ID: 1, Name: "Acme Robotics, Inc.", Slug: "acmerobotics", Website: "https://acme.dev"},
+		{ID: 2, Name: "Acme", Slug: "acme", Website: "https://acme.dev/"},
+		{ID: 3, Name: "Paloaltonetworks", Slug: "paloaltonetworks"},
+	})
+	tests := []struct {
+		c    Company
+		want []int
+	}{
+		{Company{Name: "Whatever", Website: "www.acme.dev"}, []int{1, 2}},
+		{Company{Name: "Palo Alto Networks"}, []int{3}},
+		{Company{Name: "PANW", Slug: "paloaltonetworks"}, []int{3}},
+		{Company{Name: "Unknown"}, nil},
+	}
+	for _, tt := range tests {
+		if got := ix.Match(tt.c); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Match(%+v) = %v, want %v", tt.c, got, tt.want)
+		}
+	}
+}
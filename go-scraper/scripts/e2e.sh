@@ -0,0 +1,30 @@
+#!/usr/bin/env bash
+# Runs the whole pipeline offline: cmd/fake-ats serves the fixtures in
+# testdata/fake-ats, then the scraper, CSV export and static site run
+# against a throwaway database. Run from go-scraper/.
+set -euo pipefail
+
+work=$(mktemp -d)
+port=${FAKE_ATS_PORT:-8089}
+trap 'kill "${ats_pid:-}" 2>/dev/null || true; rm -rf "$work"' EXIT
+
+go build -o "$work/bin/" ./cmd/fake-ats ./cmd/scraper ./cmd/static-site
+
+"$work/bin/fake-ats" -addr "localhost:$port" -fixtures testdata/fake-ats &
+ats_pid=$!
+for _ in $(seq 50); do
+  curl -fs "http://localhost:$port/healthz" >/dev/null && break
+  sleep 0.1
+done
+
+export DB_PATH="$work/jobs.db" SCRAPER_API_BASE="http://localhost:$port" SCRAPER_ARCHIVE_DIR="$work/archive"
+"$work/bin/scraper" --config testdata/fake-ats/config.yaml --out "$work/jobs.csv" --report "$work/run.json"
+"$work/bin/static-site" --out "$work/public"
+
+rows=$(($(wc -l <"$work/jobs.csv") - 1))
+echo "CSV rows: $rows"
+[ "$rows" -gt 0 ] || { echo "e2e: empty CSV export" >&2; exit 1; }
+grep -q '"failed_companies": 0' "$work/run.json" || { echo "e2e: some boards failed" >&2; cat "$work/run.json" >&2; exit 1; }
+[ -s "$work/public/index.html" ] || { echo "e2e: static site not generated" >&2; exit 1; }
+ls "$work/archive"/*/greenhouse/acme.json.gz "$work/archive"/*/lever/beta.json.gz >/dev/null
+echo "e2e: ok"